@@ -2,8 +2,10 @@ package auth
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -16,17 +18,60 @@ import (
 
 var jwtSecret = []byte("your-secret-key-change-in-production")
 
-func GenerateJWT(userID int, username string, isAdmin bool) (string, error) {
-	header := map[string]interface{}{
-		"alg": "HS256",
-		"typ": "JWT",
-	}
+const mfaTokenPurpose = "mfa"
+
+// GenerateJWT issues a session token. sessionVersion must match the user's current
+// version for the token to be accepted, so bumping it revokes existing sessions.
+func GenerateJWT(userID int, username string, isAdmin bool, sessionVersion int) (string, error) {
+	return encodeJWT(models.JWTClaims{
+		UserID:         userID,
+		Username:       username,
+		IsAdmin:        isAdmin,
+		SessionVersion: sessionVersion,
+		Exp:            time.Now().Add(24 * time.Hour).Unix(),
+	})
+}
 
-	claims := models.JWTClaims{
+// GenerateMFAToken issues a short-lived token proving the password step succeeded.
+// It is only accepted by ValidateMFAToken, never as a session token.
+func GenerateMFAToken(userID int, username string) (string, error) {
+	return encodeJWT(models.JWTClaims{
 		UserID:   userID,
 		Username: username,
-		IsAdmin:  isAdmin,
-		Exp:      time.Now().Add(24 * time.Hour).Unix(),
+		Purpose:  mfaTokenPurpose,
+		Exp:      time.Now().Add(5 * time.Minute).Unix(),
+	})
+}
+
+// ImpersonationTTL is how long an impersonation token stays valid. It cannot be
+// renewed; the admin starts a new session instead.
+const ImpersonationTTL = 15 * time.Minute
+
+// GenerateImpersonationToken issues a session token for userID on behalf of the
+// admin impersonatorID, along with a random ID identifying the session in the audit log
+func GenerateImpersonationToken(userID int, username string, sessionVersion, impersonatorID int, readOnly bool) (string, string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	sessionID := hex.EncodeToString(b)
+
+	token, err := encodeJWT(models.JWTClaims{
+		UserID:          userID,
+		Username:        username,
+		SessionVersion:  sessionVersion,
+		Exp:             time.Now().Add(ImpersonationTTL).Unix(),
+		ImpersonatorID:  impersonatorID,
+		ImpersonationID: sessionID,
+		ReadOnly:        readOnly,
+	})
+	return token, sessionID, err
+}
+
+func encodeJWT(claims models.JWTClaims) (string, error) {
+	header := map[string]interface{}{
+		"alg": "HS256",
+		"typ": "JWT",
 	}
 
 	headerJSON, _ := json.Marshal(header)
@@ -37,25 +82,43 @@ func GenerateJWT(userID int, username string, isAdmin bool) (string, error) {
 
 	message := headerB64 + "." + claimsB64
 
-	h := hmac.New(sha256.New, jwtSecret)
-	h.Write([]byte(message))
-	signature := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
-
-	return message + "." + signature, nil
+	return message + "." + sign(message), nil
 }
 
 func ValidateJWT(tokenString string) (*models.JWTClaims, error) {
+	claims, err := decodeJWT(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("invalid token purpose")
+	}
+
+	return claims, nil
+}
+
+func ValidateMFAToken(tokenString string) (*models.JWTClaims, error) {
+	claims, err := decodeJWT(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != mfaTokenPurpose {
+		return nil, fmt.Errorf("invalid token purpose")
+	}
+
+	return claims, nil
+}
+
+func decodeJWT(tokenString string) (*models.JWTClaims, error) {
 	parts := strings.Split(tokenString, ".")
 	if len(parts) != 3 {
 		return nil, fmt.Errorf("invalid token format")
 	}
 
 	message := parts[0] + "." + parts[1]
-	h := hmac.New(sha256.New, jwtSecret)
-	h.Write([]byte(message))
-	expectedSignature := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
-
-	if parts[2] != expectedSignature {
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(message))) {
 		return nil, fmt.Errorf("invalid token signature")
 	}
 
@@ -76,6 +139,38 @@ func ValidateJWT(tokenString string) (*models.JWTClaims, error) {
 	return &claims, nil
 }
 
+// sign returns the base64url HMAC-SHA256 signature of message using the server secret
+func sign(message string) string {
+	h := hmac.New(sha256.New, jwtSecret)
+	h.Write([]byte(message))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// GenerateOpaqueToken returns a random URL-safe token and the hash to store for it
+func GenerateOpaqueToken() (string, string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token)
+}
+
+// APITokenPrefix marks personal API tokens so they can be told apart from JWTs in
+// the Authorization header
+const APITokenPrefix = "lnk_"
+
+// GenerateAPIToken returns a new personal API token and the hash to store for it
+func GenerateAPIToken() (string, string) {
+	token, _ := GenerateOpaqueToken()
+	token = APITokenPrefix + token
+	return token, HashToken(token)
+}
+
+// HashToken hashes a high-entropy token for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func HashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -86,4 +181,31 @@ func HashPassword(password string) (string, error) {
 
 func CheckPassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-}
\ No newline at end of file
+}
+
+// Failed login backoff: the first few failures are free, after that each failure
+// doubles the lockout, capped at lockoutMax
+const (
+	lockoutFreeAttempts = 3
+	lockoutBase         = 30 * time.Second
+	lockoutMax          = time.Hour
+
+	// LockoutWindow is how long failures count towards a lockout; the next failure
+	// after a quiet period this long starts the count again
+	LockoutWindow = 24 * time.Hour
+)
+
+// LockoutDuration returns how long an account stays locked after failedCount consecutive failures
+func LockoutDuration(failedCount int) time.Duration {
+	if failedCount < lockoutFreeAttempts {
+		return 0
+	}
+	duration := lockoutBase
+	for i := lockoutFreeAttempts; i < failedCount; i++ {
+		duration *= 2
+		if duration >= lockoutMax {
+			return lockoutMax
+		}
+	}
+	return duration
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
@@ -9,35 +10,52 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
 
+const (
+	oauthCookieName = "oauth_state"
+	oauthCookieTTL  = 10 * time.Minute
+)
+
 var (
 	googleOAuthConfig *oauth2.Config
-	oauthStateString  = generateRandomState()
+	allowedRedirects  []string
 )
 
 type GoogleUser struct {
-	ID      string `json:"id"`
-	Email   string `json:"email"`
-	Name    string `json:"name"`
-	Picture string `json:"picture"`
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	VerifiedEmail bool   `json:"verified_email"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+}
+
+// oauthFlow is the per-login state kept in a signed cookie between
+// GetGoogleLoginURL and HandleGoogleCallback
+type oauthFlow struct {
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Redirect string `json:"redirect"`
+	Exp      int64  `json:"exp"`
 }
 
 func InitOAuth() {
 	clientID := os.Getenv("GOOGLE_CLIENT_ID")
 	clientSecret := os.Getenv("GOOGLE_CLIENT_SECRET")
 	redirectURL := os.Getenv("GOOGLE_REDIRECT_URL")
-	
+
 	if clientID == "" || clientSecret == "" {
 		// Use default values for development
 		clientID = "your-google-client-id"
 		clientSecret = "your-google-client-secret"
 		redirectURL = "http://localhost:8080/api/auth/google/callback"
 	}
-	
+
 	if redirectURL == "" {
 		redirectURL = "http://localhost:8080/api/auth/google/callback"
 	}
@@ -49,6 +67,17 @@ func InitOAuth() {
 		Scopes:       []string{"openid", "profile", "email"},
 		Endpoint:     google.Endpoint,
 	}
+
+	// Post-login redirect targets (local paths only)
+	allowedRedirects = []string{"/", "/admin", "/save"}
+	if list := os.Getenv("OAUTH_REDIRECT_ALLOWLIST"); list != "" {
+		allowedRedirects = nil
+		for _, path := range strings.Split(list, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				allowedRedirects = append(allowedRedirects, path)
+			}
+		}
+	}
 }
 
 func generateRandomState() string {
@@ -57,30 +86,122 @@ func generateRandomState() string {
 	return base64.URLEncoding.EncodeToString(b)
 }
 
-func GetGoogleLoginURL() string {
-	return googleOAuthConfig.AuthCodeURL(oauthStateString, oauth2.AccessTypeOffline)
+// ValidateRedirect returns target if it is an allow-listed local path, or "/" otherwise
+func ValidateRedirect(target string) string {
+	if target == "" || !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.Contains(target, "\\") {
+		return "/"
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" {
+		return "/"
+	}
+
+	for _, allowed := range allowedRedirects {
+		if parsed.Path == allowed {
+			return parsed.Path
+		}
+	}
+
+	return "/"
 }
 
-func HandleGoogleCallback(code, state string) (*GoogleUser, error) {
-	if state != oauthStateString {
-		return nil, fmt.Errorf("invalid oauth state")
+// GetGoogleLoginURL starts a login flow: it binds a fresh state and PKCE verifier
+// to the browser via a signed cookie and returns the Google consent URL.
+func GetGoogleLoginURL(w http.ResponseWriter, r *http.Request, redirect string) (string, error) {
+	flow := oauthFlow{
+		State:    generateRandomState(),
+		Verifier: oauth2.GenerateVerifier(),
+		Redirect: ValidateRedirect(redirect),
+		Exp:      time.Now().Add(oauthCookieTTL).Unix(),
 	}
 
-	token, err := googleOAuthConfig.Exchange(context.Background(), code)
+	flowJSON, err := json.Marshal(flow)
 	if err != nil {
-		return nil, fmt.Errorf("code exchange failed: %s", err.Error())
+		return "", err
 	}
+	payload := base64.RawURLEncoding.EncodeToString(flowJSON)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthCookieName,
+		Value:    payload + "." + sign(payload),
+		Path:     "/api/auth/google",
+		MaxAge:   int(oauthCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return googleOAuthConfig.AuthCodeURL(flow.State, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(flow.Verifier)), nil
+}
 
-	response, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + url.QueryEscape(token.AccessToken))
+// readOAuthFlow verifies and decodes the flow cookie, clearing it so it can only be used once
+func readOAuthFlow(w http.ResponseWriter, r *http.Request) (*oauthFlow, error) {
+	cookie, err := r.Cookie(oauthCookieName)
 	if err != nil {
-		return nil, fmt.Errorf("failed getting user info: %s", err.Error())
+		return nil, fmt.Errorf("missing oauth state cookie")
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthCookieName,
+		Value:    "",
+		Path:     "/api/auth/google",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(sign(parts[0]))) {
+		return nil, fmt.Errorf("invalid oauth state cookie")
+	}
+
+	flowJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid oauth state cookie")
+	}
+
+	var flow oauthFlow
+	if err := json.Unmarshal(flowJSON, &flow); err != nil {
+		return nil, fmt.Errorf("invalid oauth state cookie")
+	}
+
+	if time.Now().Unix() > flow.Exp {
+		return nil, fmt.Errorf("oauth state expired")
+	}
+
+	return &flow, nil
+}
+
+// HandleGoogleCallback validates the state against the browser's flow cookie, exchanges the
+// code using the PKCE verifier and returns the Google user along with the post-login redirect.
+func HandleGoogleCallback(w http.ResponseWriter, r *http.Request, code, state string) (*GoogleUser, string, error) {
+	flow, err := readOAuthFlow(w, r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if state == "" || !hmac.Equal([]byte(state), []byte(flow.State)) {
+		return nil, "", fmt.Errorf("invalid oauth state")
+	}
+
+	ctx := context.Background()
+	token, err := googleOAuthConfig.Exchange(ctx, code, oauth2.VerifierOption(flow.Verifier))
+	if err != nil {
+		return nil, "", fmt.Errorf("code exchange failed: %s", err.Error())
+	}
+
+	response, err := googleOAuthConfig.Client(ctx, token).Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed getting user info: %s", err.Error())
 	}
 	defer response.Body.Close()
 
 	var googleUser GoogleUser
 	if err := json.NewDecoder(response.Body).Decode(&googleUser); err != nil {
-		return nil, fmt.Errorf("failed parsing user info: %s", err.Error())
+		return nil, "", fmt.Errorf("failed parsing user info: %s", err.Error())
 	}
 
-	return &googleUser, nil
-}
\ No newline at end of file
+	return &googleUser, ValidateRedirect(flow.Redirect), nil
+}
@@ -0,0 +1,91 @@
+package auth
+
+// Permission names an action on the admin API
+type Permission string
+
+const (
+	PermLinksRead         Permission = "links:read"
+	PermLinksDelete       Permission = "links:delete"
+	PermLinksLock         Permission = "links:lock"
+	PermLinksForcePrivate Permission = "links:force_private"
+	PermUsersRead         Permission = "users:read"
+	PermUsersDelete       Permission = "users:delete"
+	PermUsersManageRoles  Permission = "users:manage_roles"
+	PermUsersUnlock       Permission = "users:unlock"
+	PermUsersSuspend      Permission = "users:suspend"
+	PermUsersImpersonate  Permission = "users:impersonate"
+	PermReportsManage     Permission = "reports:manage"
+	PermSecurityRead      Permission = "security:read"
+	PermSettingsManage    Permission = "settings:manage"
+)
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// Role describes a role and the permissions it grants
+type Role struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// Roles lists the built-in roles from least to most privileged
+var Roles = []Role{
+	{
+		Name:        RoleUser,
+		Description: "Manage own links",
+		Permissions: []Permission{},
+	},
+	{
+		Name:        RoleModerator,
+		Description: "Review all links, work the report queue and lock or force links private",
+		Permissions: []Permission{PermLinksRead, PermLinksLock, PermLinksForcePrivate, PermReportsManage},
+	},
+	{
+		Name:        RoleAdmin,
+		Description: "Full administrative access",
+		Permissions: []Permission{
+			PermLinksRead, PermLinksDelete, PermLinksLock, PermLinksForcePrivate,
+			PermUsersRead, PermUsersDelete, PermUsersManageRoles, PermUsersUnlock, PermUsersSuspend, PermUsersImpersonate,
+			PermReportsManage, PermSecurityRead, PermSettingsManage,
+		},
+	},
+}
+
+// ValidRole reports whether name is a built-in role
+func ValidRole(name string) bool {
+	for _, role := range Roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether role grants perm
+func HasPermission(role string, perm Permission) bool {
+	for _, r := range Roles {
+		if r.Name != role {
+			continue
+		}
+		for _, p := range r.Permissions {
+			if p == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsStaff reports whether role grants any admin API permission
+func IsStaff(role string) bool {
+	for _, r := range Roles {
+		if r.Name == role {
+			return len(r.Permissions) > 0
+		}
+	}
+	return false
+}
@@ -0,0 +1,330 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// Authenticator data flags
+const (
+	authFlagUserPresent  = 0x01
+	authFlagUserVerified = 0x04
+	authFlagAttested     = 0x40
+)
+
+// COSE algorithm identifiers supported for passkeys
+const (
+	COSEAlgES256 = -7
+	COSEAlgEdDSA = -8
+	COSEAlgRS256 = -257
+)
+
+var (
+	webAuthnRPID    string
+	webAuthnRPName  string
+	webAuthnOrigins []string
+)
+
+// WebAuthnCredential is the result of a verified registration
+type WebAuthnCredential struct {
+	ID        []byte
+	PublicKey []byte // COSE_Key encoded public key
+	SignCount uint32
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func InitWebAuthn() {
+	webAuthnRPID = os.Getenv("WEBAUTHN_RP_ID")
+	if webAuthnRPID == "" {
+		webAuthnRPID = "localhost"
+	}
+
+	webAuthnRPName = os.Getenv("WEBAUTHN_RP_NAME")
+	if webAuthnRPName == "" {
+		webAuthnRPName = "Links"
+	}
+
+	origins := os.Getenv("WEBAUTHN_ORIGINS")
+	if origins == "" {
+		origins = "http://localhost:8080"
+	}
+	webAuthnOrigins = nil
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			webAuthnOrigins = append(webAuthnOrigins, origin)
+		}
+	}
+}
+
+func WebAuthnRPID() string {
+	return webAuthnRPID
+}
+
+func WebAuthnRPName() string {
+	return webAuthnRPName
+}
+
+// NewWebAuthnChallenge returns a random base64url challenge
+func NewWebAuthnChallenge() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeWebAuthnBase64 decodes base64url input as sent by browsers, with or without padding
+func DecodeWebAuthnBase64(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// ClientDataChallenge extracts the challenge from clientDataJSON so the server can look
+// up the pending ceremony before verifying the rest of the response
+func ClientDataChallenge(clientDataJSON []byte) (string, error) {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil || data.Challenge == "" {
+		return "", fmt.Errorf("invalid client data")
+	}
+	return data.Challenge, nil
+}
+
+// VerifyRegistration validates a navigator.credentials.create() response against the
+// expected challenge and returns the new credential. Attestation statements are not
+// verified since registration requests "none" attestation.
+func VerifyRegistration(clientDataJSON, attestationObject []byte, challenge string) (*WebAuthnCredential, error) {
+	if err := verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid attestation object")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("attestation object missing authData")
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyAuthenticatorData(authData, false); err != nil {
+		return nil, err
+	}
+	if authData.Flags&authFlagAttested == 0 || len(authData.CredentialID) == 0 {
+		return nil, fmt.Errorf("authenticator data missing attested credential")
+	}
+
+	// Reject keys we would not be able to verify at login
+	if _, _, err := parseCOSEKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnCredential{
+		ID:        authData.CredentialID,
+		PublicKey: authData.PublicKey,
+		SignCount: authData.SignCount,
+	}, nil
+}
+
+// VerifyAssertion validates a navigator.credentials.get() response for a stored credential
+// and returns the authenticator's new signature counter. requireUV demands user verification
+// (PIN/biometric), which is needed when the passkey is the only factor.
+func VerifyAssertion(clientDataJSON, rawAuthData, signature []byte, challenge string, publicKey []byte, storedSignCount uint32, requireUV bool) (uint32, error) {
+	if err := verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := verifyAuthenticatorData(authData, requireUV); err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+	if err := verifyCOSESignature(publicKey, signed, signature); err != nil {
+		return 0, err
+	}
+
+	// A counter that does not increase suggests a cloned authenticator. Authenticators
+	// that do not implement counters always report zero.
+	if (authData.SignCount != 0 || storedSignCount != 0) && authData.SignCount <= storedSignCount {
+		return 0, fmt.Errorf("signature counter did not increase")
+	}
+
+	return authData.SignCount, nil
+}
+
+func verifyClientData(clientDataJSON []byte, expectedType, challenge string) error {
+	var data clientData
+	if err := json.Unmarshal(clientDataJSON, &data); err != nil {
+		return fmt.Errorf("invalid client data")
+	}
+	if data.Type != expectedType {
+		return fmt.Errorf("unexpected client data type")
+	}
+	if data.Challenge != challenge {
+		return fmt.Errorf("challenge mismatch")
+	}
+	for _, origin := range webAuthnOrigins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin not allowed")
+}
+
+func verifyAuthenticatorData(authData *authenticatorData, requireUV bool) error {
+	expected := sha256.Sum256([]byte(webAuthnRPID))
+	if !bytes.Equal(authData.RPIDHash, expected[:]) {
+		return fmt.Errorf("relying party ID mismatch")
+	}
+	if authData.Flags&authFlagUserPresent == 0 {
+		return fmt.Errorf("user presence not confirmed")
+	}
+	if requireUV && authData.Flags&authFlagUserVerified == 0 {
+		return fmt.Errorf("user verification required")
+	}
+	return nil
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, fmt.Errorf("authenticator data too short")
+	}
+
+	authData := &authenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if authData.Flags&authFlagAttested != 0 {
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, fmt.Errorf("attested credential data too short")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, fmt.Errorf("credential ID exceeds authenticator data")
+		}
+		authData.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, remaining, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		authData.PublicKey = rest[:len(rest)-len(remaining)]
+	}
+
+	return authData, nil
+}
+
+// parseCOSEKey decodes a COSE_Key into a Go public key and its algorithm
+func parseCOSEKey(encoded []byte) (crypto.PublicKey, int64, error) {
+	decoded, _, err := decodeCBOR(encoded)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid COSE key: %w", err)
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, fmt.Errorf("invalid COSE key")
+	}
+
+	kty, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+
+	switch {
+	case kty == 2 && alg == COSEAlgES256:
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, fmt.Errorf("invalid P-256 key")
+		}
+		point := append(append([]byte{0x04}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return nil, 0, fmt.Errorf("invalid P-256 key")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, alg, nil
+	case kty == 3 && alg == COSEAlgRS256:
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, fmt.Errorf("invalid RSA key")
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, alg, nil
+	case kty == 1 && alg == COSEAlgEdDSA:
+		crv, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), alg, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported COSE key type %d / algorithm %d", kty, alg)
+	}
+}
+
+func verifyCOSESignature(encodedKey, message, signature []byte) error {
+	publicKey, _, err := parseCOSEKey(encodedKey)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(message)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return fmt.Errorf("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("invalid signature")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, message, signature) {
+			return fmt.Errorf("invalid signature")
+		}
+	}
+	return nil
+}
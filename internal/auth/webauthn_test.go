@@ -0,0 +1,319 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"links/internal/auth/webauthntest"
+)
+
+const (
+	testRPID   = "links.test"
+	testOrigin = "https://links.test"
+)
+
+func setupWebAuthn(t *testing.T) {
+	t.Helper()
+	t.Setenv("WEBAUTHN_RP_ID", testRPID)
+	t.Setenv("WEBAUTHN_ORIGINS", "https://other.test, "+testOrigin)
+	InitWebAuthn()
+}
+
+// register runs a registration with a and returns the stored credential
+func register(t *testing.T, a *webauthntest.Authenticator) *WebAuthnCredential {
+	t.Helper()
+	challenge := NewWebAuthnChallenge()
+	clientDataJSON, attestationObject := a.Create(challenge)
+	cred, err := VerifyRegistration(clientDataJSON, attestationObject, challenge)
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+	return cred
+}
+
+func TestWebAuthnRegistrationAndAssertion(t *testing.T) {
+	setupWebAuthn(t)
+	a := webauthntest.New(testRPID, testOrigin)
+
+	cred := register(t, a)
+	if string(cred.ID) != string(a.CredentialID) {
+		t.Errorf("credential ID = %x, want %x", cred.ID, a.CredentialID)
+	}
+	if _, _, err := parseCOSEKey(cred.PublicKey); err != nil {
+		t.Fatalf("stored public key does not parse: %v", err)
+	}
+
+	stored := cred.SignCount
+	for i := 0; i < 3; i++ {
+		challenge := NewWebAuthnChallenge()
+		clientDataJSON, authData, signature := a.Get(challenge)
+		count, err := VerifyAssertion(clientDataJSON, authData, signature, challenge, cred.PublicKey, stored, true)
+		if err != nil {
+			t.Fatalf("assertion %d: %v", i, err)
+		}
+		if count != a.SignCount {
+			t.Errorf("assertion %d: sign count = %d, want %d", i, count, a.SignCount)
+		}
+		stored = count
+	}
+}
+
+func TestWebAuthnRegistrationRejected(t *testing.T) {
+	setupWebAuthn(t)
+
+	tests := []struct {
+		name      string
+		modify    func(a *webauthntest.Authenticator)
+		ceremony  string // Client data type to send instead of webauthn.create
+		challenge string // Challenge the client signs instead of the expected one
+		want      string
+	}{
+		{name: "bad challenge", challenge: "c29tZXRoaW5nIGVsc2U", want: "challenge mismatch"},
+		{name: "bad origin", modify: func(a *webauthntest.Authenticator) { a.Origin = "https://evil.test" }, want: "origin not allowed"},
+		{name: "origin prefix", modify: func(a *webauthntest.Authenticator) { a.Origin = testOrigin + ".evil.test" }, want: "origin not allowed"},
+		{name: "bad RP ID", modify: func(a *webauthntest.Authenticator) { a.RPID = "evil.test" }, want: "relying party ID mismatch"},
+		{name: "assertion client data", ceremony: "webauthn.get", want: "unexpected client data type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := webauthntest.New(testRPID, testOrigin)
+			if tt.modify != nil {
+				tt.modify(a)
+			}
+			expected := NewWebAuthnChallenge()
+			signed := expected
+			if tt.challenge != "" {
+				signed = tt.challenge
+			}
+			clientDataJSON, attestationObject := a.Create(signed)
+			if tt.ceremony != "" {
+				clientDataJSON = a.ClientData(tt.ceremony, signed)
+			}
+
+			_, err := VerifyRegistration(clientDataJSON, attestationObject, expected)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("VerifyRegistration error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebAuthnAssertionRejected(t *testing.T) {
+	setupWebAuthn(t)
+
+	tests := []struct {
+		name      string
+		modify    func(a *webauthntest.Authenticator)
+		stored    func(a *webauthntest.Authenticator) uint32 // Counter on record before the assertion
+		requireUV bool
+		tamper    func(clientDataJSON, authData, signature []byte) ([]byte, []byte, []byte)
+		challenge string
+		want      string
+	}{
+		{name: "bad challenge", challenge: "c29tZXRoaW5nIGVsc2U", want: "challenge mismatch"},
+		{name: "bad origin", modify: func(a *webauthntest.Authenticator) { a.Origin = "http://links.test" }, want: "origin not allowed"},
+		{
+			name:   "sign count regression",
+			stored: func(a *webauthntest.Authenticator) uint32 { return a.SignCount + 5 },
+			want:   "signature counter did not increase",
+		},
+		{
+			name:   "sign count replayed",
+			stored: func(a *webauthntest.Authenticator) uint32 { return a.SignCount + 1 },
+			want:   "signature counter did not increase",
+		},
+		{
+			name:   "counter dropped to zero",
+			modify: func(a *webauthntest.Authenticator) { a.NoCounter = true },
+			stored: func(a *webauthntest.Authenticator) uint32 { return 7 },
+			want:   "signature counter did not increase",
+		},
+		{
+			name:      "user verification required",
+			modify:    func(a *webauthntest.Authenticator) { a.UserVerified = false },
+			requireUV: true,
+			want:      "user verification required",
+		},
+		{
+			name: "tampered authenticator data",
+			tamper: func(clientDataJSON, authData, signature []byte) ([]byte, []byte, []byte) {
+				authData[36] ^= 0x01
+				return clientDataJSON, authData, signature
+			},
+			want: "invalid signature",
+		},
+		{
+			name: "signature from another key",
+			tamper: func(clientDataJSON, authData, signature []byte) ([]byte, []byte, []byte) {
+				other := webauthntest.New(testRPID, testOrigin)
+				return clientDataJSON, authData, other.Sign(authData, clientDataJSON)
+			},
+			want: "invalid signature",
+		},
+		{
+			name: "truncated authenticator data",
+			tamper: func(clientDataJSON, authData, signature []byte) ([]byte, []byte, []byte) {
+				return clientDataJSON, authData[:36], signature
+			},
+			want: "authenticator data too short",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := webauthntest.New(testRPID, testOrigin)
+			cred := register(t, a)
+			if tt.modify != nil {
+				tt.modify(a)
+			}
+			stored := cred.SignCount
+			if tt.stored != nil {
+				stored = tt.stored(a)
+			}
+
+			expected := NewWebAuthnChallenge()
+			signed := expected
+			if tt.challenge != "" {
+				signed = tt.challenge
+			}
+			clientDataJSON, authData, signature := a.Get(signed)
+			if tt.tamper != nil {
+				clientDataJSON, authData, signature = tt.tamper(clientDataJSON, authData, signature)
+			}
+
+			_, err := VerifyAssertion(clientDataJSON, authData, signature, expected, cred.PublicKey, stored, tt.requireUV)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("VerifyAssertion error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebAuthnUserVerificationOptionalForSecondFactor(t *testing.T) {
+	setupWebAuthn(t)
+	a := webauthntest.New(testRPID, testOrigin)
+	a.UserVerified = false
+	cred := register(t, a)
+
+	challenge := NewWebAuthnChallenge()
+	clientDataJSON, authData, signature := a.Get(challenge)
+	if _, err := VerifyAssertion(clientDataJSON, authData, signature, challenge, cred.PublicKey, cred.SignCount, false); err != nil {
+		t.Errorf("assertion without UV as a second factor: %v", err)
+	}
+}
+
+func TestWebAuthnZeroCounterAccepted(t *testing.T) {
+	setupWebAuthn(t)
+	a := webauthntest.New(testRPID, testOrigin)
+	a.NoCounter = true
+	cred := register(t, a)
+
+	for i := 0; i < 2; i++ {
+		challenge := NewWebAuthnChallenge()
+		clientDataJSON, authData, signature := a.Get(challenge)
+		count, err := VerifyAssertion(clientDataJSON, authData, signature, challenge, cred.PublicKey, 0, true)
+		if err != nil || count != 0 {
+			t.Errorf("assertion %d with zero counter = %d, %v", i, count, err)
+		}
+	}
+}
+
+// Every truncation of a valid attestation object or authenticator data must fail
+// cleanly rather than panic
+func TestWebAuthnTruncatedInput(t *testing.T) {
+	setupWebAuthn(t)
+	a := webauthntest.New(testRPID, testOrigin)
+	challenge := NewWebAuthnChallenge()
+	clientDataJSON, attestationObject := a.Create(challenge)
+
+	for n := 0; n < len(attestationObject); n++ {
+		if _, err := VerifyRegistration(clientDataJSON, attestationObject[:n], challenge); err == nil {
+			t.Errorf("attestation object truncated to %d bytes was accepted", n)
+		}
+	}
+
+	authData := a.AuthData(true)
+	for n := 0; n < len(authData); n++ {
+		if _, err := parseAuthenticatorData(authData[:n]); err == nil {
+			t.Errorf("attested authenticator data truncated to %d bytes was accepted", n)
+		}
+	}
+}
+
+func TestDecodeCBORMalformed(t *testing.T) {
+	deep := []byte{}
+	for i := 0; i < 100; i++ {
+		deep = append(deep, 0x81) // array of one item, nested
+	}
+	deep = append(deep, 0x00)
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"empty", nil},
+		{"missing argument byte", []byte{0x18}},
+		{"short uint16 argument", []byte{0x19, 0x01}},
+		{"short uint32 argument", []byte{0x1a, 0x01, 0x02}},
+		{"short uint64 argument", []byte{0x1b, 0x01, 0x02, 0x03}},
+		{"reserved additional info", []byte{0x1c}},
+		{"indefinite-length byte string", []byte{0x5f, 0x41, 0x00, 0xff}},
+		{"byte string longer than input", []byte{0x45, 0x01, 0x02}},
+		{"huge byte string length", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge array length", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge map length", []byte{0xbb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"array missing items", []byte{0x83, 0x01, 0x02}},
+		{"map missing value", []byte{0xa1, 0x01}},
+		{"map with byte string key", []byte{0xa1, 0x41, 0x00, 0x01}},
+		{"map with array key", []byte{0xa1, 0x80, 0x01}},
+		{"tag", []byte{0xc0, 0x00}},
+		{"float", []byte{0xf9, 0x00, 0x00}},
+		{"break outside indefinite item", []byte{0xff}},
+		{"nesting too deep", deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.input); err == nil {
+				t.Errorf("decodeCBOR(%x) succeeded", tt.input)
+			}
+		})
+	}
+}
+
+func TestParseCOSEKeyRejectsBadKeys(t *testing.T) {
+	a := webauthntest.New(testRPID, testOrigin)
+	x := make([]byte, 32)
+	a.Key.X.FillBytes(x)
+
+	ec2 := func(alg, crv int64, y []byte) []byte {
+		var key []byte
+		key = webauthntest.AppendHead(key, 5, 5)
+		key = webauthntest.AppendInt(webauthntest.AppendInt(key, 1), 2)
+		key = webauthntest.AppendInt(webauthntest.AppendInt(key, 3), alg)
+		key = webauthntest.AppendInt(webauthntest.AppendInt(key, -1), crv)
+		key = webauthntest.AppendBytes(webauthntest.AppendInt(key, -2), x)
+		key = webauthntest.AppendBytes(webauthntest.AppendInt(key, -3), y)
+		return key
+	}
+
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{"not a map", webauthntest.AppendInt(nil, 1)},
+		{"unsupported algorithm", ec2(-35, 1, make([]byte, 32))},
+		{"wrong curve", ec2(-7, 2, make([]byte, 32))},
+		{"short coordinate", ec2(-7, 1, make([]byte, 31))},
+		{"point not on curve", ec2(-7, 1, make([]byte, 32))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseCOSEKey(tt.key); err == nil {
+				t.Error("parseCOSEKey succeeded")
+			}
+		})
+	}
+}
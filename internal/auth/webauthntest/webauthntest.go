@@ -0,0 +1,207 @@
+// Package webauthntest provides a software passkey authenticator for tests. It
+// produces the same clientDataJSON, attestation objects and assertions a browser
+// would, signed with an in-memory P-256 key.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+)
+
+// Authenticator flags, as in the authenticator data
+const (
+	FlagUserPresent  = 0x01
+	FlagUserVerified = 0x04
+	FlagAttested     = 0x40
+)
+
+// Authenticator is a software ES256 authenticator holding one credential
+type Authenticator struct {
+	RPID         string
+	Origin       string
+	Key          *ecdsa.PrivateKey
+	CredentialID []byte
+	UserHandle   string // base64url user.id returned with assertions, if set
+	SignCount    uint32 // Incremented before each assertion unless NoCounter is set
+	NoCounter    bool   // Always report a zero counter, like many platform authenticators
+	UserVerified bool   // Set the UV flag
+}
+
+// New returns an authenticator with a fresh key and random credential ID
+func New(rpID, origin string) *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &Authenticator{RPID: rpID, Origin: origin, Key: key, CredentialID: id, UserVerified: true}
+}
+
+// ClientData builds clientDataJSON for a ceremony of the given type
+// ("webauthn.create" or "webauthn.get")
+func (a *Authenticator) ClientData(ceremony, challenge string) []byte {
+	data, _ := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    a.Origin,
+	})
+	return data
+}
+
+// COSEKey returns the credential's public key as an EC2 COSE_Key
+func (a *Authenticator) COSEKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.Key.X.FillBytes(x)
+	a.Key.Y.FillBytes(y)
+
+	var key []byte
+	key = AppendHead(key, 5, 5)
+	key = AppendInt(AppendInt(key, 1), 2)    // kty: EC2
+	key = AppendInt(AppendInt(key, 3), -7)   // alg: ES256
+	key = AppendInt(AppendInt(key, -1), 1)   // crv: P-256
+	key = AppendBytes(AppendInt(key, -2), x) // x
+	key = AppendBytes(AppendInt(key, -3), y) // y
+	return key
+}
+
+// AuthData builds authenticator data with the current counter and flags, including
+// the attested credential when attested is set
+func (a *Authenticator) AuthData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	flags := byte(FlagUserPresent)
+	if a.UserVerified {
+		flags |= FlagUserVerified
+	}
+	if attested {
+		flags |= FlagAttested
+	}
+
+	data := append([]byte(nil), rpIDHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.SignCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.CredentialID)))
+		data = append(data, a.CredentialID...)
+		data = append(data, a.COSEKey()...)
+	}
+	return data
+}
+
+// Create answers navigator.credentials.create() with "none" attestation
+func (a *Authenticator) Create(challenge string) (clientDataJSON, attestationObject []byte) {
+	var obj []byte
+	obj = AppendHead(obj, 5, 3)
+	obj = AppendText(AppendText(obj, "fmt"), "none")
+	obj = AppendHead(AppendText(obj, "attStmt"), 5, 0)
+	obj = AppendBytes(AppendText(obj, "authData"), a.AuthData(true))
+	return a.ClientData("webauthn.create", challenge), obj
+}
+
+// Get answers navigator.credentials.get(), advancing the signature counter
+func (a *Authenticator) Get(challenge string) (clientDataJSON, authData, signature []byte) {
+	if !a.NoCounter {
+		a.SignCount++
+	}
+	clientDataJSON = a.ClientData("webauthn.get", challenge)
+	authData = a.AuthData(false)
+	return clientDataJSON, authData, a.Sign(authData, clientDataJSON)
+}
+
+// Sign signs authenticator data and the client data hash as an assertion does
+func (a *Authenticator) Sign(authData, clientDataJSON []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.Key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signature
+}
+
+// Credential is a credential as serialized by the browser, ready to be posted to the
+// register/finish or login/finish endpoints
+type Credential struct {
+	ID       string            `json:"id"`
+	RawID    string            `json:"rawId"`
+	Type     string            `json:"type"`
+	Response map[string]string `json:"response"`
+}
+
+// CreateCredential runs Create and encodes the result for register/finish
+func (a *Authenticator) CreateCredential(challenge string) Credential {
+	clientDataJSON, attestationObject := a.Create(challenge)
+	return a.credential(map[string]string{
+		"clientDataJSON":    encode(clientDataJSON),
+		"attestationObject": encode(attestationObject),
+	})
+}
+
+// GetCredential runs Get and encodes the result for login/finish
+func (a *Authenticator) GetCredential(challenge string) Credential {
+	clientDataJSON, authData, signature := a.Get(challenge)
+	response := map[string]string{
+		"clientDataJSON":    encode(clientDataJSON),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+	}
+	if a.UserHandle != "" {
+		response["userHandle"] = a.UserHandle
+	}
+	return a.credential(response)
+}
+
+func (a *Authenticator) credential(response map[string]string) Credential {
+	return Credential{
+		ID:       encode(a.CredentialID),
+		RawID:    encode(a.CredentialID),
+		Type:     "public-key",
+		Response: response,
+	}
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// AppendHead appends a CBOR item head with the given major type and argument
+func AppendHead(b []byte, major byte, arg uint64) []byte {
+	major <<= 5
+	switch {
+	case arg < 24:
+		return append(b, major|byte(arg))
+	case arg <= 0xff:
+		return append(b, major|24, byte(arg))
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, major|27), arg)
+	}
+}
+
+// AppendInt appends a CBOR integer
+func AppendInt(b []byte, n int64) []byte {
+	if n < 0 {
+		return AppendHead(b, 1, uint64(-1-n))
+	}
+	return AppendHead(b, 0, uint64(n))
+}
+
+// AppendBytes appends a CBOR byte string
+func AppendBytes(b, value []byte) []byte {
+	return append(AppendHead(b, 2, uint64(len(value))), value...)
+}
+
+// AppendText appends a CBOR text string
+func AppendText(b []byte, value string) []byte {
+	return append(AppendHead(b, 3, uint64(len(value))), value...)
+}
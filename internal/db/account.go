@@ -0,0 +1,66 @@
+package db
+
+import (
+	"links/internal/models"
+)
+
+func (db *Database) GetProfile(userID int) (*models.Profile, error) {
+	user, err := db.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := models.Profile{User: *user}
+	query := `SELECT email, COALESCE(email_verified, 0), password IS NOT NULL AND password != '', google_id IS NOT NULL AND google_id != '' FROM users WHERE id = ?`
+	err = db.conn.QueryRow(query, userID).Scan(&profile.Email, &profile.EmailVerified, &profile.HasPassword, &profile.GoogleLinked)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (db *Database) GetPasswordHash(userID int) (string, error) {
+	var hashedPassword string
+	err := db.conn.QueryRow(`SELECT COALESCE(password, '') FROM users WHERE id = ?`, userID).Scan(&hashedPassword)
+	return hashedPassword, err
+}
+
+// UpdateUsername renames the user; it fails with a constraint error if the name is taken
+func (db *Database) UpdateUsername(userID int, username string) error {
+	result, err := db.conn.Exec(`UPDATE users SET username = ? WHERE id = ?`, username, userID)
+	if err != nil {
+		return conflictError(err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// UpdateEmail changes the address and marks it unverified until the user confirms it
+func (db *Database) UpdateEmail(userID int, email *string) error {
+	_, err := db.conn.Exec(`UPDATE users SET email = ?, email_verified = 0 WHERE id = ? AND email IS NOT ?`, email, userID, email)
+	return err
+}
+
+// UpdatePassword stores a new password hash and bumps the session version, revoking
+// every token issued before the change. It returns the new session version.
+func (db *Database) UpdatePassword(userID int, hashedPassword string) (int, error) {
+	query := `UPDATE users SET password = ?, session_version = session_version + 1 WHERE id = ? RETURNING session_version`
+	var sessionVersion int
+	err := db.conn.QueryRow(query, hashedPassword, userID).Scan(&sessionVersion)
+	return sessionVersion, err
+}
+
+// DeleteUser permanently removes the user's own account and all of their data. Unlike
+// an admin deletion it does not go through the trash.
+func (db *Database) DeleteUser(userID int) error {
+	return db.PurgeUser(userID)
+}
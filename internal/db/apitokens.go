@@ -0,0 +1,78 @@
+package db
+
+import (
+	"time"
+
+	"links/internal/models"
+)
+
+// CreateAPIToken stores a hashed personal API token
+func (db *Database) CreateAPIToken(userID int, name, tokenHash string) (*models.APIToken, error) {
+	token := models.APIToken{Name: name, CreatedAt: time.Now().Format("2006-01-02 15:04:05")}
+	query := `INSERT INTO api_tokens (user_id, name, token_hash, created_at) VALUES (?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, userID, name, tokenHash, token.CreatedAt)
+	if err != nil {
+		return nil, conflictError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	token.ID = int(id)
+	return &token, nil
+}
+
+func (db *Database) GetAPITokens(userID int) ([]models.APIToken, error) {
+	query := `SELECT id, name, created_at, last_used_at FROM api_tokens WHERE user_id = ? ORDER BY created_at DESC, id DESC`
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		var token models.APIToken
+		if err := rows.Scan(&token.ID, &token.Name, &token.CreatedAt, &token.LastUsedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func (db *Database) CountAPITokens(userID int) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM api_tokens WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+func (db *Database) DeleteAPIToken(id, userID int) error {
+	result, err := db.conn.Exec(`DELETE FROM api_tokens WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetUserByAPIToken returns the owner of a token and records its use. It returns
+// ErrNotFound if the token is unknown or its account deleted.
+func (db *Database) GetUserByAPIToken(tokenHash string) (*models.User, error) {
+	query := `UPDATE api_tokens SET last_used_at = ? WHERE token_hash = ? RETURNING user_id`
+	var userID int
+	err := db.conn.QueryRow(query, time.Now().Format("2006-01-02 15:04:05"), tokenHash).Scan(&userID)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetUserByID(userID)
+}
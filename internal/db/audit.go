@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"links/internal/models"
+)
+
+// WriteAudit appends an entry to the audit log
+func (db *Database) WriteAudit(entry models.AuditEntry) error {
+	var actorID sql.NullInt64
+	if entry.ActorID != 0 {
+		actorID = sql.NullInt64{Int64: int64(entry.ActorID), Valid: true}
+	}
+
+	query := `INSERT INTO audit_log (actor_id, actor_username, action, target_type, target_id, before_value, after_value, ip, user_agent, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, actorID, entry.ActorUsername, entry.Action, entry.TargetType, entry.TargetID,
+		entry.Before, entry.After, entry.IP, entry.UserAgent, time.Now().Format("2006-01-02 15:04:05"))
+	return err
+}
+
+// GetAuditLog returns the matching entries, newest first, along with the total match count
+func (db *Database) GetAuditLog(filter models.AuditFilter) ([]models.AuditEntry, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor_username = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		// "login.*" matches every login action
+		if prefix, ok := strings.CutSuffix(filter.Action, "*"); ok {
+			conditions = append(conditions, "action LIKE ? ESCAPE '\\'")
+			args = append(args, escapeLike(prefix)+"%")
+		} else {
+			conditions = append(conditions, "action = ?")
+			args = append(args, filter.Action)
+		}
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, filter.TargetID)
+	}
+	if filter.Since != "" {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if filter.Until != "" {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM audit_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, COALESCE(actor_id, 0), actor_username, action, target_type, target_id, before_value, after_value, ip, user_agent, created_at
+		FROM audit_log` + where + ` ORDER BY id DESC LIMIT ? OFFSET ?`
+	rows, err := db.conn.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorUsername, &e.Action, &e.TargetType, &e.TargetID,
+			&e.Before, &e.After, &e.IP, &e.UserAgent, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
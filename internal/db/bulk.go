@@ -0,0 +1,150 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"links/internal/models"
+)
+
+// AdminLinkIDs returns the IDs of up to max+1 links matching the filter, so callers
+// can tell when a filter matches more than they are willing to change at once
+func (db *Database) AdminLinkIDs(filter models.LinkFilter, max int) ([]int, error) {
+	from, args := adminLinkFrom(filter)
+	rows, err := db.conn.Query(`SELECT l.id`+from+` ORDER BY l.id LIMIT ?`, append(args, max+1)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// AdminBulkLinks applies a moderation action ("delete", "lock", "unlock" or
+// "force_private") to every link in one transaction. Links that do not exist are
+// reported as failed rather than aborting the batch.
+func (db *Database) AdminBulkLinks(linkIDs []int, action string, adminID int) ([]models.BulkResult, error) {
+	var query string
+	var args []interface{}
+	switch action {
+	case "delete":
+		query = `UPDATE links SET deleted_at = ?, deleted_by = ? WHERE id = ? AND deleted_at IS NULL`
+		args = []interface{}{time.Now().Format("2006-01-02 15:04:05"), adminID}
+	case "lock":
+		query = `UPDATE links SET is_locked = 1 WHERE id = ? AND deleted_at IS NULL`
+	case "unlock":
+		query = `UPDATE links SET is_locked = 0 WHERE id = ? AND deleted_at IS NULL`
+	case "force_private":
+		query = `UPDATE links SET is_private = 1, is_locked = 1 WHERE id = ? AND deleted_at IS NULL`
+	default:
+		return nil, fmt.Errorf("unknown bulk action %q", action)
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	results := make([]models.BulkResult, 0, len(linkIDs))
+	for _, id := range linkIDs {
+		result := models.BulkResult{ID: id}
+		if err := expectRow(stmt.Exec(append(args, id)...)); err == ErrNotFound {
+			result.Error = "link not found"
+		} else if err != nil {
+			return nil, err
+		} else {
+			result.OK = true
+		}
+		results = append(results, result)
+	}
+	return results, tx.Commit()
+}
+
+// BulkUpdateLinks applies an update to several of the user's links in one
+// transaction. allowPublic decides whether a link's URL may be made public.
+func (db *Database) BulkUpdateLinks(userID int, linkIDs []int, update models.BulkLinkUpdate, allowPublic func(url string) bool) ([]models.BulkResult, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	results := make([]models.BulkResult, 0, len(linkIDs))
+	for _, id := range linkIDs {
+		result := models.BulkResult{ID: id}
+
+		var linkURL string
+		var tags sql.NullString
+		var isLocked bool
+		err := tx.QueryRow(`SELECT url, tags, COALESCE(is_locked, 0) FROM links WHERE id = ? AND user_id = ? AND deleted_at IS NULL`, id, userID).
+			Scan(&linkURL, &tags, &isLocked)
+		if err == ErrNotFound {
+			result.Error = "link not found"
+			results = append(results, result)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch update.Action {
+		case "tag":
+			_, err = tx.Exec(`UPDATE links SET tags = ? WHERE id = ?`, mergeTags(tags.String, update.Tags), id)
+		case "category":
+			_, err = tx.Exec(`UPDATE links SET category = ? WHERE id = ?`, update.Category, id)
+		case "privacy":
+			if isLocked {
+				result.Error = "link privacy is locked by administrator"
+			} else if !update.IsPrivate && !allowPublic(linkURL) {
+				result.Error = "links to this domain cannot be shared publicly"
+			} else {
+				_, err = tx.Exec(`UPDATE links SET is_private = ? WHERE id = ?`, update.IsPrivate, id)
+			}
+		case "delete":
+			_, err = tx.Exec(`UPDATE links SET deleted_at = ?, deleted_by = ? WHERE id = ?`, now, userID, id)
+		default:
+			return nil, fmt.Errorf("unknown bulk action %q", update.Action)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result.OK = result.Error == ""
+		results = append(results, result)
+	}
+	return results, tx.Commit()
+}
+
+// mergeTags adds the comma-separated tags in added to existing, skipping
+// duplicates and keeping at most 10 tags
+func mergeTags(existing, added string) string {
+	var merged []string
+	seen := map[string]bool{}
+	for _, tag := range strings.Split(existing+","+added, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] || len(merged) >= 10 {
+			continue
+		}
+		seen[tag] = true
+		merged = append(merged, tag)
+	}
+	return strings.Join(merged, ",")
+}
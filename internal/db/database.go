@@ -2,7 +2,8 @@ package db
 
 import (
 	"database/sql"
-	"fmt"
+	"strings"
+	"time"
 
 	"links/internal/models"
 
@@ -36,22 +37,32 @@ func (db *Database) CreateUser(username, hashedPassword, createdAt string) (int6
 	query := `INSERT INTO users (username, password, created_at, is_admin) VALUES (?, ?, ?, 0)`
 	result, err := db.conn.Exec(query, username, hashedPassword, createdAt)
 	if err != nil {
-		return 0, err
+		return 0, conflictError(err)
 	}
 	return result.LastInsertId()
 }
 
 func (db *Database) GetUserByUsername(username string) (*models.User, string, error) {
-	query := `SELECT id, username, password, created_at, COALESCE(is_admin, 0) FROM users WHERE username = ?`
+	query := `SELECT id, username, COALESCE(password, ''), created_at, COALESCE(is_admin, 0), COALESCE(role, 'user'), COALESCE(totp_enabled, 0), EXISTS (SELECT 1 FROM webauthn_credentials c WHERE c.user_id = users.id), COALESCE(session_version, 0), suspended_at, suspended_until, suspend_reason FROM users WHERE username = ? AND deleted_at IS NULL`
 	var user models.User
 	var hashedPassword string
-	err := db.conn.QueryRow(query, username).Scan(&user.ID, &user.Username, &hashedPassword, &user.CreatedAt, &user.IsAdmin)
+	err := db.conn.QueryRow(query, username).Scan(&user.ID, &user.Username, &hashedPassword, &user.CreatedAt, &user.IsAdmin, &user.Role, &user.TOTPEnabled, &user.PasskeyEnabled, &user.SessionVersion, &user.SuspendedAt, &user.SuspendedUntil, &user.SuspendReason)
 	if err != nil {
 		return nil, "", err
 	}
 	return &user, hashedPassword, nil
 }
 
+func (db *Database) GetUserByID(userID int) (*models.User, error) {
+	query := `SELECT id, username, created_at, COALESCE(is_admin, 0), COALESCE(role, 'user'), COALESCE(totp_enabled, 0), EXISTS (SELECT 1 FROM webauthn_credentials c WHERE c.user_id = users.id), COALESCE(session_version, 0), suspended_at, suspended_until, suspend_reason FROM users WHERE id = ? AND deleted_at IS NULL`
+	var user models.User
+	err := db.conn.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.CreatedAt, &user.IsAdmin, &user.Role, &user.TOTPEnabled, &user.PasskeyEnabled, &user.SessionVersion, &user.SuspendedAt, &user.SuspendedUntil, &user.SuspendReason)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (db *Database) CreateLink(userID int, url string, description, tags, category *string, createdAt string, isPrivate bool) (int64, error) {
 	query := `INSERT INTO links (user_id, url, description, tags, category, created_at, is_private) VALUES (?, ?, ?, ?, ?, ?, ?)`
 	result, err := db.conn.Exec(query, userID, url, description, tags, category, createdAt, isPrivate)
@@ -62,7 +73,7 @@ func (db *Database) CreateLink(userID int, url string, description, tags, catego
 }
 
 func (db *Database) GetLinksByUserID(userID int) ([]models.Link, error) {
-	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username FROM links l JOIN users u ON l.user_id = u.id WHERE l.user_id = ? ORDER BY l.created_at DESC`
+	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username FROM links l JOIN users u ON l.user_id = u.id WHERE l.user_id = ? AND l.deleted_at IS NULL ORDER BY l.created_at DESC`
 	rows, err := db.conn.Query(query, userID)
 	if err != nil {
 		return nil, err
@@ -82,19 +93,19 @@ func (db *Database) GetLinksByUserID(userID int) ([]models.Link, error) {
 }
 
 func (db *Database) CreateOAuthUser(email, username, googleID, createdAt string) (int64, error) {
-	query := `INSERT INTO users (username, email, google_id, created_at, is_admin) VALUES (?, ?, ?, ?, 0)`
+	query := `INSERT INTO users (username, email, google_id, created_at, is_admin, email_verified) VALUES (?, ?, ?, ?, 0, 1)`
 	result, err := db.conn.Exec(query, username, email, googleID, createdAt)
 	if err != nil {
-		return 0, err
+		return 0, conflictError(err)
 	}
 	return result.LastInsertId()
 }
 
 func (db *Database) GetUserByGoogleID(googleID string) (*models.User, error) {
-	query := `SELECT id, username, email, created_at, COALESCE(is_admin, 0) FROM users WHERE google_id = ?`
+	query := `SELECT id, username, email, created_at, COALESCE(is_admin, 0), COALESCE(role, 'user'), COALESCE(totp_enabled, 0), EXISTS (SELECT 1 FROM webauthn_credentials c WHERE c.user_id = users.id), COALESCE(session_version, 0), deleted_at, suspended_at, suspended_until, suspend_reason FROM users WHERE google_id = ?`
 	var user models.User
 	var userEmail string
-	err := db.conn.QueryRow(query, googleID).Scan(&user.ID, &user.Username, &userEmail, &user.CreatedAt, &user.IsAdmin)
+	err := db.conn.QueryRow(query, googleID).Scan(&user.ID, &user.Username, &userEmail, &user.CreatedAt, &user.IsAdmin, &user.Role, &user.TOTPEnabled, &user.PasskeyEnabled, &user.SessionVersion, &user.DeletedAt, &user.SuspendedAt, &user.SuspendedUntil, &user.SuspendReason)
 	if err != nil {
 		return nil, err
 	}
@@ -102,10 +113,10 @@ func (db *Database) GetUserByGoogleID(googleID string) (*models.User, error) {
 }
 
 func (db *Database) GetUserByEmail(email string) (*models.User, error) {
-	query := `SELECT id, username, email, created_at, COALESCE(is_admin, 0) FROM users WHERE email = ?`
+	query := `SELECT id, username, email, created_at, COALESCE(is_admin, 0), COALESCE(role, 'user'), COALESCE(totp_enabled, 0), EXISTS (SELECT 1 FROM webauthn_credentials c WHERE c.user_id = users.id), COALESCE(session_version, 0), deleted_at, suspended_at, suspended_until, suspend_reason FROM users WHERE email = ? AND COALESCE(email_verified, 0) = 1`
 	var user models.User
 	var userEmail string
-	err := db.conn.QueryRow(query, email).Scan(&user.ID, &user.Username, &userEmail, &user.CreatedAt, &user.IsAdmin)
+	err := db.conn.QueryRow(query, email).Scan(&user.ID, &user.Username, &userEmail, &user.CreatedAt, &user.IsAdmin, &user.Role, &user.TOTPEnabled, &user.PasskeyEnabled, &user.SessionVersion, &user.DeletedAt, &user.SuspendedAt, &user.SuspendedUntil, &user.SuspendReason)
 	if err != nil {
 		return nil, err
 	}
@@ -113,8 +124,8 @@ func (db *Database) GetUserByEmail(email string) (*models.User, error) {
 }
 
 func (db *Database) GetPublicLinks() ([]models.Link, error) {
-	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username FROM links l JOIN users u ON l.user_id = u.id WHERE l.is_private = 0 ORDER BY l.created_at DESC`
-	rows, err := db.conn.Query(query)
+	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username FROM links l JOIN users u ON l.user_id = u.id WHERE ` + publicLinks + ` ORDER BY l.created_at DESC`
+	rows, err := db.conn.Query(query, time.Now().Format("2006-01-02 15:04:05"))
 	if err != nil {
 		return nil, err
 	}
@@ -132,8 +143,56 @@ func (db *Database) GetPublicLinks() ([]models.Link, error) {
 	return links, nil
 }
 
+// GetLink returns one of the user's links that is not in the trash
+func (db *Database) GetLink(linkID, userID int) (*models.Link, error) {
+	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username FROM links l JOIN users u ON l.user_id = u.id WHERE l.id = ? AND l.user_id = ? AND l.deleted_at IS NULL`
+	var link models.Link
+	err := db.conn.QueryRow(query, linkID, userID).Scan(&link.ID, &link.UserID, &link.URL, &link.Description, &link.Tags, &link.Category, &link.CreatedAt, &link.IsPrivate, &link.IsFavorite, &link.AccessCount, &link.IsLocked, &link.Username)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// ListLinks returns a page of the user's links, newest first, and how many there are
+func (db *Database) ListLinks(userID, limit, offset int) ([]models.Link, int, error) {
+	return db.linkPage(`l.user_id = ? AND l.deleted_at IS NULL`, []interface{}{userID}, limit, offset)
+}
+
+// ListPublicLinks returns a page of the links shown on the public page, newest first
+func (db *Database) ListPublicLinks(limit, offset int) ([]models.Link, int, error) {
+	return db.linkPage(publicLinks, []interface{}{time.Now().Format("2006-01-02 15:04:05")}, limit, offset)
+}
+
+func (db *Database) linkPage(where string, args []interface{}, limit, offset int) ([]models.Link, int, error) {
+	var total int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM links l JOIN users u ON l.user_id = u.id WHERE `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username
+		FROM links l JOIN users u ON l.user_id = u.id WHERE ` + where + ` ORDER BY l.created_at DESC, l.id DESC LIMIT ? OFFSET ?`
+	rows, err := db.conn.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	links := []models.Link{}
+	for rows.Next() {
+		var link models.Link
+		err := rows.Scan(&link.ID, &link.UserID, &link.URL, &link.Description, &link.Tags, &link.Category, &link.CreatedAt, &link.IsPrivate, &link.IsFavorite, &link.AccessCount, &link.IsLocked, &link.Username)
+		if err != nil {
+			return nil, 0, err
+		}
+		links = append(links, link)
+	}
+	return links, total, rows.Err()
+}
+
 func (db *Database) ToggleFavorite(linkID, userID int, isFavorite bool) error {
-	query := `UPDATE links SET is_favorite = ? WHERE id = ? AND user_id = ?`
+	query := `UPDATE links SET is_favorite = ? WHERE id = ? AND user_id = ? AND deleted_at IS NULL`
 	result, err := db.conn.Exec(query, isFavorite, linkID, userID)
 	if err != nil {
 		return err
@@ -145,7 +204,7 @@ func (db *Database) ToggleFavorite(linkID, userID int, isFavorite bool) error {
 	}
 	
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
 	
 	return nil
@@ -153,7 +212,7 @@ func (db *Database) ToggleFavorite(linkID, userID int, isFavorite bool) error {
 
 func (db *Database) TogglePrivacy(linkID, userID int, isPrivate bool) error {
 	// Check if link is locked first
-	query := `SELECT is_locked FROM links WHERE id = ? AND user_id = ?`
+	query := `SELECT is_locked FROM links WHERE id = ? AND user_id = ? AND deleted_at IS NULL`
 	var isLocked bool
 	err := db.conn.QueryRow(query, linkID, userID).Scan(&isLocked)
 	if err != nil {
@@ -162,7 +221,7 @@ func (db *Database) TogglePrivacy(linkID, userID int, isPrivate bool) error {
 	
 	// If locked, prevent privacy changes
 	if isLocked {
-		return fmt.Errorf("link privacy is locked by administrator")
+		return ErrLocked
 	}
 	
 	query = `UPDATE links SET is_private = ? WHERE id = ? AND user_id = ?`
@@ -177,15 +236,16 @@ func (db *Database) TogglePrivacy(linkID, userID int, isPrivate bool) error {
 	}
 	
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
 	
 	return nil
 }
 
+// DeleteLink moves the user's link to their trash
 func (db *Database) DeleteLink(linkID, userID int) error {
-	query := `DELETE FROM links WHERE id = ? AND user_id = ?`
-	result, err := db.conn.Exec(query, linkID, userID)
+	query := `UPDATE links SET deleted_at = ?, deleted_by = ? WHERE id = ? AND user_id = ? AND deleted_at IS NULL`
+	result, err := db.conn.Exec(query, time.Now().Format("2006-01-02 15:04:05"), userID, linkID, userID)
 	if err != nil {
 		return err
 	}
@@ -196,62 +256,202 @@ func (db *Database) DeleteLink(linkID, userID int) error {
 	}
 	
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
 	
 	return nil
 }
 
 func (db *Database) IncrementAccessCount(linkID int) error {
-	query := `UPDATE links SET access_count = access_count + 1 WHERE id = ?`
+	query := `UPDATE links SET access_count = access_count + 1 WHERE id = ? AND deleted_at IS NULL`
 	_, err := db.conn.Exec(query, linkID)
 	return err
 }
 
 // Admin functions
-func (db *Database) GetAllLinks() ([]models.Link, error) {
-	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username FROM links l JOIN users u ON l.user_id = u.id ORDER BY l.created_at DESC`
-	rows, err := db.conn.Query(query)
+
+// linkHost extracts the lowercased host from links.url: the text between "://" and
+// the first ":", "/", "?" or "#"
+const linkHost = `lower(substr(replace(replace(replace(substr(l.url, instr(l.url, '://') + 3), ':', '/'), '?', '/'), '#', '/') || '/', 1,
+	instr(replace(replace(replace(substr(l.url, instr(l.url, '://') + 3), ':', '/'), '?', '/'), '#', '/') || '/', '/') - 1))`
+
+var linkSortColumns = map[string]string{
+	"created_at":   "l.created_at",
+	"access_count": "COALESCE(l.access_count, 0)",
+	"username":     "u.username",
+	"url":          "l.url",
+}
+
+// adminLinkFrom builds the FROM and WHERE clauses selecting the links (aliased l,
+// joined with their owner u) that match the filter
+func adminLinkFrom(filter models.LinkFilter) (string, []interface{}) {
+	conditions := []string{"l.deleted_at IS NULL", "u.deleted_at IS NULL"}
+	var args []interface{}
+
+	if filter.Query != "" {
+		conditions = append(conditions, "(l.url LIKE ? ESCAPE '\\' OR l.description LIKE ? ESCAPE '\\')")
+		pattern := "%" + escapeLike(filter.Query) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if filter.Username != "" {
+		conditions = append(conditions, "u.username = ?")
+		args = append(args, filter.Username)
+	}
+	if filter.Domain != "" {
+		domain := strings.ToLower(strings.TrimPrefix(filter.Domain, "*."))
+		conditions = append(conditions, "("+linkHost+" = ? OR "+linkHost+" LIKE ? ESCAPE '\\')")
+		args = append(args, domain, "%."+escapeLike(domain))
+	}
+	if filter.IsPrivate != nil {
+		conditions = append(conditions, "l.is_private = ?")
+		args = append(args, *filter.IsPrivate)
+	}
+	if filter.IsLocked != nil {
+		conditions = append(conditions, "COALESCE(l.is_locked, 0) = ?")
+		args = append(args, *filter.IsLocked)
+	}
+	if filter.Since != "" {
+		conditions = append(conditions, "l.created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if filter.Until != "" {
+		conditions = append(conditions, "l.created_at < ?")
+		args = append(args, filter.Until)
+	}
+
+	return ` FROM links l JOIN users u ON l.user_id = u.id WHERE ` + strings.Join(conditions, " AND "), args
+}
+
+// GetAdminLinks returns one page of links matching the filter along with the total
+// match count
+func (db *Database) GetAdminLinks(filter models.LinkFilter) ([]models.Link, int, error) {
+	from, args := adminLinkFrom(filter)
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	sortColumn, ok := linkSortColumns[filter.Sort]
+	if !ok {
+		sortColumn = linkSortColumns["created_at"]
+	}
+	direction := " ASC"
+	if filter.Desc {
+		direction = " DESC"
+	}
+
+	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username` +
+		from + ` ORDER BY ` + sortColumn + direction + `, l.id` + direction + ` LIMIT ? OFFSET ?`
+	rows, err := db.conn.Query(query, append(args, filter.Limit, filter.Offset)...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
-	var links []models.Link
+	links := []models.Link{}
 	for rows.Next() {
 		var link models.Link
 		err := rows.Scan(&link.ID, &link.UserID, &link.URL, &link.Description, &link.Tags, &link.Category, &link.CreatedAt, &link.IsPrivate, &link.IsFavorite, &link.AccessCount, &link.IsLocked, &link.Username)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		links = append(links, link)
 	}
-	return links, nil
+	return links, total, rows.Err()
 }
 
-func (db *Database) GetAllUsers() ([]models.User, error) {
-	query := `SELECT id, username, created_at, COALESCE(is_admin, 0) FROM users ORDER BY created_at DESC`
-	rows, err := db.conn.Query(query)
+// AdminGetLink returns any link by ID regardless of owner or privacy
+func (db *Database) AdminGetLink(linkID int) (*models.Link, error) {
+	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username FROM links l JOIN users u ON l.user_id = u.id WHERE l.id = ? AND l.deleted_at IS NULL`
+	var link models.Link
+	err := db.conn.QueryRow(query, linkID).Scan(&link.ID, &link.UserID, &link.URL, &link.Description, &link.Tags, &link.Category, &link.CreatedAt, &link.IsPrivate, &link.IsFavorite, &link.AccessCount, &link.IsLocked, &link.Username)
 	if err != nil {
 		return nil, err
 	}
+	return &link, nil
+}
+
+var userSortColumns = map[string]string{
+	"created_at": "created_at",
+	"username":   "username",
+	"link_count": "link_count",
+	"last_login": "last_login_at",
+}
+
+// GetAdminUsers returns one page of users matching the filter, with their link
+// counts and last sign-in, along with the total match count
+func (db *Database) GetAdminUsers(filter models.UserFilter) ([]models.AdminUser, int, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+
+	if filter.Query != "" {
+		conditions = append(conditions, "username LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.Role != "" {
+		conditions = append(conditions, "COALESCE(role, 'user') = ?")
+		args = append(args, filter.Role)
+	}
+	if filter.IsAdmin != nil {
+		conditions = append(conditions, "COALESCE(is_admin, 0) = ?")
+		args = append(args, *filter.IsAdmin)
+	}
+	if filter.Since != "" {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if filter.Until != "" {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until)
+	}
+
+	where := ` WHERE ` + strings.Join(conditions, " AND ")
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	sortColumn, ok := userSortColumns[filter.Sort]
+	if !ok {
+		sortColumn = userSortColumns["created_at"]
+	}
+	direction := " ASC"
+	if filter.Desc {
+		direction = " DESC"
+	}
+
+	query := `SELECT * FROM (
+			SELECT id, username, created_at, COALESCE(is_admin, 0), COALESCE(role, 'user'), COALESCE(totp_enabled, 0), suspended_at, suspended_until, suspend_reason,
+				(SELECT COUNT(*) FROM links l WHERE l.user_id = users.id AND l.deleted_at IS NULL) AS link_count,
+				(SELECT MAX(a.created_at) FROM audit_log a WHERE a.actor_id = users.id AND a.action = 'login.success') AS last_login_at
+			FROM users` + where + `
+		) ORDER BY ` + sortColumn + direction + `, id` + direction + ` LIMIT ? OFFSET ?`
+	rows, err := db.conn.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
 	defer rows.Close()
 
-	var users []models.User
+	users := []models.AdminUser{}
 	for rows.Next() {
-		var user models.User
-		err := rows.Scan(&user.ID, &user.Username, &user.CreatedAt, &user.IsAdmin)
+		var user models.AdminUser
+		err := rows.Scan(&user.ID, &user.Username, &user.CreatedAt, &user.IsAdmin, &user.Role, &user.TOTPEnabled, &user.SuspendedAt, &user.SuspendedUntil, &user.SuspendReason,
+			&user.LinkCount, &user.LastLoginAt)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		users = append(users, user)
 	}
-	return users, nil
+	return users, total, rows.Err()
 }
 
-func (db *Database) AdminDeleteLink(linkID int) error {
-	query := `DELETE FROM links WHERE id = ?`
-	result, err := db.conn.Exec(query, linkID)
+// AdminDeleteLink soft-deletes any link. Links removed by an admin stay out of the
+// owner's trash so they cannot be restored by the owner.
+func (db *Database) AdminDeleteLink(linkID, adminID int) error {
+	query := `UPDATE links SET deleted_at = ?, deleted_by = ? WHERE id = ? AND deleted_at IS NULL`
+	result, err := db.conn.Exec(query, time.Now().Format("2006-01-02 15:04:05"), adminID, linkID)
 	if err != nil {
 		return err
 	}
@@ -262,14 +462,14 @@ func (db *Database) AdminDeleteLink(linkID int) error {
 	}
 	
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
 	
 	return nil
 }
 
 func (db *Database) AdminToggleLinkLock(linkID int, isLocked bool) error {
-	query := `UPDATE links SET is_locked = ? WHERE id = ?`
+	query := `UPDATE links SET is_locked = ? WHERE id = ? AND deleted_at IS NULL`
 	result, err := db.conn.Exec(query, isLocked, linkID)
 	if err != nil {
 		return err
@@ -281,14 +481,14 @@ func (db *Database) AdminToggleLinkLock(linkID int, isLocked bool) error {
 	}
 	
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
 	
 	return nil
 }
 
 func (db *Database) AdminForcePrivateLink(linkID int) error {
-	query := `UPDATE links SET is_private = 1, is_locked = 1 WHERE id = ?`
+	query := `UPDATE links SET is_private = 1, is_locked = 1 WHERE id = ? AND deleted_at IS NULL`
 	result, err := db.conn.Exec(query, linkID)
 	if err != nil {
 		return err
@@ -300,15 +500,24 @@ func (db *Database) AdminForcePrivateLink(linkID int) error {
 	}
 	
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
 	
 	return nil
 }
 
 func (db *Database) AdminToggleUserAdmin(userID int, isAdmin bool) error {
-	query := `UPDATE users SET is_admin = ? WHERE id = ?`
-	result, err := db.conn.Exec(query, isAdmin, userID)
+	role := "user"
+	if isAdmin {
+		role = "admin"
+	}
+	return db.AdminSetUserRole(userID, role)
+}
+
+// AdminSetUserRole assigns a role, keeping the legacy is_admin flag in sync
+func (db *Database) AdminSetUserRole(userID int, role string) error {
+	query := `UPDATE users SET role = ?, is_admin = ? WHERE id = ?`
+	result, err := db.conn.Exec(query, role, role == "admin", userID)
 	if err != nil {
 		return err
 	}
@@ -319,38 +528,82 @@ func (db *Database) AdminToggleUserAdmin(userID int, isAdmin bool) error {
 	}
 	
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
 	
 	return nil
 }
 
+// AdminDeleteUser soft-deletes an account. The user can no longer sign in and their
+// links are hidden until the account is restored or purged.
 func (db *Database) AdminDeleteUser(userID int) error {
-	// First delete all user's links
-	_, err := db.conn.Exec(`DELETE FROM links WHERE user_id = ?`, userID)
+	query := `UPDATE users SET deleted_at = ?, session_version = COALESCE(session_version, 0) + 1 WHERE id = ? AND deleted_at IS NULL`
+	result, err := db.conn.Exec(query, time.Now().Format("2006-01-02 15:04:05"), userID)
 	if err != nil {
 		return err
 	}
-	
-	// Then delete the user
-	query := `DELETE FROM users WHERE id = ?`
-	result, err := db.conn.Exec(query, userID)
-	if err != nil {
-		return err
-	}
-	
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	
+
 	if rowsAffected == 0 {
-		return sql.ErrNoRows
+		return ErrNotFound
 	}
-	
+
 	return nil
 }
 
+// PurgeUser permanently deletes an account along with everything keyed to it. Foreign
+// keys are not enforced, so each table is cleared here, in one transaction so a failure
+// leaves the account intact.
+func (db *Database) PurgeUser(userID int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var username string
+	if err := tx.QueryRow(`SELECT username FROM users WHERE id = ?`, userID).Scan(&username); err != nil {
+		return err
+	}
+
+	// Children before the rows they reference
+	statements := []string{
+		`DELETE FROM reports WHERE reporter_id = ?1 OR link_id IN (SELECT id FROM links WHERE user_id = ?1)`,
+		`DELETE FROM links WHERE user_id = ?`,
+		`DELETE FROM user_tokens WHERE user_id = ?`,
+		`DELETE FROM recovery_codes WHERE user_id = ?`,
+		`DELETE FROM webauthn_credentials WHERE user_id = ?`,
+		`DELETE FROM webauthn_challenges WHERE user_id = ?`,
+		`DELETE FROM api_tokens WHERE user_id = ?`,
+		`DELETE FROM webhook_deliveries WHERE webhook_id IN (SELECT id FROM webhooks WHERE user_id = ?)`,
+		`DELETE FROM webhooks WHERE user_id = ?`,
+		`DELETE FROM feed_items WHERE subscription_id IN (SELECT id FROM feed_subscriptions WHERE user_id = ?)`,
+		`DELETE FROM feed_subscriptions WHERE user_id = ?`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement, userID); err != nil {
+			return err
+		}
+	}
+
+	// Login failures are keyed by the username typed in, not the account
+	if _, err := tx.Exec(`DELETE FROM login_lockouts WHERE username = ?`, username); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM failed_logins WHERE username = ?`, username); err != nil {
+		return err
+	}
+
+	if err := expectRow(tx.Exec(`DELETE FROM users WHERE id = ?`, userID)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (db *Database) createTables() error {
 	// Create users table with OAuth support
 	usersTable := `
@@ -409,5 +662,309 @@ func (db *Database) createTables() error {
 	migrationQuery6 := `ALTER TABLE links ADD COLUMN is_locked BOOLEAN NOT NULL DEFAULT 0`
 	db.conn.Exec(migrationQuery6) // Ignore error if column already exists
 
+	// Add TOTP two-factor columns to users if they don't exist (migration)
+	db.conn.Exec(`ALTER TABLE users ADD COLUMN totp_secret TEXT`)
+	db.conn.Exec(`ALTER TABLE users ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT 0`)
+	db.conn.Exec(`ALTER TABLE users ADD COLUMN totp_last_step INTEGER NOT NULL DEFAULT 0`)
+
+	// Soft delete: deleted_at marks trashed rows, deleted_by records who removed a link (migration)
+	db.conn.Exec(`ALTER TABLE links ADD COLUMN deleted_at TEXT`)
+	db.conn.Exec(`ALTER TABLE links ADD COLUMN deleted_by INTEGER`)
+	db.conn.Exec(`ALTER TABLE users ADD COLUMN deleted_at TEXT`)
+
+	// Suspension state: suspended_until is NULL for an indefinite suspension (migration)
+	db.conn.Exec(`ALTER TABLE users ADD COLUMN suspended_at TEXT`)
+	db.conn.Exec(`ALTER TABLE users ADD COLUMN suspended_until TEXT`)
+	db.conn.Exec(`ALTER TABLE users ADD COLUMN suspend_reason TEXT`)
+
+	// Add role to users and map existing admins to the full admin role (migration)
+	if _, err := db.conn.Exec(`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'`); err == nil {
+		db.conn.Exec(`UPDATE users SET role = 'admin' WHERE is_admin = 1`)
+	}
+
+	// Add session_version to users; bumping it revokes all issued session tokens (migration)
+	db.conn.Exec(`ALTER TABLE users ADD COLUMN session_version INTEGER NOT NULL DEFAULT 0`)
+
+	// Add email_verified to users; Google-linked emails were verified by Google (migration)
+	if _, err := db.conn.Exec(`ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT 0`); err == nil {
+		db.conn.Exec(`UPDATE users SET email_verified = 1 WHERE google_id IS NOT NULL AND email IS NOT NULL`)
+	}
+
+	// Hash of the token in the user's private feed URL; NULL while disabled (migration)
+	db.conn.Exec(`ALTER TABLE users ADD COLUMN feed_token_hash TEXT`)
+	db.conn.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_feed_token ON users (feed_token_hash)`)
+
+	// Create one-time recovery codes table (stored hashed)
+	recoveryCodesTable := `
+	CREATE TABLE IF NOT EXISTS recovery_codes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		code_hash TEXT NOT NULL,
+		used_at TEXT,
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	)`
+
+	if _, err := db.conn.Exec(recoveryCodesTable); err != nil {
+		return err
+	}
+
+	// Create key/value settings table for instance-wide policies
+	settingsTable := `
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	)`
+
+	if _, err := db.conn.Exec(settingsTable); err != nil {
+		return err
+	}
+
+	// Single-use tokens for password reset and email verification (stored hashed)
+	userTokensTable := `
+	CREATE TABLE IF NOT EXISTS user_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		purpose TEXT NOT NULL,
+		email TEXT NOT NULL,
+		expires_at INTEGER NOT NULL,
+		used_at TEXT,
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	)`
+
+	if _, err := db.conn.Exec(userTokensTable); err != nil {
+		return err
+	}
+
+	// Personal API tokens for scripts, extensions and the bookmarklet (stored hashed)
+	apiTokensTable := `
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		created_at TEXT NOT NULL,
+		last_used_at TEXT,
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	)`
+
+	if _, err := db.conn.Exec(apiTokensTable); err != nil {
+		return err
+	}
+
+	// Outgoing webhooks for link events; user_id is NULL for site-wide webhooks
+	webhooksTable := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT 1,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	)`
+
+	if _, err := db.conn.Exec(webhooksTable); err != nil {
+		return err
+	}
+
+	// Queue and log of webhook deliveries, retried until delivered or failed
+	webhookDeliveriesTable := `
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		response_code INTEGER,
+		error TEXT,
+		next_attempt_at INTEGER NOT NULL,
+		last_attempt_at TEXT,
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (webhook_id) REFERENCES webhooks (id)
+	)`
+
+	if _, err := db.conn.Exec(webhookDeliveriesTable); err != nil {
+		return err
+	}
+
+	db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at)`)
+	db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, id)`)
+
+	// Feeds users subscribe to; new items are saved as links. next_check_at is a Unix
+	// time so the poller can claim due subscriptions with a lease, like deliveries.
+	feedSubscriptionsTable := `
+	CREATE TABLE IF NOT EXISTS feed_subscriptions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		name TEXT NOT NULL,
+		is_private BOOLEAN NOT NULL DEFAULT 0,
+		active BOOLEAN NOT NULL DEFAULT 1,
+		etag TEXT,
+		last_modified TEXT,
+		failures INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		last_checked_at TEXT,
+		next_check_at INTEGER NOT NULL,
+		created_at TEXT NOT NULL,
+		UNIQUE (user_id, url),
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	)`
+
+	if _, err := db.conn.Exec(feedSubscriptionsTable); err != nil {
+		return err
+	}
+
+	// Items already seen in each subscription, so they are saved only once
+	feedItemsTable := `
+	CREATE TABLE IF NOT EXISTS feed_items (
+		subscription_id INTEGER NOT NULL,
+		guid TEXT NOT NULL,
+		url TEXT NOT NULL,
+		seen_at TEXT NOT NULL,
+		PRIMARY KEY (subscription_id, guid),
+		FOREIGN KEY (subscription_id) REFERENCES feed_subscriptions (id)
+	)`
+
+	if _, err := db.conn.Exec(feedItemsTable); err != nil {
+		return err
+	}
+
+	db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_feed_subscriptions_due ON feed_subscriptions (active, next_check_at)`)
+	db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_feed_items_url ON feed_items (subscription_id, url)`)
+
+	// Create WebAuthn (passkey) credentials table
+	webAuthnCredentialsTable := `
+	CREATE TABLE IF NOT EXISTS webauthn_credentials (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		credential_id TEXT UNIQUE NOT NULL,
+		public_key BLOB NOT NULL,
+		sign_count INTEGER NOT NULL DEFAULT 0,
+		name TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		last_used_at TEXT,
+		FOREIGN KEY (user_id) REFERENCES users (id)
+	)`
+
+	if _, err := db.conn.Exec(webAuthnCredentialsTable); err != nil {
+		return err
+	}
+
+	// Pending WebAuthn ceremonies; each challenge can be consumed once
+	webAuthnChallengesTable := `
+	CREATE TABLE IF NOT EXISTS webauthn_challenges (
+		challenge TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		purpose TEXT NOT NULL,
+		expires_at INTEGER NOT NULL
+	)`
+
+	if _, err := db.conn.Exec(webAuthnChallengesTable); err != nil {
+		return err
+	}
+
+	// Per-account failed login tracking for backoff and lockout
+	loginLockoutsTable := `
+	CREATE TABLE IF NOT EXISTS login_lockouts (
+		username TEXT PRIMARY KEY,
+		failed_count INTEGER NOT NULL DEFAULT 0,
+		locked_until INTEGER NOT NULL DEFAULT 0,
+		last_failed_at TEXT
+	)`
+
+	if _, err := db.conn.Exec(loginLockoutsTable); err != nil {
+		return err
+	}
+
+	// Audit record of failed login attempts
+	failedLoginsTable := `
+	CREATE TABLE IF NOT EXISTS failed_logins (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL,
+		ip TEXT NOT NULL,
+		user_agent TEXT,
+		reason TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	)`
+
+	if _, err := db.conn.Exec(failedLoginsTable); err != nil {
+		return err
+	}
+
+	db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_failed_logins_username ON failed_logins (username, created_at)`)
+
+	// User reports against public links and their moderation outcome
+	reportsTable := `
+	CREATE TABLE IF NOT EXISTS reports (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		link_id INTEGER NOT NULL,
+		reporter_id INTEGER NOT NULL,
+		reason TEXT NOT NULL,
+		details TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'open',
+		resolution TEXT,
+		resolved_by INTEGER,
+		resolved_at TEXT,
+		note TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL,
+		FOREIGN KEY (link_id) REFERENCES links (id) ON DELETE CASCADE
+	)`
+
+	if _, err := db.conn.Exec(reportsTable); err != nil {
+		return err
+	}
+
+	db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_reports_status ON reports (status, link_id)`)
+
+	// Admin-managed block/allow policy for the domains links point to
+	domainRulesTable := `
+	CREATE TABLE IF NOT EXISTS domain_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		pattern TEXT NOT NULL UNIQUE,
+		action TEXT NOT NULL,
+		note TEXT NOT NULL DEFAULT '',
+		created_by INTEGER,
+		created_at TEXT NOT NULL
+	)`
+
+	if _, err := db.conn.Exec(domainRulesTable); err != nil {
+		return err
+	}
+
+	// Append-only audit trail of admin and security-relevant actions. actor_id is not a
+	// foreign key so entries outlive deleted accounts.
+	auditLogTable := `
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		actor_id INTEGER,
+		actor_username TEXT NOT NULL DEFAULT '',
+		action TEXT NOT NULL,
+		target_type TEXT NOT NULL DEFAULT '',
+		target_id TEXT NOT NULL DEFAULT '',
+		before_value TEXT NOT NULL DEFAULT '',
+		after_value TEXT NOT NULL DEFAULT '',
+		ip TEXT NOT NULL DEFAULT '',
+		user_agent TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL
+	)`
+
+	if _, err := db.conn.Exec(auditLogTable); err != nil {
+		return err
+	}
+
+	db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log (action, created_at)`)
+	db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log (actor_username, created_at)`)
+	db.conn.Exec(`CREATE INDEX IF NOT EXISTS idx_audit_log_actor_id ON audit_log (actor_id, action, created_at)`)
+
+	// Reject edits and deletions at the database level
+	db.conn.Exec(`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END`)
+	db.conn.Exec(`CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN SELECT RAISE(ABORT, 'audit_log is append-only'); END`)
+
 	return nil
 }
\ No newline at end of file
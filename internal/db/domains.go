@@ -0,0 +1,112 @@
+package db
+
+import (
+	"net/url"
+	"time"
+
+	"links/internal/models"
+)
+
+func (db *Database) GetDomainRules() ([]models.DomainRule, error) {
+	query := `SELECT d.id, d.pattern, d.action, d.note, COALESCE(u.username, ''), d.created_at
+		FROM domain_rules d LEFT JOIN users u ON d.created_by = u.id ORDER BY d.pattern`
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.DomainRule{}
+	for rows.Next() {
+		var rule models.DomainRule
+		if err := rows.Scan(&rule.ID, &rule.Pattern, &rule.Action, &rule.Note, &rule.CreatedBy, &rule.CreatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// SaveDomainRule creates the rule for pattern, or replaces the action and note of
+// an existing one
+func (db *Database) SaveDomainRule(pattern, action, note string, createdBy int) (int64, error) {
+	query := `INSERT INTO domain_rules (pattern, action, note, created_by, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (pattern) DO UPDATE SET action = excluded.action, note = excluded.note, created_by = excluded.created_by, created_at = excluded.created_at`
+	if _, err := db.conn.Exec(query, pattern, action, note, createdBy, time.Now().Format("2006-01-02 15:04:05")); err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err := db.conn.QueryRow(`SELECT id FROM domain_rules WHERE pattern = ?`, pattern).Scan(&id)
+	return id, err
+}
+
+func (db *Database) GetDomainRule(ruleID int) (*models.DomainRule, error) {
+	query := `SELECT d.id, d.pattern, d.action, d.note, COALESCE(u.username, ''), d.created_at
+		FROM domain_rules d LEFT JOIN users u ON d.created_by = u.id WHERE d.id = ?`
+	var rule models.DomainRule
+	err := db.conn.QueryRow(query, ruleID).Scan(&rule.ID, &rule.Pattern, &rule.Action, &rule.Note, &rule.CreatedBy, &rule.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (db *Database) DeleteDomainRule(ruleID int) error {
+	return expectRow(db.conn.Exec(`DELETE FROM domain_rules WHERE id = ?`, ruleID))
+}
+
+// ForcePrivateDisallowedLinks forces private and locks every public link whose host
+// the current rules do not allow to be shared, returning the IDs of the links changed
+func (db *Database) ForcePrivateDisallowedLinks() ([]int, error) {
+	rules, err := db.GetDomainRules()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(`SELECT id, url FROM links WHERE is_private = 0 AND deleted_at IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	var linkIDs []int
+	for rows.Next() {
+		var id int
+		var rawURL string
+		if err := rows.Scan(&id, &rawURL); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			continue
+		}
+		if models.DomainAction(rules, parsed.Hostname()) != models.DomainAllow {
+			linkIDs = append(linkIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var changed []int
+	for _, id := range linkIDs {
+		result, err := tx.Exec(`UPDATE links SET is_private = 1, is_locked = 1 WHERE id = ? AND is_private = 0`, id)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			changed = append(changed, id)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
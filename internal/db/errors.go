@@ -0,0 +1,34 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Errors returned by Database methods; test for them with errors.Is
+var (
+	// ErrNotFound means the row does not exist or is not visible to the caller. It is
+	// sql.ErrNoRows itself, so single-row lookups return it without translation.
+	ErrNotFound = sql.ErrNoRows
+
+	// ErrLocked means an administrator has locked the setting being changed
+	ErrLocked = errors.New("locked by administrator")
+
+	// ErrConflict means the change would duplicate something that must be unique,
+	// such as a username
+	ErrConflict = errors.New("conflicts with an existing record")
+)
+
+// conflictError translates a UNIQUE constraint violation into ErrConflict and
+// returns any other error unchanged
+func conflictError(err error) error {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"time"
+
+	"links/internal/models"
+)
+
+// UserFeedLinks returns the newest public links of a user. It returns ErrNotFound if
+// there is no such account.
+func (db *Database) UserFeedLinks(username string, limit int) ([]models.Link, error) {
+	var userID int
+	err := db.conn.QueryRow(`SELECT id FROM users WHERE username = ? AND deleted_at IS NULL`, username).Scan(&userID)
+	if err != nil {
+		return nil, err
+	}
+	links, _, err := db.linkPage(publicLinks+` AND l.user_id = ?`, []interface{}{time.Now().Format("2006-01-02 15:04:05"), userID}, limit, 0)
+	return links, err
+}
+
+// TagFeedLinks returns the newest public links carrying tag
+func (db *Database) TagFeedLinks(tag string, limit int) ([]models.Link, error) {
+	links, _, err := db.linkPage(publicLinks+` AND instr(',' || REPLACE(COALESCE(l.tags, ''), ' ', '') || ',', ?) > 0`,
+		[]interface{}{time.Now().Format("2006-01-02 15:04:05"), "," + tag + ","}, limit, 0)
+	return links, err
+}
+
+// UnreadFeedLinks returns the user's newest links, private ones included, that
+// have never been opened
+func (db *Database) UnreadFeedLinks(userID, limit int) ([]models.Link, error) {
+	links, _, err := db.linkPage(`l.user_id = ? AND l.deleted_at IS NULL AND COALESCE(l.access_count, 0) = 0`, []interface{}{userID}, limit, 0)
+	return links, err
+}
+
+// SetFeedToken stores the hash of the user's private feed token, replacing any
+// earlier one; nil disables the private feed
+func (db *Database) SetFeedToken(userID int, tokenHash *string) error {
+	return expectRow(db.conn.Exec(`UPDATE users SET feed_token_hash = ? WHERE id = ? AND deleted_at IS NULL`, tokenHash, userID))
+}
+
+// GetUserByFeedToken returns the owner of a private feed token
+func (db *Database) GetUserByFeedToken(tokenHash string) (*models.User, error) {
+	var userID int
+	err := db.conn.QueryRow(`SELECT id FROM users WHERE feed_token_hash = ? AND deleted_at IS NULL`, tokenHash).Scan(&userID)
+	if err != nil {
+		return nil, err
+	}
+	return db.GetUserByID(userID)
+}
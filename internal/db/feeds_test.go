@@ -0,0 +1,80 @@
+package db
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"links/internal/models"
+)
+
+// Every query for public links hides the same links: private, trashed, and those of
+// deleted or suspended accounts
+func TestPublicLinkQueries(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().Format("2006-01-02 15:04:05")
+	must := func(step string, err error) {
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+	}
+	newUser := func(username string) int {
+		id, err := db.CreateUser(username, "hash", now)
+		must("CreateUser", err)
+		return int(id)
+	}
+	tags := "go"
+	newLink := func(userID int, url string, private bool) int {
+		id, err := db.CreateLink(userID, url, nil, &tags, nil, now, private)
+		must("CreateLink", err)
+		return int(id)
+	}
+
+	alice, suspended, deleted, reporter := newUser("alice"), newUser("suspended"), newUser("deleted"), newUser("reporter")
+	visible := newLink(alice, "https://example.com/public", false)
+	hidden := []int{
+		newLink(alice, "https://example.com/private", true),
+		newLink(alice, "https://example.com/trashed", false),
+		newLink(suspended, "https://example.com/suspended", false),
+		newLink(deleted, "https://example.com/deleted", false),
+	}
+	must("DeleteLink", db.DeleteLink(hidden[1], alice))
+	must("SuspendUser", db.SuspendUser(suspended, "spam", nil))
+	must("DeleteUser", db.DeleteUser(deleted))
+
+	ids := func(links []models.Link, err error) []int {
+		t.Helper()
+		must("query", err)
+		var ids []int
+		for _, link := range links {
+			ids = append(ids, link.ID)
+		}
+		sort.Ints(ids)
+		return ids
+	}
+	page := func() ([]models.Link, error) {
+		links, _, err := db.ListPublicLinks(50, 0)
+		return links, err
+	}
+
+	for name, got := range map[string][]int{
+		"GetPublicLinks":  ids(db.GetPublicLinks()),
+		"ListPublicLinks": ids(page()),
+		"UserFeedLinks":   ids(db.UserFeedLinks("alice", 50)),
+		"TagFeedLinks":    ids(db.TagFeedLinks("go", 50)),
+	} {
+		if len(got) != 1 || got[0] != visible {
+			t.Errorf("%s = %v, want [%d]", name, got, visible)
+		}
+	}
+
+	// Only links on the public page can be reported
+	if _, err := db.CreateReport(visible, reporter, "spam", ""); err != nil {
+		t.Errorf("reporting a public link: %v", err)
+	}
+	for _, id := range hidden {
+		if _, err := db.CreateReport(id, reporter, "spam", ""); err != ErrNotFound {
+			t.Errorf("reporting hidden link %d = %v, want ErrNotFound", id, err)
+		}
+	}
+}
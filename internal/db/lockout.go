@@ -0,0 +1,110 @@
+package db
+
+import (
+	"time"
+
+	"links/internal/models"
+)
+
+// GetLockedUntil returns when the account's current lockout ends (Unix seconds), or 0
+func (db *Database) GetLockedUntil(username string) (int64, error) {
+	var lockedUntil int64
+	err := db.conn.QueryRow(`SELECT locked_until FROM login_lockouts WHERE username = ?`, username).Scan(&lockedUntil)
+	if err != nil {
+		if err == ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return lockedUntil, nil
+}
+
+// RecordFailedLogin writes an audit row and increments the account's consecutive
+// failure counter, returning the new count. The counter starts again from one when
+// the previous failure is older than window.
+func (db *Database) RecordFailedLogin(username, ip, userAgent, reason string, window time.Duration) (int, error) {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	windowStart := time.Now().Add(-window).Format("2006-01-02 15:04:05")
+
+	_, err := db.conn.Exec(`INSERT INTO failed_logins (username, ip, user_agent, reason, created_at) VALUES (?, ?, ?, ?, ?)`, username, ip, userAgent, reason, now)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO login_lockouts (username, failed_count, last_failed_at) VALUES (?, 1, ?)
+		ON CONFLICT(username) DO UPDATE SET
+			failed_count = CASE WHEN last_failed_at < ? THEN 1 ELSE failed_count + 1 END,
+			last_failed_at = excluded.last_failed_at
+		RETURNING failed_count`
+	var failedCount int
+	err = db.conn.QueryRow(query, username, now, windowStart).Scan(&failedCount)
+	return failedCount, err
+}
+
+func (db *Database) SetLockedUntil(username string, lockedUntil int64) error {
+	_, err := db.conn.Exec(`UPDATE login_lockouts SET locked_until = ? WHERE username = ?`, lockedUntil, username)
+	return err
+}
+
+// ClearLoginFailures resets the counter after a successful login or an admin unlock
+func (db *Database) ClearLoginFailures(username string) error {
+	_, err := db.conn.Exec(`DELETE FROM login_lockouts WHERE username = ?`, username)
+	return err
+}
+
+// PruneLoginFailures deletes failed attempts logged before before, and counters whose
+// last failure precedes windowStart once any lockout they set has ended. It returns
+// the number of attempts deleted.
+func (db *Database) PruneLoginFailures(before, windowStart time.Time) (int64, error) {
+	result, err := db.conn.Exec(`DELETE FROM failed_logins WHERE created_at < ?`, before.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = db.conn.Exec(`DELETE FROM login_lockouts WHERE last_failed_at < ? AND locked_until <= ?`, windowStart.Format("2006-01-02 15:04:05"), time.Now().Unix())
+	return deleted, err
+}
+
+// GetLoginLockouts lists accounts with outstanding failed attempts, most recent first
+func (db *Database) GetLoginLockouts() ([]models.LoginLockout, error) {
+	query := `SELECT username, failed_count, locked_until, last_failed_at FROM login_lockouts ORDER BY last_failed_at DESC`
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lockouts := []models.LoginLockout{}
+	for rows.Next() {
+		var lockout models.LoginLockout
+		if err := rows.Scan(&lockout.Username, &lockout.FailedCount, &lockout.LockedUntil, &lockout.LastFailedAt); err != nil {
+			return nil, err
+		}
+		lockouts = append(lockouts, lockout)
+	}
+	return lockouts, rows.Err()
+}
+
+// GetFailedLogins returns the most recent failed attempts, optionally for one username
+func (db *Database) GetFailedLogins(username string, limit int) ([]models.FailedLogin, error) {
+	query := `SELECT id, username, ip, user_agent, reason, created_at FROM failed_logins WHERE (? = '' OR username = ?) ORDER BY id DESC LIMIT ?`
+	rows, err := db.conn.Query(query, username, username, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.FailedLogin{}
+	for rows.Next() {
+		var attempt models.FailedLogin
+		if err := rows.Scan(&attempt.ID, &attempt.Username, &attempt.IP, &attempt.UserAgent, &attempt.Reason, &attempt.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
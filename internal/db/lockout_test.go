@@ -0,0 +1,89 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestDB(t *testing.T) *Database {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "links.db"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// ageFailures moves the account's failures back in time
+func ageFailures(t *testing.T, db *Database, username string, age time.Duration) {
+	t.Helper()
+	at := time.Now().Add(-age).Format("2006-01-02 15:04:05")
+	if _, err := db.conn.Exec(`UPDATE login_lockouts SET last_failed_at = ? WHERE username = ?`, at, username); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec(`UPDATE failed_logins SET created_at = ? WHERE username = ?`, at, username); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordFailedLoginWindow(t *testing.T) {
+	db := newTestDB(t)
+
+	for want := 1; want <= 3; want++ {
+		count, err := db.RecordFailedLogin("alice", "192.0.2.1", "test", "invalid_password", time.Hour)
+		if err != nil || count != want {
+			t.Fatalf("failure %d: count = %d, %v", want, count, err)
+		}
+	}
+
+	// Within the window failures keep adding up
+	ageFailures(t, db, "alice", 30*time.Minute)
+	if count, _ := db.RecordFailedLogin("alice", "192.0.2.1", "test", "invalid_password", time.Hour); count != 4 {
+		t.Errorf("failure inside the window: count = %d, want 4", count)
+	}
+
+	// After a quiet window the count starts over
+	ageFailures(t, db, "alice", 2*time.Hour)
+	if count, _ := db.RecordFailedLogin("alice", "192.0.2.1", "test", "invalid_password", time.Hour); count != 1 {
+		t.Errorf("failure after the window: count = %d, want 1", count)
+	}
+}
+
+func TestPruneLoginFailures(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, username := range []string{"old", "locked", "recent"} {
+		if _, err := db.RecordFailedLogin(username, "192.0.2.1", "test", "unknown_user", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ageFailures(t, db, "old", 48*time.Hour)
+	ageFailures(t, db, "locked", 48*time.Hour)
+	if err := db.SetLockedUntil("locked", time.Now().Add(time.Hour).Unix()); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := db.PruneLoginFailures(time.Now().Add(-24*time.Hour), time.Now().Add(-time.Hour))
+	if err != nil || deleted != 2 {
+		t.Fatalf("PruneLoginFailures = %d, %v; want 2 attempts deleted", deleted, err)
+	}
+
+	attempts, err := db.GetFailedLogins("", 10)
+	if err != nil || len(attempts) != 1 || attempts[0].Username != "recent" {
+		t.Errorf("remaining attempts = %+v, %v", attempts, err)
+	}
+
+	lockouts, err := db.GetLoginLockouts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remaining := map[string]bool{}
+	for _, lockout := range lockouts {
+		remaining[lockout.Username] = true
+	}
+	if remaining["old"] || !remaining["locked"] || !remaining["recent"] {
+		t.Errorf("remaining lockout counters = %v, want locked and recent", remaining)
+	}
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// userTables are the tables PurgeUser must clear, with the condition selecting a
+// user's rows
+var userTables = map[string]string{
+	"links":                `user_id = ?1`,
+	"user_tokens":          `user_id = ?1`,
+	"recovery_codes":       `user_id = ?1`,
+	"webauthn_credentials": `user_id = ?1`,
+	"webauthn_challenges":  `user_id = ?1`,
+	"api_tokens":           `user_id = ?1`,
+	"webhooks":             `user_id = ?1`,
+	"webhook_deliveries":   `webhook_id IN (SELECT id FROM webhooks WHERE user_id = ?1)`,
+	"feed_subscriptions":   `user_id = ?1`,
+	"feed_items":           `subscription_id IN (SELECT id FROM feed_subscriptions WHERE user_id = ?1)`,
+	"reports":              `reporter_id = ?1 OR link_id IN (SELECT id FROM links WHERE user_id = ?1)`,
+	"login_lockouts":       `username = (SELECT username FROM users WHERE id = ?1)`,
+	"failed_logins":        `username = (SELECT username FROM users WHERE id = ?1)`,
+	"users":                `id = ?1`,
+}
+
+// populateUser creates a user with a row in every table PurgeUser clears except
+// reports, which need a second user
+func populateUser(t *testing.T, db *Database, username string) int {
+	t.Helper()
+	now := time.Now().Format("2006-01-02 15:04:05")
+	must := func(step string, err error) {
+		if err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+	}
+
+	id, err := db.CreateUser(username, "hash", now)
+	must("CreateUser", err)
+	userID := int(id)
+
+	_, err = db.CreateLink(userID, "https://example.com/"+username, nil, nil, nil, now, false)
+	must("CreateLink", err)
+	must("CreateUserToken", db.CreateUserToken(userID, "token-"+username, "verify_email", username+"@example.com", time.Hour))
+	must("EnableTOTP", db.EnableTOTP(userID, 0, []string{"code-" + username}))
+	_, err = db.CreateWebAuthnCredential(userID, "cred-"+username, []byte{1}, 0, "Key", now)
+	must("CreateWebAuthnCredential", err)
+	must("CreateWebAuthnChallenge", db.CreateWebAuthnChallenge("challenge-"+username, userID, "register", time.Hour))
+	_, err = db.CreateAPIToken(userID, "CLI", "api-"+username)
+	must("CreateAPIToken", err)
+	hook, err := db.CreateWebhook(userID, "https://hooks.example.com/"+username, "secret", []string{"link.created"})
+	must("CreateWebhook", err)
+	_, err = db.CreateWebhookDelivery(hook.ID, "link.created", "{}", time.Now())
+	must("CreateWebhookDelivery", err)
+	sub, err := db.CreateFeedSubscription(userID, "https://example.com/"+username+".xml", "Feed", false, time.Now())
+	must("CreateFeedSubscription", err)
+	must("RecordFeedItem", db.RecordFeedItem(sub.ID, "guid", "https://example.com/item"))
+	_, err = db.RecordFailedLogin(username, "192.0.2.1", "test", "invalid_password", time.Hour)
+	must("RecordFailedLogin", err)
+	return userID
+}
+
+// countUserRows returns the number of rows the user still has in each table
+func countUserRows(t *testing.T, db *Database, userID int, username string) map[string]int {
+	t.Helper()
+	counts := map[string]int{}
+	for table, condition := range userTables {
+		// The user row may be gone, so match login failures by name directly
+		query := `SELECT COUNT(*) FROM ` + table + ` WHERE ` + condition
+		args := []interface{}{userID}
+		if table == "login_lockouts" || table == "failed_logins" {
+			query = `SELECT COUNT(*) FROM ` + table + ` WHERE username = ?1`
+			args = []interface{}{username}
+		}
+		var n int
+		if err := db.conn.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatalf("counting %s: %v", table, err)
+		}
+		counts[table] = n
+	}
+	return counts
+}
+
+func TestPurgeUserRemovesEverything(t *testing.T) {
+	db := newTestDB(t)
+	aliceID := populateUser(t, db, "alice")
+	bobID := populateUser(t, db, "bob")
+
+	// Each reports the other's link: Alice's own report and the one against her link
+	// both go with her account
+	for _, pair := range [][2]int{{aliceID, bobID}, {bobID, aliceID}} {
+		var linkID int
+		if err := db.conn.QueryRow(`SELECT id FROM links WHERE user_id = ?`, pair[0]).Scan(&linkID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.CreateReport(linkID, pair[1], "spam", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for table, n := range countUserRows(t, db, aliceID, "alice") {
+		if n == 0 {
+			t.Fatalf("setup left %s empty", table)
+		}
+	}
+
+	if err := db.PurgeUser(aliceID); err != nil {
+		t.Fatalf("PurgeUser: %v", err)
+	}
+	for table, n := range countUserRows(t, db, aliceID, "alice") {
+		if n != 0 {
+			t.Errorf("%d rows left in %s", n, table)
+		}
+	}
+
+	// Bob keeps everything except the report he filed against Alice's link
+	for table, n := range countUserRows(t, db, bobID, "bob") {
+		if n == 0 && table != "reports" {
+			t.Errorf("bob's rows in %s were deleted", table)
+		}
+	}
+
+	if err := db.PurgeUser(aliceID); err != ErrNotFound {
+		t.Errorf("purging again = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPurgeUserIsAtomic(t *testing.T) {
+	db := newTestDB(t)
+	userID := populateUser(t, db, "alice")
+	before := countUserRows(t, db, userID, "alice")
+
+	// Make a statement late in the purge fail
+	if _, err := db.conn.Exec(`DROP TABLE feed_subscriptions`); err != nil {
+		t.Fatal(err)
+	}
+	delete(userTables, "feed_subscriptions")
+	delete(userTables, "feed_items")
+	defer func() {
+		userTables["feed_subscriptions"] = `user_id = ?1`
+		userTables["feed_items"] = `subscription_id IN (SELECT id FROM feed_subscriptions WHERE user_id = ?1)`
+	}()
+
+	if err := db.PurgeUser(userID); err == nil {
+		t.Fatal("PurgeUser succeeded without feed_subscriptions")
+	}
+	for table, n := range countUserRows(t, db, userID, "alice") {
+		if n != before[table] {
+			t.Errorf("%s: %d rows after a failed purge, want %d", table, n, before[table])
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"links/internal/models"
+)
+
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+)
+
+// ErrDuplicateReport is returned when the user already has an open report on the link
+var ErrDuplicateReport = fmt.Errorf("link already reported: %w", ErrConflict)
+
+// CreateReport files a report against a public link. It returns ErrNotFound if the
+// link is not visible to the reporter or belongs to them.
+func (db *Database) CreateReport(linkID, reporterID int, reason, details string) (int64, error) {
+	var exists bool
+	err := db.conn.QueryRow(`SELECT EXISTS (SELECT 1 FROM reports WHERE link_id = ? AND reporter_id = ? AND status = 'open')`, linkID, reporterID).Scan(&exists)
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		return 0, ErrDuplicateReport
+	}
+
+	query := `INSERT INTO reports (link_id, reporter_id, reason, details, status, created_at)
+		SELECT l.id, ?, ?, ?, 'open', ? FROM links l JOIN users u ON l.user_id = u.id
+		WHERE l.id = ? AND ` + publicLinks + ` AND l.user_id != ?`
+	now := time.Now().Format("2006-01-02 15:04:05")
+	result, err := db.conn.Exec(query, reporterID, reason, details, now, linkID, now, reporterID)
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		return 0, ErrNotFound
+	}
+	return result.LastInsertId()
+}
+
+const reportColumns = `r.id, r.link_id, l.url, l.user_id, owner.username, r.reporter_id, COALESCE(reporter.username, ''),
+	r.reason, r.details, r.status, r.resolution, resolver.username, r.resolved_at, r.note, r.created_at`
+
+const reportJoins = ` FROM reports r
+	JOIN links l ON r.link_id = l.id
+	JOIN users owner ON l.user_id = owner.id
+	LEFT JOIN users reporter ON r.reporter_id = reporter.id
+	LEFT JOIN users resolver ON r.resolved_by = resolver.id`
+
+func scanReport(row interface{ Scan(...interface{}) error }) (*models.Report, error) {
+	var r models.Report
+	err := row.Scan(&r.ID, &r.LinkID, &r.LinkURL, &r.LinkOwnerID, &r.LinkOwner, &r.ReporterID, &r.Reporter,
+		&r.Reason, &r.Details, &r.Status, &r.Resolution, &r.ResolvedBy, &r.ResolvedAt, &r.Note, &r.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetReports lists reports with the given status (all statuses if empty), oldest open
+// reports first so the queue is worked in order
+func (db *Database) GetReports(status string) ([]models.Report, error) {
+	query := `SELECT ` + reportColumns + reportJoins + ` WHERE (? = '' OR r.status = ?) ORDER BY r.status = 'resolved', r.id`
+	rows, err := db.conn.Query(query, status, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	return reports, rows.Err()
+}
+
+func (db *Database) GetReport(reportID int) (*models.Report, error) {
+	return scanReport(db.conn.QueryRow(`SELECT `+reportColumns+reportJoins+` WHERE r.id = ?`, reportID))
+}
+
+// ResolveReports closes open reports with the moderator's action. When allForLink is
+// set every open report on the report's link is closed, since the action applies to
+// the link rather than the individual complaint.
+func (db *Database) ResolveReports(reportID int, allForLink bool, resolution string, resolvedBy int, note string) (int64, error) {
+	now := time.Now().Format("2006-01-02 15:04:05")
+	query := `UPDATE reports SET status = 'resolved', resolution = ?, resolved_by = ?, resolved_at = ?, note = ?
+		WHERE status = 'open' AND (id = ? OR (? AND link_id = (SELECT link_id FROM reports WHERE id = ?)))`
+	result, err := db.conn.Exec(query, resolution, resolvedBy, now, note, reportID, allForLink, reportID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
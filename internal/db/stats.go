@@ -0,0 +1,143 @@
+package db
+
+import (
+	"time"
+
+	"links/internal/models"
+)
+
+// GetStats computes the admin dashboard figures. days bounds the time series and
+// limit the length of each top-N list. Deleted users and links are excluded.
+func (db *Database) GetStats(days, limit int) (*models.Stats, error) {
+	now := time.Now()
+	stats := &models.Stats{Days: days}
+
+	err := db.conn.QueryRow(`SELECT
+		(SELECT COUNT(*) FROM users WHERE deleted_at IS NULL),
+		COUNT(*),
+		COALESCE(SUM(CASE WHEN is_private = 0 THEN 1 ELSE 0 END), 0)
+		FROM links WHERE deleted_at IS NULL`).Scan(&stats.TotalUsers, &stats.TotalLinks, &stats.PublicLinks)
+	if err != nil {
+		return nil, err
+	}
+	stats.PrivateLinks = stats.TotalLinks - stats.PublicLinks
+	if stats.TotalLinks > 0 {
+		stats.PublicRatio = float64(stats.PublicLinks) / float64(stats.TotalLinks)
+	}
+
+	// A user is active if they signed in or saved a link during the window
+	since7 := now.AddDate(0, 0, -7).Format("2006-01-02 15:04:05")
+	since30 := now.AddDate(0, 0, -30).Format("2006-01-02 15:04:05")
+	err = db.conn.QueryRow(`SELECT COUNT(DISTINCT CASE WHEN at >= ? THEN id END), COUNT(DISTINCT id) FROM (
+			SELECT actor_id AS id, created_at AS at FROM audit_log WHERE action = 'login.success' AND created_at >= ?
+			UNION ALL
+			SELECT user_id, created_at FROM links WHERE created_at >= ?
+		) WHERE id IN (SELECT id FROM users WHERE deleted_at IS NULL)`,
+		since7, since30, since30).Scan(&stats.ActiveUsers7d, &stats.ActiveUsers30d)
+	if err != nil {
+		return nil, err
+	}
+
+	first := now.AddDate(0, 0, -(days - 1))
+	if stats.NewUsers, err = db.dailyCounts(`SELECT substr(created_at, 1, 10) AS day, COUNT(*) FROM users
+		WHERE deleted_at IS NULL AND created_at >= ? GROUP BY day`, first, days); err != nil {
+		return nil, err
+	}
+	if stats.NewLinks, err = db.dailyCounts(`SELECT substr(created_at, 1, 10) AS day, COUNT(*) FROM links
+		WHERE deleted_at IS NULL AND created_at >= ? GROUP BY day`, first, days); err != nil {
+		return nil, err
+	}
+
+	if stats.TopDomains, err = db.namedCounts(`SELECT `+linkHost+` AS host, COUNT(*) AS n FROM links l
+		WHERE l.deleted_at IS NULL GROUP BY host ORDER BY n DESC, host LIMIT ?`, limit); err != nil {
+		return nil, err
+	}
+
+	// Tags are stored comma-separated; split them with a recursive CTE
+	if stats.TopTags, err = db.namedCounts(`WITH RECURSIVE split (tag, rest) AS (
+			SELECT '', tags || ',' FROM links WHERE deleted_at IS NULL AND tags IS NOT NULL AND tags != ''
+			UNION ALL
+			SELECT trim(substr(rest, 1, instr(rest, ',') - 1)), substr(rest, instr(rest, ',') + 1) FROM split WHERE rest != ''
+		)
+		SELECT tag, COUNT(*) AS n FROM split WHERE tag != '' GROUP BY tag ORDER BY n DESC, tag LIMIT ?`, limit); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.conn.Query(`SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username
+		FROM links l JOIN users u ON l.user_id = u.id WHERE l.deleted_at IS NULL AND COALESCE(l.access_count, 0) > 0
+		ORDER BY l.access_count DESC, l.id LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stats.MostAccessed = []models.Link{}
+	for rows.Next() {
+		var link models.Link
+		err := rows.Scan(&link.ID, &link.UserID, &link.URL, &link.Description, &link.Tags, &link.Category, &link.CreatedAt, &link.IsPrivate, &link.IsFavorite, &link.AccessCount, &link.IsLocked, &link.Username)
+		if err != nil {
+			return nil, err
+		}
+		stats.MostAccessed = append(stats.MostAccessed, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var pageCount, freePages, pageSize int64
+	db.conn.QueryRow(`PRAGMA page_count`).Scan(&pageCount)
+	db.conn.QueryRow(`PRAGMA freelist_count`).Scan(&freePages)
+	db.conn.QueryRow(`PRAGMA page_size`).Scan(&pageSize)
+	stats.StorageBytes = pageCount * pageSize
+	stats.FreeBytes = freePages * pageSize
+
+	return stats, nil
+}
+
+// dailyCounts runs a (day, count) query from first onwards and fills in the days
+// without rows so the series has exactly days entries
+func (db *Database) dailyCounts(query string, first time.Time, days int) ([]models.DailyCount, error) {
+	start := first.Format("2006-01-02")
+	rows, err := db.conn.Query(query, start)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var day string
+		var n int
+		if err := rows.Scan(&day, &n); err != nil {
+			return nil, err
+		}
+		counts[day] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	series := make([]models.DailyCount, days)
+	for i := range series {
+		day := first.AddDate(0, 0, i).Format("2006-01-02")
+		series[i] = models.DailyCount{Date: day, Count: counts[day]}
+	}
+	return series, nil
+}
+
+func (db *Database) namedCounts(query string, args ...interface{}) ([]models.NamedCount, error) {
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.NamedCount{}
+	for rows.Next() {
+		var c models.NamedCount
+		if err := rows.Scan(&c.Name, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
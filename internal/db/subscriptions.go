@@ -0,0 +1,164 @@
+package db
+
+import (
+	"time"
+
+	"links/internal/models"
+)
+
+const subscriptionColumns = `s.id, s.user_id, s.url, s.name, s.is_private, s.active, s.last_checked_at, COALESCE(s.last_error, ''),
+	datetime(s.next_check_at, 'unixepoch', 'localtime'), s.created_at, s.failures, COALESCE(s.etag, ''), COALESCE(s.last_modified, '')`
+
+func scanSubscription(scanner interface{ Scan(...interface{}) error }) (*models.FeedSubscription, error) {
+	var sub models.FeedSubscription
+	err := scanner.Scan(&sub.ID, &sub.UserID, &sub.URL, &sub.Name, &sub.IsPrivate, &sub.Active, &sub.LastCheckedAt, &sub.LastError,
+		&sub.NextCheckAt, &sub.CreatedAt, &sub.Failures, &sub.ETag, &sub.LastModified)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// CreateFeedSubscription adds a subscription that the poller first picks up at
+// nextCheck. It returns ErrConflict if the user already follows url.
+func (db *Database) CreateFeedSubscription(userID int, url, name string, isPrivate bool, nextCheck time.Time) (*models.FeedSubscription, error) {
+	query := `INSERT INTO feed_subscriptions (user_id, url, name, is_private, active, next_check_at, created_at) VALUES (?, ?, ?, ?, 1, ?, ?)`
+	result, err := db.conn.Exec(query, userID, url, name, isPrivate, nextCheck.Unix(), time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, conflictError(err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return db.GetFeedSubscription(int(id), userID)
+}
+
+func (db *Database) GetFeedSubscriptions(userID int) ([]models.FeedSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM feed_subscriptions s WHERE s.user_id = ? ORDER BY s.id`
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.FeedSubscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+func (db *Database) GetFeedSubscription(id, userID int) (*models.FeedSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM feed_subscriptions s WHERE s.id = ? AND s.user_id = ?`
+	return scanSubscription(db.conn.QueryRow(query, id, userID))
+}
+
+func (db *Database) CountFeedSubscriptions(userID int) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM feed_subscriptions WHERE user_id = ?`, userID).Scan(&count)
+	return count, err
+}
+
+// UpdateFeedSubscription changes a subscription's settings. Re-enabling one clears
+// its failure count, so it is polled again without backing off.
+func (db *Database) UpdateFeedSubscription(id, userID int, name string, isPrivate, active bool) error {
+	query := `UPDATE feed_subscriptions SET name = ?, is_private = ?, failures = CASE WHEN active = 0 AND ? THEN 0 ELSE failures END, active = ?
+		WHERE id = ? AND user_id = ?`
+	return expectRow(db.conn.Exec(query, name, isPrivate, active, active, id, userID))
+}
+
+// DeleteFeedSubscription removes a subscription and its seen items. Links it saved
+// are kept.
+func (db *Database) DeleteFeedSubscription(id, userID int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := expectRow(tx.Exec(`DELETE FROM feed_subscriptions WHERE id = ? AND user_id = ?`, id, userID)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM feed_items WHERE subscription_id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ClaimFeedSubscriptions returns up to limit active subscriptions that are due and
+// pushes their next check back by lease, so a feed still being fetched is not picked
+// up again. Subscriptions of deleted or suspended accounts are left alone.
+func (db *Database) ClaimFeedSubscriptions(now time.Time, lease time.Duration, limit int) ([]models.FeedSubscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM feed_subscriptions s JOIN users u ON s.user_id = u.id
+		WHERE s.active = 1 AND s.next_check_at <= ? AND u.deleted_at IS NULL AND ` + ownerNotSuspended + `
+		ORDER BY s.next_check_at, s.id LIMIT ?`
+	rows, err := db.conn.Query(query, now.Unix(), now.Format("2006-01-02 15:04:05"), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []models.FeedSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, *sub)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	claimed := []models.FeedSubscription{}
+	for _, sub := range due {
+		result, err := db.conn.Exec(`UPDATE feed_subscriptions SET next_check_at = ? WHERE id = ? AND active = 1 AND next_check_at <= ?`,
+			now.Add(lease).Unix(), sub.ID, now.Unix())
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			claimed = append(claimed, sub)
+		}
+	}
+	return claimed, nil
+}
+
+// RecordFeedPoll stores the outcome of a poll and when to poll next
+func (db *Database) RecordFeedPoll(sub *models.FeedSubscription, nextCheck time.Time) error {
+	var lastError *string
+	if sub.LastError != "" {
+		lastError = &sub.LastError
+	}
+	query := `UPDATE feed_subscriptions SET name = ?, etag = ?, last_modified = ?, failures = ?, last_error = ?, last_checked_at = ?, next_check_at = ?
+		WHERE id = ?`
+	_, err := db.conn.Exec(query, sub.Name, sub.ETag, sub.LastModified, sub.Failures, lastError, sub.LastCheckedAt, nextCheck.Unix(), sub.ID)
+	if err == nil {
+		sub.NextCheckAt = nextCheck.Format("2006-01-02 15:04:05")
+	}
+	return err
+}
+
+// FeedItemSeen reports whether an item was already handled: its GUID or URL was
+// seen in this subscription before, or the subscriber already saved the URL
+func (db *Database) FeedItemSeen(sub *models.FeedSubscription, guid, url string) (bool, error) {
+	var seen bool
+	query := `SELECT EXISTS (SELECT 1 FROM feed_items WHERE subscription_id = ? AND (guid = ? OR url = ?))
+		OR EXISTS (SELECT 1 FROM links WHERE user_id = ? AND url = ? AND deleted_at IS NULL)`
+	err := db.conn.QueryRow(query, sub.ID, guid, url, sub.UserID, url).Scan(&seen)
+	return seen, err
+}
+
+// RecordFeedItem marks an item of a subscription as handled
+func (db *Database) RecordFeedItem(subscriptionID int, guid, url string) error {
+	_, err := db.conn.Exec(`INSERT OR IGNORE INTO feed_items (subscription_id, guid, url, seen_at) VALUES (?, ?, ?, ?)`,
+		subscriptionID, guid, url, time.Now().Format("2006-01-02 15:04:05"))
+	return err
+}
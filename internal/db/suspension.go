@@ -0,0 +1,29 @@
+package db
+
+import (
+	"time"
+)
+
+// ownerNotSuspended filters joined users (aliased u) down to accounts without a
+// suspension in effect; it takes the current time as its only parameter
+const ownerNotSuspended = `(u.suspended_at IS NULL OR u.suspended_until <= ?)`
+
+// publicLinks matches the links shown on the public page; it takes the same argument
+// as ownerNotSuspended
+const publicLinks = `l.is_private = 0 AND l.deleted_at IS NULL AND u.deleted_at IS NULL AND ` + ownerNotSuspended
+
+// SuspendUser blocks the account from signing in until the given time (indefinitely
+// if until is nil) and revokes its existing sessions
+func (db *Database) SuspendUser(userID int, reason string, until *string) error {
+	query := `UPDATE users SET suspended_at = ?, suspended_until = ?, suspend_reason = ?, session_version = COALESCE(session_version, 0) + 1
+		WHERE id = ? AND deleted_at IS NULL`
+	return expectRow(db.conn.Exec(query, time.Now().Format("2006-01-02 15:04:05"), until, reason, userID))
+}
+
+// UnsuspendUser lifts a suspension. Returns ErrNotFound if the account is not
+// suspended.
+func (db *Database) UnsuspendUser(userID int) error {
+	query := `UPDATE users SET suspended_at = NULL, suspended_until = NULL, suspend_reason = NULL
+		WHERE id = ? AND deleted_at IS NULL AND suspended_at IS NOT NULL`
+	return expectRow(db.conn.Exec(query, userID))
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"time"
+)
+
+// Purposes for user_tokens
+const (
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeEmailVerify   = "email_verify"
+)
+
+// CreateUserToken stores a hashed single-use token bound to an email address,
+// invalidating any earlier unused token of the same purpose
+func (db *Database) CreateUserToken(userID int, tokenHash, purpose, email string, ttl time.Duration) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM user_tokens WHERE user_id = ? AND purpose = ? AND used_at IS NULL`, userID, purpose)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO user_tokens (user_id, token_hash, purpose, email, expires_at) VALUES (?, ?, ?, ?, ?)`
+	_, err = tx.Exec(query, userID, tokenHash, purpose, email, time.Now().Add(ttl).Unix())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ConsumeUserToken marks a valid token as used and returns its user and email. It
+// returns ErrNotFound if the token is unknown, expired, used or for another purpose.
+func (db *Database) ConsumeUserToken(tokenHash, purpose string) (int, string, error) {
+	query := `UPDATE user_tokens SET used_at = ? WHERE token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at >= ? RETURNING user_id, email`
+	var userID int
+	var email string
+	now := time.Now()
+	err := db.conn.QueryRow(query, now.Format("2006-01-02 15:04:05"), tokenHash, purpose, now.Unix()).Scan(&userID, &email)
+	if err != nil {
+		return 0, "", err
+	}
+	return userID, email, nil
+}
+
+// GetUserIDByVerifiedEmail finds the account whose confirmed email matches
+func (db *Database) GetUserIDByVerifiedEmail(email string) (int, error) {
+	var userID int
+	err := db.conn.QueryRow(`SELECT id FROM users WHERE email = ? AND COALESCE(email_verified, 0) = 1 AND deleted_at IS NULL`, email).Scan(&userID)
+	return userID, err
+}
+
+// MarkEmailVerified confirms the email only if it is still the account's current address
+func (db *Database) MarkEmailVerified(userID int, email string) error {
+	result, err := db.conn.Exec(`UPDATE users SET email_verified = 1 WHERE id = ? AND email = ?`, userID, email)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
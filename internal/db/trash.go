@@ -0,0 +1,145 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"links/internal/models"
+)
+
+// GetTrashedLinks returns links the user moved to their trash, most recently deleted first
+func (db *Database) GetTrashedLinks(userID int) ([]models.Link, error) {
+	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username, l.deleted_at FROM links l JOIN users u ON l.user_id = u.id WHERE l.user_id = ? AND l.deleted_at IS NOT NULL AND l.deleted_by = l.user_id ORDER BY l.deleted_at DESC`
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.Link{}
+	for rows.Next() {
+		var link models.Link
+		err := rows.Scan(&link.ID, &link.UserID, &link.URL, &link.Description, &link.Tags, &link.Category, &link.CreatedAt, &link.IsPrivate, &link.IsFavorite, &link.AccessCount, &link.IsLocked, &link.Username, &link.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// RestoreLink moves a link the user deleted back out of their trash
+func (db *Database) RestoreLink(linkID, userID int) error {
+	query := `UPDATE links SET deleted_at = NULL, deleted_by = NULL WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL AND deleted_by = user_id`
+	return expectRow(db.conn.Exec(query, linkID, userID))
+}
+
+// PurgeLink permanently deletes a link from the user's trash
+func (db *Database) PurgeLink(linkID, userID int) error {
+	query := `DELETE FROM links WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL AND deleted_by = user_id`
+	return expectRow(db.conn.Exec(query, linkID, userID))
+}
+
+// EmptyTrash permanently deletes every link in the user's trash
+func (db *Database) EmptyTrash(userID int) (int64, error) {
+	result, err := db.conn.Exec(`DELETE FROM links WHERE user_id = ? AND deleted_at IS NOT NULL AND deleted_by = user_id`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// AdminRestoreLink restores any soft-deleted link, including ones removed by an admin
+func (db *Database) AdminRestoreLink(linkID int) error {
+	query := `UPDATE links SET deleted_at = NULL, deleted_by = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+	return expectRow(db.conn.Exec(query, linkID))
+}
+
+// GetDeletedUsers lists soft-deleted accounts, most recently deleted first
+func (db *Database) GetDeletedUsers() ([]models.User, error) {
+	query := `SELECT id, username, created_at, COALESCE(is_admin, 0), COALESCE(role, 'user'), deleted_at FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC`
+	rows, err := db.conn.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username, &user.CreatedAt, &user.IsAdmin, &user.Role, &user.DeletedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// AdminRestoreUser undoes AdminDeleteUser; the account's links become visible again
+func (db *Database) AdminRestoreUser(userID int) error {
+	return expectRow(db.conn.Exec(`UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, userID))
+}
+
+// AdminPurgeUser permanently deletes an account that is already in the trash
+func (db *Database) AdminPurgeUser(userID int) error {
+	var deletedAt sql.NullString
+	err := db.conn.QueryRow(`SELECT deleted_at FROM users WHERE id = ?`, userID).Scan(&deletedAt)
+	if err != nil {
+		return err
+	}
+	if !deletedAt.Valid {
+		return ErrNotFound
+	}
+	return db.PurgeUser(userID)
+}
+
+// PurgeDeleted permanently deletes links and accounts soft-deleted before cutoff,
+// returning how many of each were removed
+func (db *Database) PurgeDeleted(cutoff time.Time) (int64, int64, error) {
+	before := cutoff.Format("2006-01-02 15:04:05")
+
+	result, err := db.conn.Exec(`DELETE FROM links WHERE deleted_at IS NOT NULL AND deleted_at < ?`, before)
+	if err != nil {
+		return 0, 0, err
+	}
+	links, _ := result.RowsAffected()
+
+	rows, err := db.conn.Query(`SELECT id FROM users WHERE deleted_at IS NOT NULL AND deleted_at < ?`, before)
+	if err != nil {
+		return links, 0, err
+	}
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return links, 0, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+
+	var users int64
+	for _, id := range userIDs {
+		if err := db.PurgeUser(id); err != nil {
+			return links, users, err
+		}
+		users++
+	}
+	return links, users, nil
+}
+
+// expectRow converts an update or delete that matched nothing into ErrNotFound
+func expectRow(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
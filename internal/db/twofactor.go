@@ -0,0 +1,193 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+const settingRequireAdmin2FA = "require_admin_2fa"
+
+// GetTOTP returns the user's TOTP secret (possibly pending enrollment), whether it is
+// enabled and the last accepted time step
+func (db *Database) GetTOTP(userID int) (string, bool, int64, error) {
+	query := `SELECT COALESCE(totp_secret, ''), COALESCE(totp_enabled, 0), COALESCE(totp_last_step, 0) FROM users WHERE id = ?`
+	var secret string
+	var enabled bool
+	var lastStep int64
+	err := db.conn.QueryRow(query, userID).Scan(&secret, &enabled, &lastStep)
+	if err != nil {
+		return "", false, 0, err
+	}
+	return secret, enabled, lastStep, nil
+}
+
+// SetPendingTOTPSecret stores a new secret that is not active until EnableTOTP succeeds
+func (db *Database) SetPendingTOTPSecret(userID int, secret string) error {
+	query := `UPDATE users SET totp_secret = ?, totp_last_step = 0 WHERE id = ? AND COALESCE(totp_enabled, 0) = 0`
+	result, err := db.conn.Exec(query, secret, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// EnableTOTP activates the pending secret and replaces the user's recovery codes
+func (db *Database) EnableTOTP(userID int, lastStep int64, recoveryCodeHashes []string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_enabled = 1, totp_last_step = ? WHERE id = ?`, lastStep, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DisableTOTP removes the secret and all recovery codes
+func (db *Database) DisableTOTP(userID int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE users SET totp_secret = NULL, totp_enabled = 0, totp_last_step = 0 WHERE id = ?`, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateTOTPLastStep records an accepted time step; it fails with ErrNotFound if the
+// step was already used, which prevents replaying a code
+func (db *Database) UpdateTOTPLastStep(userID int, step int64) error {
+	query := `UPDATE users SET totp_last_step = ? WHERE id = ? AND totp_last_step < ?`
+	result, err := db.conn.Exec(query, step, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) ReplaceRecoveryCodes(userID int, codeHashes []string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO recovery_codes (user_id, code_hash) VALUES (?, ?)`, userID, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as consumed, returning ErrNotFound if
+// no matching unused code exists
+func (db *Database) UseRecoveryCode(userID int, codeHash string) error {
+	query := `UPDATE recovery_codes SET used_at = ? WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`
+	result, err := db.conn.Exec(query, time.Now().Format("2006-01-02 15:04:05"), userID, codeHash)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (db *Database) CountRecoveryCodes(userID int) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// Settings
+
+func (db *Database) GetSetting(key string) (string, error) {
+	var value string
+	err := db.conn.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	return value, err
+}
+
+func (db *Database) SetSetting(key, value string) error {
+	query := `INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`
+	_, err := db.conn.Exec(query, key, value)
+	return err
+}
+
+// RequireAdmin2FA reports whether the admin policy requiring 2FA for is_admin users is on
+func (db *Database) RequireAdmin2FA() (bool, error) {
+	value, err := db.GetSetting(settingRequireAdmin2FA)
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+func (db *Database) SetRequireAdmin2FA(required bool) error {
+	value := "false"
+	if required {
+		value = "true"
+	}
+	return db.SetSetting(settingRequireAdmin2FA, value)
+}
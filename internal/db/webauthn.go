@@ -0,0 +1,95 @@
+package db
+
+import (
+	"time"
+
+	"links/internal/models"
+)
+
+// CreateWebAuthnChallenge stores a pending ceremony challenge; userID is 0 for
+// passwordless logins where the user is not known yet
+func (db *Database) CreateWebAuthnChallenge(challenge string, userID int, purpose string, ttl time.Duration) error {
+	// Drop abandoned ceremonies while we're here
+	db.conn.Exec(`DELETE FROM webauthn_challenges WHERE expires_at < ?`, time.Now().Unix())
+
+	query := `INSERT INTO webauthn_challenges (challenge, user_id, purpose, expires_at) VALUES (?, ?, ?, ?)`
+	_, err := db.conn.Exec(query, challenge, userID, purpose, time.Now().Add(ttl).Unix())
+	return err
+}
+
+// ConsumeWebAuthnChallenge deletes the challenge and returns the user it was issued for.
+// It returns ErrNotFound if the challenge is unknown, already used, expired or for
+// another purpose.
+func (db *Database) ConsumeWebAuthnChallenge(challenge, purpose string) (int, error) {
+	query := `DELETE FROM webauthn_challenges WHERE challenge = ? AND purpose = ? AND expires_at >= ? RETURNING user_id`
+	var userID int
+	err := db.conn.QueryRow(query, challenge, purpose, time.Now().Unix()).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (db *Database) CreateWebAuthnCredential(userID int, credentialID string, publicKey []byte, signCount uint32, name, createdAt string) (int64, error) {
+	query := `INSERT INTO webauthn_credentials (user_id, credential_id, public_key, sign_count, name, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, userID, credentialID, publicKey, signCount, name, createdAt)
+	if err != nil {
+		return 0, conflictError(err)
+	}
+	return result.LastInsertId()
+}
+
+func (db *Database) GetWebAuthnCredential(credentialID string) (*models.WebAuthnCredential, error) {
+	query := `SELECT id, user_id, credential_id, public_key, sign_count, name, created_at, last_used_at FROM webauthn_credentials WHERE credential_id = ?`
+	var cred models.WebAuthnCredential
+	err := db.conn.QueryRow(query, credentialID).Scan(&cred.ID, &cred.UserID, &cred.CredentialID, &cred.PublicKey, &cred.SignCount, &cred.Name, &cred.CreatedAt, &cred.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+func (db *Database) GetWebAuthnCredentialsByUserID(userID int) ([]models.WebAuthnCredential, error) {
+	query := `SELECT id, user_id, credential_id, public_key, sign_count, name, created_at, last_used_at FROM webauthn_credentials WHERE user_id = ? ORDER BY created_at DESC`
+	rows, err := db.conn.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creds := []models.WebAuthnCredential{}
+	for rows.Next() {
+		var cred models.WebAuthnCredential
+		err := rows.Scan(&cred.ID, &cred.UserID, &cred.CredentialID, &cred.PublicKey, &cred.SignCount, &cred.Name, &cred.CreatedAt, &cred.LastUsedAt)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, cred)
+	}
+	return creds, rows.Err()
+}
+
+func (db *Database) UpdateWebAuthnSignCount(id int, signCount uint32) error {
+	query := `UPDATE webauthn_credentials SET sign_count = ?, last_used_at = ? WHERE id = ?`
+	_, err := db.conn.Exec(query, signCount, time.Now().Format("2006-01-02 15:04:05"), id)
+	return err
+}
+
+func (db *Database) DeleteWebAuthnCredential(id, userID int) error {
+	query := `DELETE FROM webauthn_credentials WHERE id = ? AND user_id = ?`
+	result, err := db.conn.Exec(query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"links/internal/models"
+)
+
+// webhookOwner maps an owner ID to the user_id column; 0 means a site-wide webhook.
+// Queries compare with "user_id IS ?" so both cases match.
+func webhookOwner(ownerID int) interface{} {
+	if ownerID == 0 {
+		return nil
+	}
+	return ownerID
+}
+
+func scanWebhook(scanner interface{ Scan(...interface{}) error }) (*models.Webhook, error) {
+	var hook models.Webhook
+	var events string
+	if err := scanner.Scan(&hook.ID, &hook.UserID, &hook.URL, &events, &hook.Active, &hook.CreatedAt); err != nil {
+		return nil, err
+	}
+	hook.Events = strings.Split(events, ",")
+	return &hook, nil
+}
+
+func (db *Database) CreateWebhook(ownerID int, url, secret string, events []string) (*models.Webhook, error) {
+	createdAt := time.Now().Format("2006-01-02 15:04:05")
+	query := `INSERT INTO webhooks (user_id, url, secret, events, active, created_at) VALUES (?, ?, ?, ?, 1, ?)`
+	result, err := db.conn.Exec(query, webhookOwner(ownerID), url, secret, strings.Join(events, ","), createdAt)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	hook, err := db.GetWebhook(int(id), ownerID)
+	if err != nil {
+		return nil, err
+	}
+	hook.Secret = secret
+	return hook, nil
+}
+
+func (db *Database) GetWebhooks(ownerID int) ([]models.Webhook, error) {
+	query := `SELECT id, user_id, url, events, active, created_at FROM webhooks WHERE user_id IS ? ORDER BY id`
+	rows, err := db.conn.Query(query, webhookOwner(ownerID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (db *Database) GetWebhook(id, ownerID int) (*models.Webhook, error) {
+	query := `SELECT id, user_id, url, events, active, created_at FROM webhooks WHERE id = ? AND user_id IS ?`
+	return scanWebhook(db.conn.QueryRow(query, id, webhookOwner(ownerID)))
+}
+
+func (db *Database) CountWebhooks(ownerID int) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM webhooks WHERE user_id IS ?`, webhookOwner(ownerID)).Scan(&count)
+	return count, err
+}
+
+func (db *Database) UpdateWebhook(id, ownerID int, url string, events []string, active bool) error {
+	query := `UPDATE webhooks SET url = ?, events = ?, active = ? WHERE id = ? AND user_id IS ?`
+	result, err := db.conn.Exec(query, url, strings.Join(events, ","), active, id, webhookOwner(ownerID))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteWebhook removes a webhook along with its delivery log
+func (db *Database) DeleteWebhook(id, ownerID int) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id IS ?`, id, webhookOwner(ownerID))
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// EnqueueWebhookEvent queues payload for every active webhook subscribed to event:
+// those of the link's owner and, for public links, the site-wide ones
+func (db *Database) EnqueueWebhookEvent(event string, ownerID int, public bool, payload string) (int64, error) {
+	now := time.Now()
+	query := `INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at, created_at)
+		SELECT id, ?, ?, ?, ?, ? FROM webhooks
+		WHERE active = 1 AND (',' || events || ',') LIKE ? AND (user_id = ? OR (user_id IS NULL AND ?))`
+	result, err := db.conn.Exec(query, event, payload, models.DeliveryPending, now.Unix(), now.Format("2006-01-02 15:04:05"),
+		"%,"+event+",%", ownerID, public)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CreateWebhookDelivery queues a single delivery that the caller sends itself; it is
+// not picked up by the dispatcher before claimUntil
+func (db *Database) CreateWebhookDelivery(webhookID int, event, payload string, claimUntil time.Time) (*models.WebhookDelivery, error) {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	result, err := db.conn.Exec(query, webhookID, event, payload, models.DeliveryPending, claimUntil.Unix(), time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return db.getWebhookDelivery(int(id))
+}
+
+const deliveryColumns = `d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, COALESCE(d.response_code, 0), COALESCE(d.error, ''), d.created_at, d.last_attempt_at, w.url, w.secret`
+
+func scanDelivery(scanner interface{ Scan(...interface{}) error }) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := scanner.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.Error, &d.CreatedAt, &d.LastAttemptAt, &d.URL, &d.Secret)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (db *Database) getWebhookDelivery(id int) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d JOIN webhooks w ON d.webhook_id = w.id WHERE d.id = ?`
+	return scanDelivery(db.conn.QueryRow(query, id))
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries that are due and
+// pushes their next attempt back by lease, so a delivery still in flight is not
+// picked up again
+func (db *Database) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d JOIN webhooks w ON d.webhook_id = w.id
+		WHERE d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at, d.id LIMIT ?`
+	rows, err := db.conn.Query(query, models.DeliveryPending, now.Unix(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []models.WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, *d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	claimed := []models.WebhookDelivery{}
+	for _, d := range due {
+		result, err := db.conn.Exec(`UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ? AND status = ? AND next_attempt_at <= ?`,
+			now.Add(lease).Unix(), d.ID, models.DeliveryPending, now.Unix())
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := result.RowsAffected(); n == 1 {
+			claimed = append(claimed, d)
+		}
+	}
+	return claimed, nil
+}
+
+// RecordWebhookAttempt stores the outcome of an attempt. nextAttempt is only used
+// while the delivery is still pending.
+func (db *Database) RecordWebhookAttempt(d *models.WebhookDelivery, nextAttempt time.Time) error {
+	query := `UPDATE webhook_deliveries SET status = ?, attempts = ?, response_code = ?, error = ?, last_attempt_at = ?, next_attempt_at = ? WHERE id = ?`
+	_, err := db.conn.Exec(query, d.Status, d.Attempts, d.ResponseCode, d.Error, d.LastAttemptAt, nextAttempt.Unix(), d.ID)
+	return err
+}
+
+// GetWebhookDeliveries returns a webhook's most recent deliveries, newest first
+func (db *Database) GetWebhookDeliveries(webhookID, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries d JOIN webhooks w ON d.webhook_id = w.id
+		WHERE d.webhook_id = ? ORDER BY d.id DESC LIMIT ?`
+	rows, err := db.conn.Query(query, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// PruneWebhookDeliveries removes finished deliveries created before the cutoff
+func (db *Database) PruneWebhookDeliveries(before time.Time) (int64, error) {
+	result, err := db.conn.Exec(`DELETE FROM webhook_deliveries WHERE status != ? AND created_at < ?`,
+		models.DeliveryPending, before.Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetLinksByIDs returns links by ID whatever their owner, including links in the
+// trash, to describe them in webhook events
+func (db *Database) GetLinksByIDs(ids []int) ([]models.Link, error) {
+	if len(ids) == 0 {
+		return []models.Link{}, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	query := `SELECT l.id, l.user_id, l.url, l.description, l.tags, l.category, l.created_at, l.is_private, l.is_favorite, COALESCE(l.access_count, 0), COALESCE(l.is_locked, 0), u.username, l.deleted_at
+		FROM links l JOIN users u ON l.user_id = u.id WHERE l.id IN (` + placeholders + `) ORDER BY l.id`
+	rows, err := db.conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.Link{}
+	for rows.Next() {
+		var link models.Link
+		err := rows.Scan(&link.ID, &link.UserID, &link.URL, &link.Description, &link.Tags, &link.Category, &link.CreatedAt, &link.IsPrivate, &link.IsFavorite, &link.AccessCount, &link.IsLocked, &link.Username, &link.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
@@ -0,0 +1,135 @@
+// Package events is an in-process publish/subscribe broker for pushing link changes
+// to connected browsers. Recent events are kept so a client that reconnects with
+// the last ID it saw misses nothing.
+package events
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTooManySubscribers is returned by Subscribe once the broker is full
+var ErrTooManySubscribers = errors.New("too many event subscribers")
+
+// Event is one message for subscribers. Public events go to everyone; the rest only
+// to subscribers signed in as UserID.
+type Event struct {
+	ID     uint64
+	Type   string
+	Data   []byte // JSON
+	UserID int
+	Public bool
+}
+
+// visibleTo reports whether a subscriber signed in as userID (0 when anonymous) may
+// receive the event
+func (e Event) visibleTo(userID int) bool {
+	return e.Public || (userID != 0 && e.UserID == userID)
+}
+
+type Broker struct {
+	mu             sync.Mutex
+	nextID         uint64
+	history        []Event // Oldest first, at most historySize
+	historySize    int
+	bufferSize     int
+	maxSubscribers int
+	subs           map[*Subscription]struct{}
+}
+
+// NewBroker keeps the last historySize events for resuming and buffers up to
+// bufferSize events per subscriber. IDs start from the current time so they keep
+// increasing across restarts, and a client holding an ID from before a restart is
+// told to reload instead of being sent a wrong backlog.
+func NewBroker(historySize, bufferSize, maxSubscribers int) *Broker {
+	return &Broker{
+		nextID:         uint64(time.Now().UnixMilli()) * 1000,
+		historySize:    historySize,
+		bufferSize:     bufferSize,
+		maxSubscribers: maxSubscribers,
+		subs:           map[*Subscription]struct{}{},
+	}
+}
+
+// Subscription receives events on C until it is closed. C is also closed when the
+// subscriber falls a full buffer behind; it should then reconnect and resume.
+type Subscription struct {
+	C      <-chan Event
+	c      chan Event
+	userID int
+	broker *Broker
+}
+
+// Publish assigns the event an ID, records it and hands it to every subscriber
+// allowed to see it without blocking
+func (b *Broker) Publish(eventType string, userID int, public bool, data []byte) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := Event{ID: b.nextID, Type: eventType, Data: data, UserID: userID, Public: public}
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for sub := range b.subs {
+		if !event.visibleTo(sub.userID) {
+			continue
+		}
+		select {
+		case sub.c <- event:
+		default:
+			// Too slow: drop the connection rather than hold up everyone else
+			b.remove(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe registers a subscriber signed in as userID (0 when anonymous). With a
+// lastID from an earlier connection it also returns the events published since, or
+// no backlog and complete false when some of them are no longer kept.
+func (b *Broker) Subscribe(userID int, lastID uint64) (sub *Subscription, backlog []Event, complete bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.subs) >= b.maxSubscribers {
+		return nil, nil, false, ErrTooManySubscribers
+	}
+
+	complete = true
+	if lastID != 0 {
+		oldest := b.nextID + 1
+		if len(b.history) > 0 {
+			oldest = b.history[0].ID
+		}
+		complete = lastID+1 >= oldest && lastID <= b.nextID
+		for _, event := range b.history {
+			if complete && event.ID > lastID && event.visibleTo(userID) {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	c := make(chan Event, b.bufferSize)
+	sub = &Subscription{C: c, c: c, userID: userID, broker: b}
+	b.subs[sub] = struct{}{}
+	return sub, backlog, complete, nil
+}
+
+// Close unregisters the subscription; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// remove must be called with b.mu held
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
@@ -0,0 +1,197 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+// received drains what sub was sent so far, and reports whether C was closed
+func received(sub *Subscription) (ids []uint64, closed bool) {
+	for {
+		select {
+		case event, open := <-sub.C:
+			if !open {
+				return ids, true
+			}
+			ids = append(ids, event.ID)
+		default:
+			return ids, false
+		}
+	}
+}
+
+func eventIDs(events []Event) []uint64 {
+	var ids []uint64
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func TestSubscribeReplaysBacklog(t *testing.T) {
+	b := NewBroker(10, 10, 10)
+	first := b.Publish("link.created", 0, true, []byte(`{}`))
+	mine := b.Publish("link.created", 1, false, []byte(`{}`))
+	b.Publish("link.created", 2, false, []byte(`{}`))
+	last := b.Publish("link.updated", 1, true, []byte(`{}`))
+
+	tests := []struct {
+		name   string
+		userID int
+		lastID uint64
+		want   []uint64
+	}{
+		{"new connection", 1, 0, nil},
+		{"owner", 1, first.ID, []uint64{mine.ID, last.ID}},
+		{"anonymous", 0, first.ID, []uint64{last.ID}},
+		{"other user", 3, first.ID, []uint64{last.ID}},
+		{"up to date", 1, last.ID, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, backlog, complete, err := b.Subscribe(tt.userID, tt.lastID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sub.Close()
+			if !complete {
+				t.Error("complete = false, want true")
+			}
+			if got := eventIDs(backlog); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("backlog = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A client that missed events the broker no longer keeps is told to reload
+func TestSubscribeAfterHistoryRollsOver(t *testing.T) {
+	b := NewBroker(3, 10, 10)
+	var published []Event
+	for i := 0; i < 5; i++ {
+		published = append(published, b.Publish("link.created", 0, true, []byte(`{}`)))
+	}
+
+	tests := []struct {
+		name     string
+		lastID   uint64
+		complete bool
+		want     []uint64
+	}{
+		{"missed a dropped event", published[0].ID, false, nil},
+		{"missed only kept events", published[1].ID, true, eventIDs(published[2:])},
+		{"ID from before a restart", published[4].ID + 1000, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, backlog, complete, err := b.Subscribe(0, tt.lastID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sub.Close()
+			if complete != tt.complete {
+				t.Errorf("complete = %v, want %v", complete, tt.complete)
+			}
+			if got := eventIDs(backlog); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("backlog = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Private events only reach subscribers signed in as their owner
+func TestPublishPrivateEvents(t *testing.T) {
+	b := NewBroker(10, 10, 10)
+	subscribe := func(userID int) *Subscription {
+		sub, _, _, err := b.Subscribe(userID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(sub.Close)
+		return sub
+	}
+	anonymous, owner, other := subscribe(0), subscribe(1), subscribe(2)
+
+	private := b.Publish("link.created", 1, false, []byte(`{}`))
+	public := b.Publish("link.created", 1, true, []byte(`{}`))
+
+	for name, tt := range map[string]struct {
+		sub  *Subscription
+		want []uint64
+	}{
+		"anonymous": {anonymous, []uint64{public.ID}},
+		"owner":     {owner, []uint64{private.ID, public.ID}},
+		"other":     {other, []uint64{public.ID}},
+	} {
+		if got, _ := received(tt.sub); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s received %v, want %v", name, got, tt.want)
+		}
+	}
+}
+
+// A subscriber that falls a full buffer behind is dropped without holding up the rest
+func TestPublishDropsSlowSubscriber(t *testing.T) {
+	b := NewBroker(10, 2, 10)
+	slow, _, _, err := b.Subscribe(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, _, _, err := b.Subscribe(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fast.Close()
+
+	var want []uint64
+	for i := 0; i < 3; i++ {
+		want = append(want, b.Publish("link.created", 0, true, []byte(`{}`)).ID)
+		if got, closed := received(fast); closed || len(got) != 1 || got[0] != want[i] {
+			t.Fatalf("fast subscriber received %v (closed %v), want [%d]", got, closed, want[i])
+		}
+	}
+
+	got, closed := received(slow)
+	if !closed {
+		t.Error("slow subscriber was not dropped")
+	}
+	if !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("slow subscriber received %v before being dropped, want %v", got, want[:2])
+	}
+	slow.Close() // Already removed; must not close C twice
+
+	// The dropped subscriber no longer takes a slot
+	b.mu.Lock()
+	subscribers := len(b.subs)
+	b.mu.Unlock()
+	if subscribers != 1 {
+		t.Errorf("%d subscribers registered, want 1", subscribers)
+	}
+}
+
+// Subscribers beyond the cap are refused until one leaves
+func TestSubscribeLimit(t *testing.T) {
+	b := NewBroker(10, 10, 2)
+	first, _, _, err := b.Subscribe(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, _, err := b.Subscribe(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	if _, _, _, err := b.Subscribe(2, 0); err != ErrTooManySubscribers {
+		t.Fatalf("third subscriber: err = %v, want ErrTooManySubscribers", err)
+	}
+
+	// Closing a subscription frees its slot
+	first.Close()
+	third, _, _, err := b.Subscribe(2, 0)
+	if err != nil {
+		t.Fatalf("after a close: %v", err)
+	}
+	third.Close()
+}
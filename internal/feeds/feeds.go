@@ -0,0 +1,172 @@
+// Package feeds renders lists of links as Atom 1.0 and RSS 2.0 documents, and
+// parses and polls the RSS, Atom and JSON feeds users subscribe to
+package feeds
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Feed is the format-independent description of a feed
+type Feed struct {
+	Title       string
+	Description string
+	ID          string // Permanent IRI identifying the feed
+	SelfURL     string // Where the feed itself is served
+	PageURL     string // The HTML page the feed mirrors
+	Updated     time.Time
+	Entries     []Entry
+}
+
+type Entry struct {
+	ID         string
+	Title      string
+	URL        string
+	Summary    string
+	Author     string
+	Categories []string
+	Published  time.Time
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Author     *atomAuthor    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// Atom renders the feed as an Atom 1.0 document. The feed's author falls back to
+// its title, since Atom requires one on every entry.
+func Atom(f Feed) ([]byte, error) {
+	doc := atomFeed{
+		Title:    f.Title,
+		Subtitle: f.Description,
+		ID:       f.ID,
+		Updated:  f.Updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: f.SelfURL},
+			{Rel: "alternate", Type: "text/html", Href: f.PageURL},
+		},
+	}
+	for _, e := range f.Entries {
+		author := e.Author
+		if author == "" {
+			author = f.Title
+		}
+		entry := atomEntry{
+			Title:     e.Title,
+			ID:        e.ID,
+			Link:      atomLink{Rel: "alternate", Href: e.URL},
+			Published: e.Published.Format(time.RFC3339),
+			Updated:   e.Published.Format(time.RFC3339),
+			Author:    &atomAuthor{Name: author},
+			Summary:   e.Summary,
+		}
+		for _, c := range e.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: c})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return render(doc)
+}
+
+type rssDoc struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          rssSelf   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Author      string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders the feed as an RSS 2.0 document
+func RSS(f Feed) ([]byte, error) {
+	description := f.Description
+	if description == "" {
+		description = f.Title
+	}
+	channel := rssChannel{
+		Title:         f.Title,
+		Link:          f.PageURL,
+		Description:   description,
+		Self:          rssSelf{Href: f.SelfURL, Rel: "self", Type: "application/rss+xml"},
+		LastBuildDate: f.Updated.Format(time.RFC1123Z),
+	}
+	for _, e := range f.Entries {
+		channel.Items = append(channel.Items, rssItem{
+			Title:       e.Title,
+			Link:        e.URL,
+			GUID:        rssGUID{Value: e.ID},
+			PubDate:     e.Published.Format(time.RFC1123Z),
+			Author:      e.Author,
+			Categories:  e.Categories,
+			Description: e.Summary,
+		})
+	}
+	return render(rssDoc{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", DCNS: "http://purl.org/dc/elements/1.1/", Channel: channel})
+}
+
+func render(doc interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
@@ -0,0 +1,287 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNotAFeed is returned by Parse for documents that are not RSS, Atom or JSON Feed
+var ErrNotAFeed = errors.New("not an RSS, Atom or JSON feed")
+
+// Parse reads an RSS 0.9x/1.0/2.0, Atom 1.0 or JSON Feed 1.x document. Entry URLs
+// are returned as found, so relative ones must be resolved by the caller. Only the
+// Title, URL, ID, Summary, Categories and Published fields of entries are set, and
+// ID falls back to the URL for items without one.
+func Parse(body []byte) (*Feed, error) {
+	body = bytes.TrimPrefix(bytes.TrimSpace(body), []byte("\xef\xbb\xbf"))
+	if len(body) > 0 && body[0] == '{' {
+		return parseJSONFeed(body)
+	}
+
+	var doc xmlFeed
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charsetReader
+	decoder.Strict = false
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotAFeed, err)
+	}
+
+	var feed *Feed
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss":
+		feed = doc.Channel.feed(doc.Channel.Items)
+	case "rdf":
+		// RSS 1.0 puts items next to the channel rather than inside it
+		feed = doc.Channel.feed(doc.Items)
+	case "feed":
+		feed = doc.atom()
+	default:
+		return nil, ErrNotAFeed
+	}
+
+	for i := range feed.Entries {
+		if feed.Entries[i].ID == "" {
+			feed.Entries[i].ID = feed.Entries[i].URL
+		}
+	}
+	return feed, nil
+}
+
+// xmlFeed decodes the RSS and Atom elements Parse uses. encoding/xml matches on the
+// local name when a field gives no namespace, so one struct serves every dialect.
+type xmlFeed struct {
+	XMLName xml.Name
+	Channel rssSource  `xml:"channel"`
+	Items   []rssEntry `xml:"item"`
+
+	// Atom
+	Title   string            `xml:"title"`
+	Links   []atomLink        `xml:"link"`
+	Updated string            `xml:"updated"`
+	Entries []atomSourceEntry `xml:"entry"`
+}
+
+// atomSourceEntry differs from atomEntry, which renders entries, in accepting any
+// number of links
+type atomSourceEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []atomLink     `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary"`
+}
+
+type rssSource struct {
+	Title       string     `xml:"title"`
+	Links       []rssLink  `xml:"link"`
+	Description string     `xml:"description"`
+	Items       []rssEntry `xml:"item"`
+}
+
+// rssLink also matches the atom:link elements many RSS feeds carry next to their
+// own link, which have an href instead of text
+type rssLink struct {
+	Value string `xml:",chardata"`
+}
+
+type rssEntry struct {
+	Title       string    `xml:"title"`
+	Links       []rssLink `xml:"link"`
+	GUID        string    `xml:"guid"`
+	Description string    `xml:"description"`
+	PubDate     string    `xml:"pubDate"`
+	Date        string    `xml:"date"` // dc:date
+	Categories  []string  `xml:"category"`
+}
+
+func (c rssSource) feed(items []rssEntry) *Feed {
+	feed := &Feed{Title: strings.TrimSpace(c.Title), Description: strings.TrimSpace(c.Description), PageURL: rssHref(c.Links)}
+	for _, item := range items {
+		date := item.PubDate
+		if date == "" {
+			date = item.Date
+		}
+		feed.Entries = append(feed.Entries, Entry{
+			ID:         strings.TrimSpace(item.GUID),
+			Title:      strings.TrimSpace(item.Title),
+			URL:        rssHref(item.Links),
+			Summary:    strings.TrimSpace(item.Description),
+			Categories: trimAll(item.Categories),
+			Published:  parseDate(date),
+		})
+	}
+	return feed
+}
+
+func (doc xmlFeed) atom() *Feed {
+	feed := &Feed{Title: strings.TrimSpace(doc.Title), PageURL: atomHref(doc.Links), Updated: parseDate(doc.Updated)}
+	for _, e := range doc.Entries {
+		published := e.Published
+		if published == "" {
+			published = e.Updated
+		}
+		var categories []string
+		for _, c := range e.Categories {
+			categories = append(categories, c.Term)
+		}
+		feed.Entries = append(feed.Entries, Entry{
+			ID:         strings.TrimSpace(e.ID),
+			Title:      strings.TrimSpace(e.Title),
+			URL:        atomHref(e.Links),
+			Summary:    strings.TrimSpace(e.Summary),
+			Categories: trimAll(categories),
+			Published:  parseDate(published),
+		})
+	}
+	return feed
+}
+
+// rssHref returns the first link with text
+func rssHref(links []rssLink) string {
+	for _, l := range links {
+		if href := strings.TrimSpace(l.Value); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+// atomHref returns the alternate link, which is the one without a rel attribute
+// or with rel="alternate"
+func atomHref(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return strings.TrimSpace(l.Href)
+		}
+	}
+	return ""
+}
+
+type jsonFeed struct {
+	Version     string `json:"version"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	HomePageURL string `json:"home_page_url"`
+	Items       []struct {
+		ID            json.RawMessage `json:"id"` // A string, though some feeds send numbers
+		URL           string          `json:"url"`
+		ExternalURL   string          `json:"external_url"`
+		Title         string          `json:"title"`
+		Summary       string          `json:"summary"`
+		ContentText   string          `json:"content_text"`
+		DatePublished string          `json:"date_published"`
+		Tags          []string        `json:"tags"`
+	} `json:"items"`
+}
+
+func parseJSONFeed(body []byte) (*Feed, error) {
+	var doc jsonFeed
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotAFeed, err)
+	}
+	if !strings.HasPrefix(doc.Version, "https://jsonfeed.org/version/") {
+		return nil, ErrNotAFeed
+	}
+
+	feed := &Feed{Title: strings.TrimSpace(doc.Title), Description: strings.TrimSpace(doc.Description), PageURL: doc.HomePageURL}
+	for _, item := range doc.Items {
+		id := jsonFeedID(item.ID)
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+		summary := item.Summary
+		if summary == "" {
+			summary = item.ContentText
+		}
+		if id == "" {
+			id = strings.TrimSpace(link)
+		}
+		feed.Entries = append(feed.Entries, Entry{
+			ID:         id,
+			Title:      strings.TrimSpace(item.Title),
+			URL:        strings.TrimSpace(link),
+			Summary:    strings.TrimSpace(summary),
+			Categories: trimAll(item.Tags),
+			Published:  parseDate(item.DatePublished),
+		})
+	}
+	return feed, nil
+}
+
+// jsonFeedID decodes an item id, which is a string or a number. Missing and null ids
+// are empty.
+func jsonFeedID(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.TrimSpace(s)
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// dateLayouts are the timestamp formats seen in the wild, most common first
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate returns the zero time for dates in none of dateLayouts
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func trimAll(values []string) []string {
+	var trimmed []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			trimmed = append(trimmed, v)
+		}
+	}
+	return trimmed
+}
+
+// charsetReader converts the single-byte encodings older feeds still declare.
+// Windows-1252 is read as Latin-1, which only differs in rarely used punctuation.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252", "cp1252":
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		converted := make([]byte, 0, len(data))
+		for _, b := range data {
+			converted = utf8.AppendRune(converted, rune(b))
+		}
+		return bytes.NewReader(converted), nil
+	}
+	return nil, fmt.Errorf("unsupported charset %q", charset)
+}
@@ -0,0 +1,124 @@
+package feeds
+
+import (
+	"reflect"
+	"testing"
+)
+
+const rssFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+	<title>Example RSS</title>
+	<link>https://example.com/</link>
+	<atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+	<item>
+		<title>With a guid</title>
+		<link>https://example.com/one</link>
+		<guid isPermaLink="false">tag:example.com,2025:1</guid>
+	</item>
+	<item>
+		<title>Without a guid</title>
+		<link>https://example.com/two</link>
+	</item>
+	<item>
+		<title>Relative link</title>
+		<link>/three</link>
+		<guid>3</guid>
+	</item>
+</channel>
+</rss>`
+
+const atomFixture = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Example Atom</title>
+	<link href="https://example.com/"/>
+	<entry>
+		<title>With an id</title>
+		<id>urn:uuid:60a76c80-d399-11d9-b93c-0003939e0af6</id>
+		<link rel="self" href="https://example.com/one.atom"/>
+		<link href="https://example.com/one"/>
+	</entry>
+	<entry>
+		<title>Without an id</title>
+		<link rel="alternate" href="https://example.com/two"/>
+	</entry>
+	<entry>
+		<title>Relative link</title>
+		<id>3</id>
+		<link href="three"/>
+	</entry>
+</feed>`
+
+const jsonFixture = `{
+	"version": "https://jsonfeed.org/version/1.1",
+	"title": "Example JSON",
+	"items": [
+		{"id": "tag:example.com,2025:1", "url": "https://example.com/one"},
+		{"id": null, "url": "https://example.com/two"},
+		{"url": "https://example.com/missing"},
+		{"id": "", "external_url": "https://example.com/external"},
+		{"id": 42, "url": "https://example.com/number"},
+		{"id": "a\/bé", "url": "https://example.com/escaped"},
+		{"id": "3", "url": "/three"}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		title string
+		want  [][2]string // ID and URL of each entry
+	}{
+		{"rss", rssFixture, "Example RSS", [][2]string{
+			{"tag:example.com,2025:1", "https://example.com/one"},
+			{"https://example.com/two", "https://example.com/two"}, // A missing guid falls back to the link
+			{"3", "/three"}, // Relative links are resolved by the poller
+		}},
+		{"atom", atomFixture, "Example Atom", [][2]string{
+			{"urn:uuid:60a76c80-d399-11d9-b93c-0003939e0af6", "https://example.com/one"},
+			{"https://example.com/two", "https://example.com/two"},
+			{"3", "three"},
+		}},
+		{"json feed", jsonFixture, "Example JSON", [][2]string{
+			{"tag:example.com,2025:1", "https://example.com/one"},
+			{"https://example.com/two", "https://example.com/two"}, // A null id falls back to the URL
+			{"https://example.com/missing", "https://example.com/missing"},
+			{"https://example.com/external", "https://example.com/external"},
+			{"42", "https://example.com/number"},
+			{"a/bé", "https://example.com/escaped"},
+			{"3", "/three"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := Parse([]byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if feed.Title != tt.title {
+				t.Errorf("title = %q, want %q", feed.Title, tt.title)
+			}
+			var got [][2]string
+			for _, entry := range feed.Entries {
+				got = append(got, [2]string{entry.ID, entry.URL})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsOtherDocuments(t *testing.T) {
+	for _, body := range []string{
+		`<html><body>Not a feed</body></html>`,
+		`{"version": "1.0", "items": []}`,
+		`not even markup`,
+	} {
+		if _, err := Parse([]byte(body)); err == nil {
+			t.Errorf("Parse(%q) succeeded", body)
+		}
+	}
+}
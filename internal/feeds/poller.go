@@ -0,0 +1,256 @@
+package feeds
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"links/internal/models"
+	"links/internal/safehttp"
+)
+
+const (
+	// PollInterval is how long a feed rests after a successful poll. Failed polls
+	// wait twice as long each time, up to maxBackoff.
+	PollInterval = time.Hour
+	maxBackoff   = 24 * time.Hour
+
+	// checkInterval is how often the poller looks for due subscriptions, and lease
+	// how long a claimed subscription is hidden from the next check
+	checkInterval = time.Minute
+	lease         = 10 * time.Minute
+	batchSize     = 10
+
+	// maxFeedSize caps how much of a feed document is read
+	maxFeedSize = 5 << 20
+
+	// maxNewItems caps the links saved by one poll, so subscribing to a feed with a
+	// long archive saves only its newest items; the rest are marked seen
+	maxNewItems = 20
+)
+
+var (
+	// ErrSkip is returned by a SaveFunc for entries that must not be saved, such as
+	// links to blocked domains. They are marked seen like saved ones.
+	ErrSkip = errors.New("entry skipped")
+
+	// ErrPollInProgress is returned by Poll while the subscription is already being
+	// polled
+	ErrPollInProgress = errors.New("feed is already being polled")
+)
+
+// Store is the part of the database the poller needs
+type Store interface {
+	ClaimFeedSubscriptions(now time.Time, lease time.Duration, limit int) ([]models.FeedSubscription, error)
+	RecordFeedPoll(sub *models.FeedSubscription, nextCheck time.Time) error
+	FeedItemSeen(sub *models.FeedSubscription, guid, url string) (bool, error)
+	RecordFeedItem(subscriptionID int, guid, url string) error
+}
+
+// SaveFunc saves a new entry, whose URL is absolute, as a link of the subscriber
+type SaveFunc func(sub *models.FeedSubscription, entry Entry) error
+
+type Poller struct {
+	store  Store
+	client *http.Client
+	save   SaveFunc
+
+	mu      sync.Mutex
+	polling map[int]bool
+}
+
+// NewPoller returns a poller that fetches feeds with client and hands new entries to
+// save. In production client should come from safehttp so subscriptions cannot
+// reach internal addresses.
+func NewPoller(store Store, client *http.Client, save SaveFunc) *Poller {
+	return &Poller{store: store, client: client, save: save, polling: map[int]bool{}}
+}
+
+// lastError is the reason shown on the subscription. Connection errors are reduced to
+// their kind, since they can describe the server's own network.
+func lastError(err error) string {
+	var urlErr *url.Error
+	switch {
+	case errors.Is(err, safehttp.ErrBlockedAddress):
+		return "destination address is not allowed"
+	case errors.As(err, &urlErr) && urlErr.Timeout():
+		return "timed out"
+	case errors.As(err, &urlErr):
+		return "could not connect"
+	}
+	return err.Error()
+}
+
+// Backoff returns how long to wait after the given number of consecutive failures
+func Backoff(failures int) time.Duration {
+	wait := PollInterval
+	for i := 1; i < failures && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+// Run polls due subscriptions until the process exits
+func (p *Poller) Run() {
+	for {
+		subs, err := p.store.ClaimFeedSubscriptions(time.Now(), lease, batchSize)
+		if err != nil {
+			log.Printf("Feed poller failed to load subscriptions: %v", err)
+		}
+		for i := range subs {
+			// Fetch and parse failures are recorded on the subscription
+			p.Poll(&subs[i])
+		}
+
+		// Keep going straight away while there is a backlog
+		if len(subs) < batchSize {
+			time.Sleep(checkInterval)
+		}
+	}
+}
+
+// Poll fetches the feed, saves entries not seen before and records the outcome on
+// the subscription and in the store. A fetch or parse failure is returned as well
+// as recorded; the subscription is then retried with backoff.
+func (p *Poller) Poll(sub *models.FeedSubscription) (*models.FeedPollResult, error) {
+	p.mu.Lock()
+	if p.polling[sub.ID] {
+		p.mu.Unlock()
+		return nil, ErrPollInProgress
+	}
+	p.polling[sub.ID] = true
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.polling, sub.ID)
+		p.mu.Unlock()
+	}()
+
+	now := time.Now()
+	checkedAt := now.Format("2006-01-02 15:04:05")
+	sub.LastCheckedAt = &checkedAt
+	result := &models.FeedPollResult{Subscription: sub}
+
+	err := p.poll(sub, result)
+	next := now.Add(PollInterval)
+	if err != nil {
+		sub.Failures++
+		sub.LastError = lastError(err)
+		if sub.LastError != err.Error() {
+			log.Printf("Polling feed subscription %d failed: %v", sub.ID, err)
+		}
+		next = now.Add(Backoff(sub.Failures))
+	} else {
+		sub.Failures = 0
+		sub.LastError = ""
+	}
+
+	if recordErr := p.store.RecordFeedPoll(sub, next); recordErr != nil {
+		log.Printf("Failed to record poll of feed subscription %d: %v", sub.ID, recordErr)
+	}
+	return result, err
+}
+
+func (p *Poller) poll(sub *models.FeedSubscription, result *models.FeedPollResult) error {
+	base, err := url.Parse(sub.URL)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("GET", sub.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Links-Feeds/1.0")
+	req.Header.Set("Accept", "application/atom+xml, application/rss+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+	if sub.ETag != "" {
+		req.Header.Set("If-None-Match", sub.ETag)
+	}
+	if sub.LastModified != "" {
+		req.Header.Set("If-Modified-Since", sub.LastModified)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxFeedSize {
+		return fmt.Errorf("feed is larger than %d MB", maxFeedSize>>20)
+	}
+	feed, err := Parse(body)
+	if err != nil {
+		return err
+	}
+
+	// Name unnamed subscriptions after the feed, escaped like other user-visible text
+	if title := []rune(strings.Join(strings.Fields(feed.Title), " ")); sub.Name == "" && len(title) > 0 {
+		if len(title) > 100 {
+			title = title[:100]
+		}
+		sub.Name = html.EscapeString(string(title))
+	}
+
+	for _, entry := range feed.Entries {
+		link, err := base.Parse(entry.URL)
+		if entry.URL == "" || err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			continue
+		}
+		entry.URL = link.String()
+
+		seen, err := p.store.FeedItemSeen(sub, entry.ID, entry.URL)
+		if err != nil {
+			return err
+		}
+		if seen {
+			result.Skipped++
+			continue
+		}
+
+		if result.Saved < maxNewItems {
+			switch err := p.save(sub, entry); {
+			case err == nil:
+				result.Saved++
+			case errors.Is(err, ErrSkip):
+				result.Skipped++
+			default:
+				// Not marked seen, so the entry is tried again on the next poll
+				return err
+			}
+		} else {
+			result.Skipped++
+		}
+
+		if err := p.store.RecordFeedItem(sub.ID, entry.ID, entry.URL); err != nil {
+			return err
+		}
+	}
+
+	// Only remember the validators once every entry is handled, so a poll cut short
+	// is not answered with 304 next time
+	sub.ETag = resp.Header.Get("ETag")
+	sub.LastModified = resp.Header.Get("Last-Modified")
+	return nil
+}
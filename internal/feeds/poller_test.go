@@ -0,0 +1,132 @@
+package feeds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"links/internal/db"
+	"links/internal/models"
+)
+
+// item is a feed entry to serve; an empty ID leaves it out, or null in a JSON Feed
+type item struct{ id, url string }
+
+func rssDocument(items []item) string {
+	var b strings.Builder
+	b.WriteString(`<rss version="2.0"><channel><title>Feed</title>`)
+	for _, it := range items {
+		fmt.Fprintf(&b, `<item><link>%s</link>`, it.url)
+		if it.id != "" {
+			fmt.Fprintf(&b, `<guid>%s</guid>`, it.id)
+		}
+		b.WriteString(`</item>`)
+	}
+	b.WriteString(`</channel></rss>`)
+	return b.String()
+}
+
+func atomDocument(items []item) string {
+	var b strings.Builder
+	b.WriteString(`<feed xmlns="http://www.w3.org/2005/Atom"><title>Feed</title>`)
+	for _, it := range items {
+		fmt.Fprintf(&b, `<entry><link href="%s"/>`, it.url)
+		if it.id != "" {
+			fmt.Fprintf(&b, `<id>%s</id>`, it.id)
+		}
+		b.WriteString(`</entry>`)
+	}
+	b.WriteString(`</feed>`)
+	return b.String()
+}
+
+func jsonDocument(items []item) string {
+	var entries []map[string]interface{}
+	for _, it := range items {
+		var id interface{}
+		if it.id != "" {
+			id = it.id
+		}
+		entries = append(entries, map[string]interface{}{"id": id, "url": it.url})
+	}
+	doc, _ := json.Marshal(map[string]interface{}{"version": "https://jsonfeed.org/version/1.1", "title": "Feed", "items": entries})
+	return string(doc)
+}
+
+// Polling a feed again saves only the entries not handled before, matched by GUID or URL
+func TestPollSavesEachEntryOnce(t *testing.T) {
+	first := []item{
+		{"1", "/one"}, // Relative to the feed
+		{"", "https://example.org/two"},
+		{"", "https://example.org/three"},
+	}
+	second := []item{
+		{"4", "https://example.org/four"},
+		{"1", "/one"},
+		{"", "https://example.org/two"},
+		{"99", "https://example.org/three"}, // Same URL under a new GUID
+	}
+
+	tests := []struct {
+		name     string
+		document func([]item) string
+	}{
+		{"rss", rssDocument},
+		{"atom", atomDocument},
+		{"json feed", jsonDocument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := db.New(filepath.Join(t.TempDir(), "links.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			userID, err := store.CreateUser("alice", "hash", time.Now().Format("2006-01-02 15:04:05"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var document string
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, document)
+			}))
+			defer receiver.Close()
+			sub, err := store.CreateFeedSubscription(int(userID), receiver.URL+"/feed", "", false, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var saved []string
+			poller := NewPoller(store, receiver.Client(), func(sub *models.FeedSubscription, entry Entry) error {
+				saved = append(saved, entry.URL)
+				return nil
+			})
+			poll := func(items []item, want []string, skipped int) {
+				t.Helper()
+				document = tt.document(items)
+				saved = nil
+				result, err := poller.Poll(sub)
+				if err != nil {
+					t.Fatalf("poll: %v", err)
+				}
+				if !reflect.DeepEqual(saved, want) {
+					t.Errorf("saved %q, want %q", saved, want)
+				}
+				if result.Saved != len(want) || result.Skipped != skipped {
+					t.Errorf("result = %d saved, %d skipped; want %d, %d", result.Saved, result.Skipped, len(want), skipped)
+				}
+			}
+
+			poll(first, []string{receiver.URL + "/one", "https://example.org/two", "https://example.org/three"}, 0)
+			poll(second, []string{"https://example.org/four"}, 3)
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"links/internal/auth"
+	"links/internal/db"
+	"links/internal/mailer"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+type AccountHandler struct {
+	db     AccountDBInterface
+	mailer mailer.Mailer
+}
+
+type AccountDBInterface interface {
+	GetProfile(userID int) (*models.Profile, error)
+	GetPasswordHash(userID int) (string, error)
+	UpdateUsername(userID int, username string) error
+	UpdateEmail(userID int, email *string) error
+	UpdatePassword(userID int, hashedPassword string) (int, error)
+	GetLinksByUserID(userID int) ([]models.Link, error)
+	DeleteUser(userID int) error
+	CreateUserToken(userID int, tokenHash, purpose, email string, ttl time.Duration) error
+	WriteAudit(entry models.AuditEntry) error
+	GetAPITokens(userID int) ([]models.APIToken, error)
+	CountAPITokens(userID int) (int, error)
+	CreateAPIToken(userID int, name, tokenHash string) (*models.APIToken, error)
+	DeleteAPIToken(id, userID int) error
+}
+
+func NewAccountHandler(db AccountDBInterface, m mailer.Mailer) *AccountHandler {
+	return &AccountHandler{db: db, mailer: m}
+}
+
+func (h *AccountHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	profile, err := h.db.GetProfile(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get profile")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// UpdateProfile changes username and/or email. A new token is returned since the
+// username is part of the session claims.
+func (h *AccountHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req models.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Username != nil {
+		username := strings.TrimSpace(*req.Username)
+		if !validUsername(username) {
+			writeError(w, "Invalid username format", http.StatusBadRequest)
+			return
+		}
+		if err := h.db.UpdateUsername(user.ID, username); errors.Is(err, db.ErrConflict) {
+			writeError(w, "Username already exists", http.StatusConflict)
+			return
+		} else if err != nil {
+			writeDBError(w, err, "User not found", "Failed to update username")
+			return
+		}
+		if username != user.Username {
+			recordAudit(h.db, r, models.AuditEntry{
+				Action:     "user.username",
+				TargetType: "user",
+				TargetID:   strconv.Itoa(user.ID),
+				Before:     auditValue(map[string]string{"username": user.Username}),
+				After:      auditValue(map[string]string{"username": username}),
+			})
+		}
+	}
+
+	if req.Email != nil {
+		var email *string
+		if trimmed := strings.TrimSpace(*req.Email); trimmed != "" {
+			if !validEmail(trimmed) {
+				writeError(w, "Invalid email address", http.StatusBadRequest)
+				return
+			}
+			email = &trimmed
+		}
+
+		current, err := h.db.GetProfile(user.ID)
+		if err != nil {
+			writeDBError(w, err, "", "Failed to update email")
+			return
+		}
+		changed := (email == nil) != (current.Email == nil) || (email != nil && *email != *current.Email)
+
+		if changed {
+			if err := h.db.UpdateEmail(user.ID, email); err != nil {
+				writeDBError(w, err, "", "Failed to update email")
+				return
+			}
+			recordAudit(h.db, r, models.AuditEntry{
+				Action:     "user.email",
+				TargetType: "user",
+				TargetID:   strconv.Itoa(user.ID),
+				Before:     auditValue(map[string]*string{"email": current.Email}),
+				After:      auditValue(map[string]*string{"email": email}),
+			})
+			if email != nil {
+				if err := sendVerificationEmail(h.db, h.mailer, user.ID, *email); err != nil {
+					log.Printf("Failed to send verification email for user %d: %v", user.ID, err)
+				}
+			}
+		}
+	}
+
+	h.writeProfileSession(w, user.ID)
+}
+
+// ChangePassword sets a new password. Accounts that already have one must confirm the
+// current password; OAuth-only accounts can set a first password. All other sessions
+// are revoked and a fresh token is returned for this one.
+func (h *AccountHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req models.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := h.db.GetPasswordHash(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to change password")
+		return
+	}
+
+	if hashedPassword != "" {
+		if err := auth.CheckPassword(hashedPassword, req.CurrentPassword); err != nil {
+			writeError(w, "Current password is incorrect", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if !validPassword(req.NewPassword) {
+		writeError(w, "Invalid password format", http.StatusBadRequest)
+		return
+	}
+
+	newHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		writeError(w, "Error hashing password", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.db.UpdatePassword(user.ID, newHash); err != nil {
+		writeError(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.password",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+	})
+
+	h.writeProfileSession(w, user.ID)
+}
+
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	export, err := h.buildExport(user.ID)
+	if err != nil {
+		writeError(w, "Failed to export account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="links-export.json"`)
+	json.NewEncoder(w).Encode(export)
+}
+
+// DeleteAccount permanently deletes the user and their links, optionally returning an
+// export of the data in the same response
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req models.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := h.db.GetPasswordHash(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to delete account")
+		return
+	}
+	if hashedPassword != "" {
+		if err := auth.CheckPassword(hashedPassword, req.Password); err != nil {
+			writeError(w, "Password is incorrect", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var export *models.AccountExport
+	if req.Export {
+		export, err = h.buildExport(user.ID)
+		if err != nil {
+			writeError(w, "Failed to export account", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := h.db.DeleteUser(user.ID); err != nil {
+		writeDBError(w, err, "", "Failed to delete account")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.delete",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     auditValue(map[string]string{"username": user.Username}),
+	})
+
+	if export == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="links-export.json"`)
+	json.NewEncoder(w).Encode(export)
+}
+
+func (h *AccountHandler) buildExport(userID int) (*models.AccountExport, error) {
+	profile, err := h.db.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := h.db.GetLinksByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if links == nil {
+		links = []models.Link{}
+	}
+
+	return &models.AccountExport{
+		Profile:    *profile,
+		Links:      links,
+		ExportedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}, nil
+}
+
+// writeProfileSession responds with a fresh token and the updated user
+func (h *AccountHandler) writeProfileSession(w http.ResponseWriter, userID int) {
+	profile, err := h.db.GetProfile(userID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get profile")
+		return
+	}
+
+	token, _ := auth.GenerateJWT(profile.ID, profile.Username, profile.IsAdmin, profile.SessionVersion)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AuthResponse{Token: token, User: profile.User})
+}
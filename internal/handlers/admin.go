@@ -1,14 +1,17 @@
 package handlers
 
 import (
-	"database/sql"
+	"encoding/csv"
 	"encoding/json"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"links/internal/auth"
 	"links/internal/db"
 	"links/internal/middleware"
+	"links/internal/models"
 )
 
 type AdminHandler struct {
@@ -19,69 +22,204 @@ func NewAdminHandler(database *db.Database) *AdminHandler {
 	return &AdminHandler{db: database}
 }
 
-func (h *AdminHandler) GetAllLinks(w http.ResponseWriter, r *http.Request) {
+type AdminPolicy struct {
+	RequireAdmin2FA bool `json:"require_admin_2fa"`
+}
+
+func (h *AdminHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	required, err := h.db.RequireAdmin2FA()
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get policy")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AdminPolicy{RequireAdmin2FA: required})
+}
+
+func (h *AdminHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUserFromContext(r.Context())
-	if user == nil || !user.IsAdmin {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+
+	var req AdminPolicy
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	// Avoid locking the acting admin out of the admin endpoints
+	if req.RequireAdmin2FA {
+		current, err := h.db.GetUserByID(user.ID)
+		if err != nil || !current.HasSecondFactor() {
+			writeError(w, "Enable two-factor authentication on your own account first", http.StatusBadRequest)
+			return
+		}
+	}
+
+	before, err := h.db.RequireAdmin2FA()
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get policy")
+		return
+	}
+
+	if err := h.db.SetRequireAdmin2FA(req.RequireAdmin2FA); err != nil {
+		writeDBError(w, err, "", "Failed to update policy")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "settings.update",
+		TargetType: "setting",
+		TargetID:   "require_admin_2fa",
+		Before:     auditValue(AdminPolicy{RequireAdmin2FA: before}),
+		After:      auditValue(req),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+const (
+	listPageSize    = 50
+	listMaxPageSize = 200
+)
+
+// listParams reads the paging and sort parameters shared by the admin listings.
+// Results are newest first unless ?order=asc is given.
+func listParams(q url.Values) (limit, offset int, sort string, desc bool) {
+	limit, _ = strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > listMaxPageSize {
+		limit = listPageSize
+	}
+	offset, _ = strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset, q.Get("sort"), q.Get("order") != "asc"
+}
+
+// boolParam parses an optional true/false query parameter; nil means no filter
+func boolParam(q url.Values, name string) (*bool, error) {
+	value := q.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetAllLinks lists links a page at a time, filtered by ?q=, ?username=, ?domain=,
+// ?private=, ?locked=, ?since= and ?until= and sorted by ?sort= and ?order=
+func (h *AdminHandler) GetAllLinks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.LinkFilter{
+		Query:    strings.TrimSpace(q.Get("q")),
+		Username: strings.TrimSpace(q.Get("username")),
+		Domain:   strings.TrimSpace(q.Get("domain")),
+		Since:    q.Get("since"),
+		Until:    q.Get("until"),
+	}
+	filter.Limit, filter.Offset, filter.Sort, filter.Desc = listParams(q)
+
+	var err error
+	if filter.IsPrivate, err = boolParam(q, "private"); err != nil {
+		writeError(w, "Invalid private filter", http.StatusBadRequest)
+		return
+	}
+	if filter.IsLocked, err = boolParam(q, "locked"); err != nil {
+		writeError(w, "Invalid locked filter", http.StatusBadRequest)
 		return
 	}
 
-	links, err := h.db.GetAllLinks()
+	links, total, err := h.db.GetAdminLinks(filter)
 	if err != nil {
-		http.Error(w, "Failed to get links", http.StatusInternalServerError)
+		writeDBError(w, err, "", "Failed to get links")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(links)
+	json.NewEncoder(w).Encode(models.LinkPage{Links: links, Total: total, Limit: filter.Limit, Offset: filter.Offset})
 }
 
+// GetAllUsers lists users a page at a time, filtered by ?q=, ?role=, ?admin=,
+// ?since= and ?until= and sorted by ?sort= and ?order=
 func (h *AdminHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	user := middleware.GetUserFromContext(r.Context())
-	if user == nil || !user.IsAdmin {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+	q := r.URL.Query()
+	filter := models.UserFilter{
+		Query: strings.TrimSpace(q.Get("q")),
+		Role:  q.Get("role"),
+		Since: q.Get("since"),
+		Until: q.Get("until"),
+	}
+	filter.Limit, filter.Offset, filter.Sort, filter.Desc = listParams(q)
+
+	var err error
+	if filter.IsAdmin, err = boolParam(q, "admin"); err != nil {
+		writeError(w, "Invalid admin filter", http.StatusBadRequest)
 		return
 	}
 
-	users, err := h.db.GetAllUsers()
+	users, total, err := h.db.GetAdminUsers(filter)
 	if err != nil {
-		http.Error(w, "Failed to get users", http.StatusInternalServerError)
+		writeDBError(w, err, "", "Failed to get users")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(users)
+	json.NewEncoder(w).Encode(models.UserPage{Users: users, Total: total, Limit: filter.Limit, Offset: filter.Offset})
 }
 
-func (h *AdminHandler) DeleteLink(w http.ResponseWriter, r *http.Request) {
-	user := middleware.GetUserFromContext(r.Context())
-	if user == nil || !user.IsAdmin {
-		http.Error(w, "Admin access required", http.StatusForbidden)
-		return
+// GetStats returns dashboard totals and time series. ?days= sets the series length
+// (default 30, at most 365) and ?limit= the length of the top lists (default 10).
+func (h *AdminHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+	if days <= 0 || days > 365 {
+		days = 30
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 10
 	}
 
-	// Extract link ID from URL path (/api/admin/links/123/delete)
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 5 {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+	stats, err := h.db.GetStats(days, limit)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get stats")
 		return
 	}
-	linkID, err := strconv.Atoi(parts[4])
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (h *AdminHandler) DeleteLink(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	linkID, err := pathID(r)
 	if err != nil {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
 		return
 	}
 
-	err = h.db.AdminDeleteLink(linkID)
-	if err == sql.ErrNoRows {
-		http.Error(w, "Link not found", http.StatusNotFound)
+	before, err := h.db.AdminGetLink(linkID)
+	if err != nil {
+		writeDBError(w, err, "Link not found", "Failed to get link")
 		return
 	}
+
+	err = h.db.AdminDeleteLink(linkID, user.ID)
 	if err != nil {
-		http.Error(w, "Failed to delete link", http.StatusInternalServerError)
+		writeDBError(w, err, "Link not found", "Failed to delete link")
 		return
 	}
+	emitLinkEvent(h.db, models.EventLinkDeleted, linkID)
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "link.delete",
+		TargetType: "link",
+		TargetID:   strconv.Itoa(linkID),
+		Before:     auditValue(before),
+	})
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -91,73 +229,72 @@ type LinkLockRequest struct {
 }
 
 func (h *AdminHandler) ToggleLinkLock(w http.ResponseWriter, r *http.Request) {
-	user := middleware.GetUserFromContext(r.Context())
-	if user == nil || !user.IsAdmin {
-		http.Error(w, "Admin access required", http.StatusForbidden)
-		return
-	}
-
-	// Extract link ID from URL path (/api/admin/links/123/lock)
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 5 {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
-		return
-	}
-	linkID, err := strconv.Atoi(parts[4])
+	linkID, err := pathID(r)
 	if err != nil {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
 		return
 	}
 
 	var req LinkLockRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	err = h.db.AdminToggleLinkLock(linkID, req.IsLocked)
-	if err == sql.ErrNoRows {
-		http.Error(w, "Link not found", http.StatusNotFound)
+	before, err := h.db.AdminGetLink(linkID)
+	if err != nil {
+		writeDBError(w, err, "Link not found", "Failed to get link")
 		return
 	}
+
+	err = h.db.AdminToggleLinkLock(linkID, req.IsLocked)
 	if err != nil {
-		http.Error(w, "Failed to toggle lock", http.StatusInternalServerError)
+		writeDBError(w, err, "Link not found", "Failed to toggle lock")
 		return
 	}
+	emitLinkEvent(h.db, models.EventLinkUpdated, linkID)
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "link.lock",
+		TargetType: "link",
+		TargetID:   strconv.Itoa(linkID),
+		Before:     auditValue(map[string]bool{"is_locked": before.IsLocked}),
+		After:      auditValue(map[string]bool{"is_locked": req.IsLocked}),
+	})
 
 	w.WriteHeader(http.StatusOK)
 }
 
 func (h *AdminHandler) ForcePrivateLink(w http.ResponseWriter, r *http.Request) {
-	user := middleware.GetUserFromContext(r.Context())
-	if user == nil || !user.IsAdmin {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+	linkID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
 		return
 	}
 
-	// Extract link ID from URL path (/api/admin/links/123/force-private)
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 5 {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
-		return
-	}
-	linkID, err := strconv.Atoi(parts[4])
+	before, err := h.db.AdminGetLink(linkID)
 	if err != nil {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		writeDBError(w, err, "Link not found", "Failed to get link")
 		return
 	}
 
 	err = h.db.AdminForcePrivateLink(linkID)
-	if err == sql.ErrNoRows {
-		http.Error(w, "Link not found", http.StatusNotFound)
-		return
-	}
 	if err != nil {
-		http.Error(w, "Failed to force private", http.StatusInternalServerError)
+		writeDBError(w, err, "Link not found", "Failed to force private")
 		return
 	}
+	emitLinkEvent(h.db, models.EventLinkUpdated, linkID)
+	if !before.IsPrivate {
+		emitLinkHidden(linkID)
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "link.force_private",
+		TargetType: "link",
+		TargetID:   strconv.Itoa(linkID),
+		Before:     auditValue(map[string]bool{"is_private": before.IsPrivate, "is_locked": before.IsLocked}),
+		After:      auditValue(map[string]bool{"is_private": true, "is_locked": true}),
+	})
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -168,84 +305,342 @@ type UserAdminRequest struct {
 
 func (h *AdminHandler) ToggleUserAdmin(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUserFromContext(r.Context())
-	if user == nil || !user.IsAdmin {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+
+	userID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	// Prevent admin from demoting themselves
+	if userID == user.ID {
+		writeError(w, "Cannot modify own admin status", http.StatusBadRequest)
 		return
 	}
 
-	// Extract user ID from URL path (/api/admin/users/123/admin)
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 5 {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	var req UserAdminRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	target, err := h.db.GetUserByID(userID)
+	if err != nil {
+		writeDBError(w, err, "User not found", "Failed to get user")
 		return
 	}
-	userID, err := strconv.Atoi(parts[4])
+
+	err = h.db.AdminToggleUserAdmin(userID, req.IsAdmin)
+	if err != nil {
+		writeDBError(w, err, "User not found", "Failed to toggle admin")
+		return
+	}
+
+	after := auth.RoleUser
+	if req.IsAdmin {
+		after = auth.RoleAdmin
+	}
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.role",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Before:     auditValue(map[string]string{"role": target.Role}),
+		After:      auditValue(map[string]string{"role": after}),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+type UserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (h *AdminHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auth.Roles)
+}
+
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	userID, err := pathID(r)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeError(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
 
 	// Prevent admin from demoting themselves
 	if userID == user.ID {
-		http.Error(w, "Cannot modify own admin status", http.StatusBadRequest)
+		writeError(w, "Cannot modify own role", http.StatusBadRequest)
 		return
 	}
 
-	var req UserAdminRequest
+	var req UserRoleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !auth.ValidRole(req.Role) {
+		writeError(w, "Unknown role", http.StatusBadRequest)
 		return
 	}
 
-	err = h.db.AdminToggleUserAdmin(userID, req.IsAdmin)
-	if err == sql.ErrNoRows {
-		http.Error(w, "User not found", http.StatusNotFound)
+	target, err := h.db.GetUserByID(userID)
+	if err != nil {
+		writeDBError(w, err, "User not found", "Failed to get user")
 		return
 	}
+
+	err = h.db.AdminSetUserRole(userID, req.Role)
 	if err != nil {
-		http.Error(w, "Failed to toggle admin", http.StatusInternalServerError)
+		writeDBError(w, err, "User not found", "Failed to set role")
 		return
 	}
 
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.role",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Before:     auditValue(map[string]string{"role": target.Role}),
+		After:      auditValue(map[string]string{"role": req.Role}),
+	})
+
 	w.WriteHeader(http.StatusOK)
 }
 
 func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUserFromContext(r.Context())
-	if user == nil || !user.IsAdmin {
-		http.Error(w, "Admin access required", http.StatusForbidden)
+
+	userID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
 
-	// Extract user ID from URL path (/api/admin/users/123/delete)
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 5 {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+	// Prevent admin from deleting themselves
+	if userID == user.ID {
+		writeError(w, "Cannot delete own account", http.StatusBadRequest)
 		return
 	}
-	userID, err := strconv.Atoi(parts[4])
+
+	target, err := h.db.GetUserByID(userID)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		writeDBError(w, err, "User not found", "Failed to get user")
 		return
 	}
 
-	// Prevent admin from deleting themselves
-	if userID == user.ID {
-		http.Error(w, "Cannot delete own account", http.StatusBadRequest)
+	err = h.db.AdminDeleteUser(userID)
+	if err != nil {
+		writeDBError(w, err, "User not found", "Failed to delete user")
 		return
 	}
 
-	err = h.db.AdminDeleteUser(userID)
-	if err == sql.ErrNoRows {
-		http.Error(w, "User not found", http.StatusNotFound)
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.delete",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Before:     auditValue(map[string]string{"username": target.Username, "role": target.Role}),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetDeletedUsers lists soft-deleted accounts awaiting restore or purge
+func (h *AdminHandler) GetDeletedUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.db.GetDeletedUsers()
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get deleted users")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+func (h *AdminHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.AdminRestoreUser(userID)
+	if err != nil {
+		writeDBError(w, err, "Deleted user not found", "Failed to restore user")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.restore",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// PurgeUser permanently deletes a soft-deleted account and all of its data
+func (h *AdminHandler) PurgeUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.AdminPurgeUser(userID)
+	if err != nil {
+		writeDBError(w, err, "Deleted user not found", "Failed to purge user")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.purge",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// RestoreLink undoes a link deletion by its owner or an admin
+func (h *AdminHandler) RestoreLink(w http.ResponseWriter, r *http.Request) {
+	linkID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.AdminRestoreLink(linkID)
+	if err != nil {
+		writeDBError(w, err, "Deleted link not found", "Failed to restore link")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "link.restore",
+		TargetType: "link",
+		TargetID:   strconv.Itoa(linkID),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AdminHandler) GetLockouts(w http.ResponseWriter, r *http.Request) {
+	lockouts, err := h.db.GetLoginLockouts()
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get lockouts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lockouts)
+}
+
+func (h *AdminHandler) GetFailedLogins(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	attempts, err := h.db.GetFailedLogins(r.URL.Query().Get("username"), limit)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get failed logins")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attempts)
+}
+
+func (h *AdminHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid user ID", http.StatusBadRequest)
 		return
 	}
+
+	target, err := h.db.GetUserByID(userID)
 	if err != nil {
-		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+		writeDBError(w, err, "User not found", "Failed to unlock user")
 		return
 	}
 
+	if err := h.db.ClearLoginFailures(target.Username); err != nil {
+		writeDBError(w, err, "", "Failed to unlock user")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.unlock",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+	})
+
 	w.WriteHeader(http.StatusOK)
-}
\ No newline at end of file
+}
+
+const (
+	auditPageSize    = 50
+	auditMaxPageSize = 500
+	auditExportLimit = 10000
+)
+
+// GetAuditLog lists audit entries filtered by actor, action (exact, or a prefix ending
+// in "*"), target_type, target_id and a since/until time range. format=csv exports
+// every match (up to auditExportLimit) instead of one page.
+func (h *AdminHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.AuditFilter{
+		Actor:      q.Get("actor"),
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+		TargetID:   q.Get("target_id"),
+		Since:      q.Get("since"),
+		Until:      q.Get("until"),
+	}
+
+	csvExport := q.Get("format") == "csv"
+	if csvExport {
+		filter.Limit = auditExportLimit
+	} else {
+		filter.Limit, _ = strconv.Atoi(q.Get("limit"))
+		if filter.Limit <= 0 || filter.Limit > auditMaxPageSize {
+			filter.Limit = auditPageSize
+		}
+		filter.Offset, _ = strconv.Atoi(q.Get("offset"))
+		if filter.Offset < 0 {
+			filter.Offset = 0
+		}
+	}
+
+	entries, total, err := h.db.GetAuditLog(filter)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get audit log")
+		return
+	}
+
+	if csvExport {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit-log.csv"`)
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"id", "created_at", "actor_id", "actor_username", "action", "target_type", "target_id", "before", "after", "ip", "user_agent"})
+		for _, e := range entries {
+			writer.Write([]string{strconv.Itoa(e.ID), e.CreatedAt, strconv.Itoa(e.ActorID), csvCell(e.ActorUsername), e.Action,
+				e.TargetType, csvCell(e.TargetID), csvCell(e.Before), csvCell(e.After), csvCell(e.IP), csvCell(e.UserAgent)})
+		}
+		writer.Flush()
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AuditPage{Entries: entries, Total: total, Limit: filter.Limit, Offset: filter.Offset})
+}
+
+// csvCell neutralizes values a spreadsheet would evaluate as a formula. Failed login
+// usernames and user agents are attacker-controlled.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"links/internal/auth"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+// maxAPITokens caps how many personal API tokens one account may hold
+const maxAPITokens = 20
+
+func (h *AccountHandler) GetAPITokens(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	tokens, err := h.db.GetAPITokens(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get API tokens")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// CreateAPIToken issues a personal API token for scripts, browser extensions and the
+// bookmarklet. The token is shown once; only its hash is stored.
+func (h *AccountHandler) CreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	name := middleware.Sanitizer.SanitizeText(req.Name)
+	if name == "" || len(name) > 100 {
+		writeError(w, "Name is required and must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.db.CountAPITokens(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to create API token")
+		return
+	}
+	if count >= maxAPITokens {
+		writeError(w, "Too many API tokens; revoke one first", http.StatusConflict)
+		return
+	}
+
+	secret, hash := auth.GenerateAPIToken()
+	token, err := h.db.CreateAPIToken(user.ID, name, hash)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to create API token")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "api_token.create",
+		TargetType: "api_token",
+		TargetID:   strconv.Itoa(token.ID),
+		After:      auditValue(map[string]string{"name": name}),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.CreateAPITokenResponse{APIToken: *token, Token: secret})
+}
+
+func (h *AccountHandler) DeleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid token ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.DeleteAPIToken(id, user.ID)
+	if err != nil {
+		writeDBError(w, err, "API token not found", "Failed to revoke API token")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "api_token.revoke",
+		TargetType: "api_token",
+		TargetID:   strconv.Itoa(id),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+// auditWriter is the subset of the database needed to append to the audit log
+type auditWriter interface {
+	WriteAudit(entry models.AuditEntry) error
+}
+
+// recordAudit fills in the request's IP, user agent and (when not already set) the
+// authenticated actor, then appends the entry. Actions taken while impersonating are
+// attributed to the admin. Failures are logged, not surfaced.
+func recordAudit(store auditWriter, r *http.Request, entry models.AuditEntry) {
+	if entry.ActorID == 0 && entry.ActorUsername == "" {
+		if user := middleware.GetUserFromContext(r.Context()); user != nil && user.ImpersonatorID != 0 {
+			entry.ActorID = user.ImpersonatorID
+			entry.ActorUsername = user.ImpersonatorUsername
+		} else if user != nil {
+			entry.ActorID = user.ID
+			entry.ActorUsername = user.Username
+		}
+	}
+	entry.IP = middleware.ClientIP(r)
+	entry.UserAgent = r.UserAgent()
+
+	if err := store.WriteAudit(entry); err != nil {
+		log.Printf("Failed to record audit entry %q: %v", entry.Action, err)
+	}
+}
+
+// auditValue encodes a before/after snapshot for the audit log
+func auditValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
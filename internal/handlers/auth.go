@@ -2,60 +2,108 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"net/mail"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"links/internal/auth"
+	"links/internal/db"
+	"links/internal/mailer"
+	"links/internal/middleware"
 	"links/internal/models"
 )
 
 type AuthHandler struct {
-	db DatabaseInterface
+	db     DatabaseInterface
+	mailer mailer.Mailer
 }
 
 type DatabaseInterface interface {
 	CreateUser(username, hashedPassword, createdAt string) (int64, error)
 	GetUserByUsername(username string) (*models.User, string, error)
+	GetUserByID(userID int) (*models.User, error)
+	GetTOTP(userID int) (string, bool, int64, error)
+	UpdateTOTPLastStep(userID int, step int64) error
+	UseRecoveryCode(userID int, codeHash string) error
+	RequireAdmin2FA() (bool, error)
+	GetLockedUntil(username string) (int64, error)
+	RecordFailedLogin(username, ip, userAgent, reason string, window time.Duration) (int, error)
+	SetLockedUntil(username string, lockedUntil int64) error
+	ClearLoginFailures(username string) error
+	UpdateEmail(userID int, email *string) error
+	CreateUserToken(userID int, tokenHash, purpose, email string, ttl time.Duration) error
+	WriteAudit(entry models.AuditEntry) error
 }
 
-func NewAuthHandler(db DatabaseInterface) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db DatabaseInterface, m mailer.Mailer) *AuthHandler {
+	return &AuthHandler{db: db, mailer: m}
 }
 
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req models.AuthRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeError(w, "Invalid request format", http.StatusBadRequest)
 		return
 	}
 
 	// Validate and sanitize inputs
 	if !h.validateAuthRequest(&req) {
-		http.Error(w, "Invalid username or password format", http.StatusBadRequest)
+		writeError(w, "Invalid username or password format", http.StatusBadRequest)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email != "" && !validEmail(req.Email) {
+		writeError(w, "Invalid email address", http.StatusBadRequest)
 		return
 	}
 
 	hashedPassword, err := auth.HashPassword(req.Password)
 	if err != nil {
-		http.Error(w, "Error hashing password", http.StatusInternalServerError)
+		writeError(w, "Error hashing password", http.StatusInternalServerError)
 		return
 	}
 
 	createdAt := time.Now().Format("2006-01-02 15:04:05")
 	userID, err := h.db.CreateUser(req.Username, hashedPassword, createdAt)
+	if errors.Is(err, db.ErrConflict) {
+		writeError(w, "Username already exists", http.StatusConflict)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Username already exists", http.StatusConflict)
+		writeDBError(w, err, "", "Failed to create account")
 		return
 	}
 
-	token, _ := auth.GenerateJWT(int(userID), req.Username, false) // New users are not admin by default
+	// Email is optional and stays unverified until the emailed link is opened
+	if req.Email != "" {
+		if err := h.db.UpdateEmail(int(userID), &req.Email); err == nil {
+			if err := sendVerificationEmail(h.db, h.mailer, int(userID), req.Email); err != nil {
+				log.Printf("Failed to send verification email for user %d: %v", userID, err)
+			}
+		}
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		ActorID:       int(userID),
+		ActorUsername: req.Username,
+		Action:        "user.register",
+		TargetType:    "user",
+		TargetID:      strconv.FormatInt(userID, 10),
+	})
+
+	token, _ := auth.GenerateJWT(int(userID), req.Username, false, 0) // New users are not admin by default
 
 	user := models.User{
 		ID:        int(userID),
 		Username:  req.Username,
 		IsAdmin:   false,
+		Role:      auth.RoleUser,
 		CreatedAt: createdAt,
 	}
 
@@ -66,59 +114,243 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.AuthRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeError(w, "Invalid request format", http.StatusBadRequest)
 		return
 	}
 
 	// Validate inputs (less strict for login than register)
 	req.Username = strings.TrimSpace(req.Username)
 	if req.Username == "" || req.Password == "" {
-		http.Error(w, "Username and password required", http.StatusBadRequest)
+		writeError(w, "Username and password required", http.StatusBadRequest)
+		return
+	}
+
+	// No account has a longer username (Google accounts use the email address), so
+	// don't log or lock out names that cannot exist
+	if len(req.Username) > 254 {
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if h.rejectIfLocked(w, req.Username) {
 		return
 	}
 
 	user, hashedPassword, err := h.db.GetUserByUsername(req.Username)
 	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		h.recordFailure(r, req.Username, "unknown_user")
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if hashedPassword == "" {
+		h.recordFailure(r, req.Username, "no_password")
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
 	if err := auth.CheckPassword(hashedPassword, req.Password); err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		h.recordFailure(r, req.Username, "invalid_password")
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	// Only reveal the suspension once the password has been verified
+	if rejectIfSuspended(w, user) {
+		return
+	}
+
+	// Second factor required: hand back a short-lived challenge instead of a session
+	if user.HasSecondFactor() {
+		mfaToken, err := auth.GenerateMFAToken(user.ID, user.Username)
+		if err != nil {
+			writeError(w, "Failed to start two-factor login", http.StatusInternalServerError)
+			return
+		}
+
+		var methods []string
+		if user.TOTPEnabled {
+			methods = append(methods, "totp", "recovery_code")
+		}
+		if user.PasskeyEnabled {
+			methods = append(methods, "webauthn")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(models.TwoFactorChallenge{TwoFactorRequired: true, MFAToken: mfaToken, Methods: methods})
+		return
+	}
+
+	h.db.ClearLoginFailures(user.Username)
+	writeSession(w, r, h.db, user, "password")
+}
+
+// LoginTwoFactor completes a login started by Login using a TOTP or recovery code
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request format", http.StatusBadRequest)
 		return
 	}
 
-	token, _ := auth.GenerateJWT(user.ID, user.Username, user.IsAdmin)
+	claims, err := auth.ValidateMFAToken(req.MFAToken)
+	if err != nil {
+		writeError(w, "Invalid or expired two-factor session", http.StatusUnauthorized)
+		return
+	}
+
+	if h.rejectIfLocked(w, claims.Username) {
+		return
+	}
+
+	user, err := h.db.GetUserByID(claims.UserID)
+	if err != nil {
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if rejectIfSuspended(w, user) {
+		return
+	}
+
+	method := h.verifySecondFactor(user.ID, req.Code)
+	if method == "" {
+		h.recordFailure(r, user.Username, "invalid_second_factor")
+		writeError(w, "Invalid two-factor code", http.StatusUnauthorized)
+		return
+	}
+
+	h.db.ClearLoginFailures(user.Username)
+	writeSession(w, r, h.db, user, method)
+}
+
+// rejectIfLocked writes a 429 with Retry-After if the account is temporarily locked
+func (h *AuthHandler) rejectIfLocked(w http.ResponseWriter, username string) bool {
+	lockedUntil, err := h.db.GetLockedUntil(username)
+	if err != nil {
+		writeDBError(w, err, "", "Login temporarily unavailable")
+		return true
+	}
+
+	remaining := lockedUntil - time.Now().Unix()
+	if remaining <= 0 {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.FormatInt(remaining, 10))
+	writeError(w, "Too many failed login attempts. Please try again later.", http.StatusTooManyRequests)
+	return true
+}
+
+// recordFailure audits a failed attempt and extends the account's lockout
+func (h *AuthHandler) recordFailure(r *http.Request, username, reason string) {
+	failedCount, err := h.db.RecordFailedLogin(username, middleware.ClientIP(r), r.UserAgent(), reason, auth.LockoutWindow)
+	if err != nil {
+		log.Printf("Failed to record failed login for %q: %v", username, err)
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		ActorUsername: username,
+		Action:        "login.failure",
+		TargetType:    "user",
+		TargetID:      username,
+		After:         auditValue(map[string]interface{}{"reason": reason, "failed_count": failedCount}),
+	})
+
+	if duration := auth.LockoutDuration(failedCount); duration > 0 {
+		lockedUntil := time.Now().Add(duration)
+		h.db.SetLockedUntil(username, lockedUntil.Unix())
+		recordAudit(h.db, r, models.AuditEntry{
+			ActorUsername: username,
+			Action:        "login.lockout",
+			TargetType:    "user",
+			TargetID:      username,
+			After:         auditValue(map[string]string{"locked_until": lockedUntil.Format("2006-01-02 15:04:05")}),
+		})
+	}
+}
+
+// verifySecondFactor accepts a current TOTP code or an unused recovery code, returning
+// which one matched, or "" if neither did
+func (h *AuthHandler) verifySecondFactor(userID int, code string) string {
+	secret, enabled, lastStep, err := h.db.GetTOTP(userID)
+	if err != nil || !enabled {
+		return ""
+	}
+
+	if step, ok := auth.VerifyTOTP(secret, code, lastStep); ok {
+		if h.db.UpdateTOTPLastStep(userID, step) != nil {
+			return ""
+		}
+		return "totp"
+	}
+
+	if h.db.UseRecoveryCode(userID, auth.HashRecoveryCode(code)) != nil {
+		return ""
+	}
+	return "recovery_code"
+}
+
+// sessionIssuer is the subset of the database needed to finish a login
+type sessionIssuer interface {
+	RequireAdmin2FA() (bool, error)
+	WriteAudit(entry models.AuditEntry) error
+}
+
+// writeSession issues the session token once all required factors are verified and
+// records the login, noting the final factor used
+func writeSession(w http.ResponseWriter, r *http.Request, store sessionIssuer, user *models.User, method string) {
+	token, _ := auth.GenerateJWT(user.ID, user.Username, user.IsAdmin, user.SessionVersion)
+
+	recordAudit(store, r, models.AuditEntry{
+		ActorID:       user.ID,
+		ActorUsername: user.Username,
+		Action:        "login.success",
+		TargetType:    "user",
+		TargetID:      strconv.Itoa(user.ID),
+		After:         auditValue(map[string]string{"method": method}),
+	})
+
+	response := models.AuthResponse{Token: token, User: *user}
+	if auth.IsStaff(user.Role) && !user.HasSecondFactor() {
+		required, err := store.RequireAdmin2FA()
+		response.TwoFactorSetupRequired = err == nil && required
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(models.AuthResponse{Token: token, User: *user})
+	json.NewEncoder(w).Encode(response)
 }
 
 // validateAuthRequest validates and sanitizes authentication requests
 func (h *AuthHandler) validateAuthRequest(req *models.AuthRequest) bool {
-	// Username validation
 	req.Username = strings.TrimSpace(req.Username)
-	if len(req.Username) < 3 || len(req.Username) > 50 {
+	return validUsername(req.Username) && validPassword(req.Password)
+}
+
+var usernameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func validUsername(username string) bool {
+	if len(username) < 3 || len(username) > 50 {
 		return false
 	}
 
 	// Username should only contain alphanumeric characters, underscores, and hyphens
-	usernameRegex := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-	if !usernameRegex.MatchString(req.Username) {
-		return false
-	}
+	return usernameRegex.MatchString(username)
+}
 
-	// Password validation
-	if len(req.Password) < 6 || len(req.Password) > 128 {
-		return false
-	}
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email && len(email) <= 254
+}
 
-	// Password strength check - at least one letter, one number
-	hasLetter := regexp.MustCompile(`[a-zA-Z]`).MatchString(req.Password)
-	hasNumber := regexp.MustCompile(`[0-9]`).MatchString(req.Password)
-	if !hasLetter || !hasNumber {
+func validPassword(password string) bool {
+	if len(password) < 6 || len(password) > 128 {
 		return false
 	}
 
-	return true
+	// Password strength check - at least one letter, one number
+	hasLetter := regexp.MustCompile(`[a-zA-Z]`).MatchString(password)
+	hasNumber := regexp.MustCompile(`[0-9]`).MatchString(password)
+	return hasLetter && hasNumber
 }
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"links/internal/auth"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+// bulkMaxItems caps how many links one bulk request may change
+const bulkMaxItems = 1000
+
+// BulkLinkFilter selects links for an admin bulk action by the same criteria as
+// the admin link listing
+type BulkLinkFilter struct {
+	Query     string `json:"q"`
+	Username  string `json:"username"`
+	Domain    string `json:"domain"`
+	IsPrivate *bool  `json:"private"`
+	IsLocked  *bool  `json:"locked"`
+	Since     string `json:"since"`
+	Until     string `json:"until"`
+}
+
+func (f BulkLinkFilter) empty() bool {
+	return f.Query == "" && f.Username == "" && f.Domain == "" && f.IsPrivate == nil && f.IsLocked == nil &&
+		f.Since == "" && f.Until == ""
+}
+
+// AdminBulkLinksRequest applies Action to the links listed in IDs or, when IDs is
+// empty, to every link matching Filter
+type AdminBulkLinksRequest struct {
+	Action string          `json:"action"` // "delete", "lock", "unlock" or "force_private"
+	IDs    []int           `json:"ids"`
+	Filter *BulkLinkFilter `json:"filter"`
+}
+
+var bulkActionPermissions = map[string]auth.Permission{
+	"delete":        auth.PermLinksDelete,
+	"lock":          auth.PermLinksLock,
+	"unlock":        auth.PermLinksLock,
+	"force_private": auth.PermLinksForcePrivate,
+}
+
+// BulkLinks applies one moderation action to many links in a single transaction
+func (h *AdminHandler) BulkLinks(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req AdminBulkLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	perm, ok := bulkActionPermissions[req.Action]
+	if !ok {
+		writeError(w, "Action must be one of delete, lock, unlock or force_private", http.StatusBadRequest)
+		return
+	}
+	if !auth.HasPermission(user.Role, perm) {
+		writeError(w, "Insufficient permissions", http.StatusForbidden)
+		return
+	}
+
+	ids := req.IDs
+	switch {
+	case len(ids) > 0:
+		if len(ids) > bulkMaxItems {
+			writeError(w, "Too many links in one request", http.StatusBadRequest)
+			return
+		}
+	case req.Filter != nil && !req.Filter.empty():
+		f := req.Filter
+		matched, err := h.db.AdminLinkIDs(models.LinkFilter{
+			Query:     strings.TrimSpace(f.Query),
+			Username:  strings.TrimSpace(f.Username),
+			Domain:    strings.TrimSpace(f.Domain),
+			IsPrivate: f.IsPrivate,
+			IsLocked:  f.IsLocked,
+			Since:     f.Since,
+			Until:     f.Until,
+		}, bulkMaxItems)
+		if err != nil {
+			writeError(w, "Failed to find links", http.StatusInternalServerError)
+			return
+		}
+		if len(matched) > bulkMaxItems {
+			writeError(w, "Filter matches more than "+strconv.Itoa(bulkMaxItems)+" links; narrow it down", http.StatusBadRequest)
+			return
+		}
+		ids = matched
+	default:
+		writeError(w, "Provide link ids or a non-empty filter", http.StatusBadRequest)
+		return
+	}
+
+	// Note which links are public now, to tell everyone which ones force_private hides
+	wasPublic := map[int]bool{}
+	if req.Action == "force_private" {
+		links, err := h.db.GetLinksByIDs(ids)
+		if err != nil {
+			writeDBError(w, err, "", "Failed to get links")
+			return
+		}
+		for _, link := range links {
+			wasPublic[link.ID] = !link.IsPrivate
+		}
+	}
+
+	results, err := h.db.AdminBulkLinks(ids, req.Action, user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to apply bulk action")
+		return
+	}
+
+	// Audit each changed link under the same action as the single-link endpoint
+	var changed, hidden []int
+	for _, result := range results {
+		if !result.OK {
+			continue
+		}
+		changed = append(changed, result.ID)
+		if wasPublic[result.ID] {
+			hidden = append(hidden, result.ID)
+		}
+		entry := models.AuditEntry{
+			Action:     "link." + req.Action,
+			TargetType: "link",
+			TargetID:   strconv.Itoa(result.ID),
+			After:      auditValue(map[string]bool{"bulk": true}),
+		}
+		switch req.Action {
+		case "lock", "unlock":
+			entry.Action = "link.lock"
+			entry.After = auditValue(map[string]bool{"bulk": true, "is_locked": req.Action == "lock"})
+		case "force_private":
+			entry.After = auditValue(map[string]bool{"bulk": true, "is_private": true, "is_locked": true})
+		}
+		recordAudit(h.db, r, entry)
+	}
+	switch req.Action {
+	case "delete":
+		emitLinkEvent(h.db, models.EventLinkDeleted, changed...)
+	case "force_private":
+		emitLinkEvent(h.db, models.EventLinkUpdated, changed...)
+		emitLinkHidden(hidden...)
+	case "lock", "unlock":
+		emitLinkEvent(h.db, models.EventLinkUpdated, changed...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NewBulkResponse(results))
+}
+
+// BulkLinksRequest changes several of the user's own links at once
+type BulkLinksRequest struct {
+	Action    string `json:"action"` // "tag", "category", "privacy" or "delete"
+	IDs       []int  `json:"ids"`
+	Tags      string `json:"tags"`       // For "tag": added to each link's tags
+	Category  string `json:"category"`   // For "category": replaces the category
+	IsPrivate bool   `json:"is_private"` // For "privacy"
+}
+
+// BulkUpdate applies one change to many of the user's links in a single transaction
+func (h *LinksHandler) BulkUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+
+	var req BulkLinksRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > bulkMaxItems {
+		writeError(w, "Provide between 1 and "+strconv.Itoa(bulkMaxItems)+" link ids", http.StatusBadRequest)
+		return
+	}
+
+	update := models.BulkLinkUpdate{Action: req.Action, IsPrivate: req.IsPrivate}
+	switch req.Action {
+	case "tag":
+		update.Tags = middleware.Sanitizer.SanitizeTags(req.Tags)
+		if update.Tags == "" {
+			writeError(w, "Tags are required", http.StatusBadRequest)
+			return
+		}
+	case "category":
+		update.Category = middleware.Sanitizer.SanitizeCategory(req.Category)
+	case "privacy", "delete":
+	default:
+		writeError(w, "Action must be one of tag, category, privacy or delete", http.StatusBadRequest)
+		return
+	}
+
+	rules, err := h.db.GetDomainRules()
+	if err != nil {
+		writeDBError(w, err, "", "Failed to check domain policy")
+		return
+	}
+	allowPublic := func(rawURL string) bool {
+		parsed, err := url.Parse(rawURL)
+		return err == nil && models.DomainAction(rules, parsed.Hostname()) == models.DomainAllow
+	}
+
+	// Note which links are private now, to tell which ones a "privacy" update made
+	// public or private
+	wasPrivate := map[int]bool{}
+	if req.Action == "privacy" {
+		links, err := h.db.GetLinksByIDs(req.IDs)
+		if err != nil {
+			writeDBError(w, err, "", "Failed to get links")
+			return
+		}
+		for _, link := range links {
+			wasPrivate[link.ID] = link.IsPrivate
+		}
+	}
+
+	results, err := h.db.BulkUpdateLinks(userID, req.IDs, update, allowPublic)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to update links")
+		return
+	}
+
+	event := models.EventLinkUpdated
+	if req.Action == "delete" {
+		event = models.EventLinkDeleted
+	}
+	var changed, madePublic, madePrivate []int
+	for _, result := range results {
+		if !result.OK {
+			continue
+		}
+		changed = append(changed, result.ID)
+		if req.Action == "privacy" && wasPrivate[result.ID] != req.IsPrivate {
+			if req.IsPrivate {
+				madePrivate = append(madePrivate, result.ID)
+			} else {
+				madePublic = append(madePublic, result.ID)
+			}
+		}
+	}
+	emitLinkEvent(h.db, event, changed...)
+	emitLinkEvent(h.db, models.EventLinkMadePublic, madePublic...)
+	emitLinkHidden(madePrivate...)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.NewBulkResponse(results))
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+var errDomainBlocked = errors.New("links to this domain are not allowed")
+
+// applyDomainPolicy rejects links to blocked domains and forces links to
+// private-only domains private. Every path that saves a link must call it.
+func (h *LinksHandler) applyDomainPolicy(link *models.Link) error {
+	action, err := h.domainAction(link.URL)
+	if err != nil {
+		return err
+	}
+	switch action {
+	case models.DomainBlock:
+		return errDomainBlocked
+	case models.DomainPrivateOnly:
+		link.IsPrivate = true
+	}
+	return nil
+}
+
+func (h *LinksHandler) domainAction(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	rules, err := h.db.GetDomainRules()
+	if err != nil {
+		return "", err
+	}
+	return models.DomainAction(rules, parsed.Hostname()), nil
+}
+
+var domainPatternRegex = regexp.MustCompile(`^(\*\.)?[a-z0-9-]+(\.[a-z0-9-]+)*$`)
+
+// normalizeDomainPattern lowercases a pattern and strips a pasted scheme or path
+func normalizeDomainPattern(pattern string) (string, bool) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if i := strings.Index(pattern, "://"); i >= 0 {
+		pattern = pattern[i+3:]
+	}
+	if i := strings.IndexAny(pattern, "/?#"); i >= 0 {
+		pattern = pattern[:i]
+	}
+	pattern = strings.TrimSuffix(pattern, ".")
+	return pattern, len(pattern) <= 253 && domainPatternRegex.MatchString(pattern)
+}
+
+// DomainRuleRequest saves a domain rule. ApplyExisting also forces private any
+// existing public links the updated rules no longer allow to be shared.
+type DomainRuleRequest struct {
+	Pattern       string `json:"pattern"`
+	Action        string `json:"action"`
+	Note          string `json:"note"`
+	ApplyExisting bool   `json:"apply_existing"`
+}
+
+func (h *AdminHandler) GetDomainRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.db.GetDomainRules()
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get domain rules")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+func (h *AdminHandler) SaveDomainRule(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req DomainRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	pattern, ok := normalizeDomainPattern(req.Pattern)
+	if !ok {
+		writeError(w, "Pattern must be a domain such as example.com or *.example.com", http.StatusBadRequest)
+		return
+	}
+	switch req.Action {
+	case models.DomainBlock, models.DomainPrivateOnly, models.DomainAllow:
+	default:
+		writeError(w, "Action must be one of block, private_only or allow", http.StatusBadRequest)
+		return
+	}
+	note := middleware.Sanitizer.SanitizeText(req.Note)
+	if runes := []rune(note); len(runes) > 200 {
+		note = string(runes[:200])
+	}
+
+	id, err := h.db.SaveDomainRule(pattern, req.Action, note, user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to save domain rule")
+		return
+	}
+
+	var forced []int
+	if req.ApplyExisting {
+		forced, err = h.db.ForcePrivateDisallowedLinks()
+		if err != nil {
+			writeDBError(w, err, "", "Saved the rule but failed to update existing links")
+			return
+		}
+		emitLinkEvent(h.db, models.EventLinkUpdated, forced...)
+		emitLinkHidden(forced...)
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "domain.save",
+		TargetType: "domain_rule",
+		TargetID:   strconv.FormatInt(id, 10),
+		After: auditValue(map[string]interface{}{
+			"pattern": pattern, "action": req.Action, "note": note, "forced_private": len(forced),
+		}),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"id": id, "forced_private": int64(len(forced))})
+}
+
+func (h *AdminHandler) DeleteDomainRule(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.db.GetDomainRule(ruleID)
+	if err != nil {
+		writeDBError(w, err, "Domain rule not found", "Failed to get domain rule")
+		return
+	}
+
+	err = h.db.DeleteDomainRule(ruleID)
+	if err != nil {
+		writeDBError(w, err, "Domain rule not found", "Failed to delete domain rule")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "domain.delete",
+		TargetType: "domain_rule",
+		TargetID:   strconv.Itoa(ruleID),
+		Before:     auditValue(map[string]string{"pattern": rule.Pattern, "action": rule.Action, "note": rule.Note}),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
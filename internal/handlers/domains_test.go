@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"links/internal/events"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+// Applying a rule to existing links notifies clients and webhooks of each link it
+// forces private
+func TestSaveDomainRuleApplyExisting(t *testing.T) {
+	database := newTestDB(t)
+	admin, token := createTestUser(t, database, "admin")
+	if err := database.AdminSetUserRole(admin.ID, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	owner, _ := createTestUser(t, database, "owner")
+	if _, err := database.CreateWebhook(owner.ID, "https://hooks.example.com/", "secret", []string{models.EventLinkUpdated}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	newLink := func(url string, private bool) int {
+		id, err := database.CreateLink(owner.ID, url, nil, nil, nil, now, private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(id)
+	}
+	newLink("https://example.com/allowed", false)
+	newLink("https://example.net/already-private", true)
+	forced := newLink("https://example.net/shared", false)
+
+	broker := events.NewBroker(100, 64, 10)
+	SetEventBroker(broker)
+	defer SetEventBroker(nil)
+	public, _, _, err := broker.Subscribe(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer public.Close()
+	own, _, _, err := broker.Subscribe(owner.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer own.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/admin/domains", middleware.AuthMiddleware(NewAdminHandler(database).SaveDomainRule))
+	w := serve(t, mux, "POST", "/api/admin/domains", token, DomainRuleRequest{Pattern: "example.net", Action: models.DomainPrivateOnly, ApplyExisting: true})
+	if w.Code != http.StatusOK {
+		t.Fatalf("save = %d %s", w.Code, w.Body)
+	}
+	var response map[string]int64
+	decode(t, w, &response)
+	if response["forced_private"] != 1 {
+		t.Errorf("forced_private = %d, want 1", response["forced_private"])
+	}
+
+	id := strconv.Itoa(forced)
+	if got, want := publicEvents(t, public), []string{EventLinkHidden + " " + id}; !reflect.DeepEqual(got, want) {
+		t.Errorf("public events = %v, want %v", got, want)
+	}
+	if got, want := publicEvents(t, own), []string{EventLinkHidden + " " + id, models.EventLinkUpdated + " " + id}; !reflect.DeepEqual(got, want) {
+		t.Errorf("owner's events = %v, want %v", got, want)
+	}
+
+	deliveries, err := database.ClaimWebhookDeliveries(time.Now(), time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Event != models.EventLinkUpdated {
+		t.Errorf("queued deliveries = %+v, want one %s", deliveries, models.EventLinkUpdated)
+	}
+}
+
+// Long notes are cut at a character boundary
+func TestSaveDomainRuleTruncatesNote(t *testing.T) {
+	database := newTestDB(t)
+	admin, token := createTestUser(t, database, "admin")
+	if err := database.AdminSetUserRole(admin.ID, "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/admin/domains", middleware.AuthMiddleware(NewAdminHandler(database).SaveDomainRule))
+	w := serve(t, mux, "POST", "/api/admin/domains", token, DomainRuleRequest{Pattern: "example.net", Action: models.DomainBlock, Note: "a" + strings.Repeat("é", 250)})
+	if w.Code != http.StatusOK {
+		t.Fatalf("save = %d %s", w.Code, w.Body)
+	}
+	var response map[string]int64
+	decode(t, w, &response)
+
+	rule, err := database.GetDomainRule(int(response["id"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a" + strings.Repeat("é", 199); rule.Note != want {
+		t.Errorf("note = %q (%d bytes), want 200 characters", rule.Note, len(rule.Note))
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"links/internal/auth"
+	"links/internal/db"
+	"links/internal/mailer"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+const (
+	passwordResetTTL = time.Hour
+	emailVerifyTTL   = 24 * time.Hour
+)
+
+type EmailHandler struct {
+	db     EmailDBInterface
+	mailer mailer.Mailer
+}
+
+type EmailDBInterface interface {
+	GetUserByID(userID int) (*models.User, error)
+	GetProfile(userID int) (*models.Profile, error)
+	GetUserIDByVerifiedEmail(email string) (int, error)
+	CreateUserToken(userID int, tokenHash, purpose, email string, ttl time.Duration) error
+	ConsumeUserToken(tokenHash, purpose string) (int, string, error)
+	MarkEmailVerified(userID int, email string) error
+	UpdatePassword(userID int, hashedPassword string) (int, error)
+	ClearLoginFailures(username string) error
+	WriteAudit(entry models.AuditEntry) error
+}
+
+// verificationSender is the subset of the database needed to send a verification email
+type verificationSender interface {
+	CreateUserToken(userID int, tokenHash, purpose, email string, ttl time.Duration) error
+}
+
+func NewEmailHandler(db EmailDBInterface, m mailer.Mailer) *EmailHandler {
+	return &EmailHandler{db: db, mailer: m}
+}
+
+// appURL builds an absolute link into the app from APP_BASE_URL
+func appURL(path string, query url.Values) string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	link := strings.TrimRight(base, "/") + path
+	if len(query) > 0 {
+		link += "?" + query.Encode()
+	}
+	return link
+}
+
+// sendVerificationEmail issues a verification token for email and mails the link
+func sendVerificationEmail(store verificationSender, m mailer.Mailer, userID int, email string) error {
+	token, tokenHash := auth.GenerateOpaqueToken()
+	if err := store.CreateUserToken(userID, tokenHash, db.TokenPurposeEmailVerify, email, emailVerifyTTL); err != nil {
+		return err
+	}
+
+	link := appURL("/login", url.Values{"verify_email_token": {token}})
+	mailer.SendAsync(m, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email address",
+		Body:    "Confirm this address for your Links account by opening:\n\n" + link + "\n\nThe link expires in 24 hours. If you did not add this address, ignore this email.",
+	})
+	return nil
+}
+
+// ForgotPassword mails a reset link to a verified address. The response is the same
+// whether or not the address exists.
+func (h *EmailHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		writeError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if userID, err := h.db.GetUserIDByVerifiedEmail(email); err == nil {
+		token, tokenHash := auth.GenerateOpaqueToken()
+		if err := h.db.CreateUserToken(userID, tokenHash, db.TokenPurposePasswordReset, email, passwordResetTTL); err == nil {
+			recordAudit(h.db, r, models.AuditEntry{
+				Action:     "token.password_reset",
+				TargetType: "user",
+				TargetID:   strconv.Itoa(userID),
+			})
+			link := appURL("/login", url.Values{"reset_token": {token}})
+			mailer.SendAsync(h.mailer, mailer.Message{
+				To:      email,
+				Subject: "Reset your password",
+				Body:    "A password reset was requested for your Links account. Choose a new password here:\n\n" + link + "\n\nThe link expires in 1 hour. If you did not request this, ignore this email.",
+			})
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword sets a new password using a reset token and revokes all sessions
+func (h *EmailHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if !validPassword(req.NewPassword) {
+		writeError(w, "Invalid password format", http.StatusBadRequest)
+		return
+	}
+
+	userID, _, err := h.db.ConsumeUserToken(auth.HashToken(req.Token), db.TokenPurposePasswordReset)
+	if err != nil {
+		writeError(w, "Invalid or expired reset link", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		writeError(w, "Error hashing password", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := h.db.UpdatePassword(userID, hashedPassword); err != nil {
+		writeError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	// Whoever holds the mailbox may now log in even if the account was locked
+	if user, err := h.db.GetUserByID(userID); err == nil {
+		h.db.ClearLoginFailures(user.Username)
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.password_reset",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *EmailHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	userID, email, err := h.db.ConsumeUserToken(auth.HashToken(req.Token), db.TokenPurposeEmailVerify)
+	if err != nil {
+		writeError(w, "Invalid or expired verification link", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.MarkEmailVerified(userID, email); err != nil {
+		writeError(w, "Email address has changed since this link was sent", http.StatusConflict)
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.email_verified",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		After:      auditValue(map[string]string{"email": email}),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendVerification sends a new verification link for the signed-in user's email
+func (h *EmailHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	profile, err := h.db.GetProfile(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get profile")
+		return
+	}
+	if profile.Email == nil || *profile.Email == "" {
+		writeError(w, "No email address on this account", http.StatusBadRequest)
+		return
+	}
+	if profile.EmailVerified {
+		writeError(w, "Email address is already verified", http.StatusConflict)
+		return
+	}
+
+	if err := sendVerificationEmail(h.db, h.mailer, user.ID, *profile.Email); err != nil {
+		writeError(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"links/internal/db"
+	"links/internal/middleware"
+)
+
+// writeError sends the JSON error envelope; it takes the same arguments as http.Error
+func writeError(w http.ResponseWriter, message string, status int) {
+	middleware.WriteError(w, message, status)
+}
+
+// errorStatus maps the database's sentinel errors to HTTP statuses
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, db.ErrLocked):
+		return http.StatusForbidden
+	case errors.Is(err, db.ErrConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeDBError reports a failed database call. notFound is the message for
+// ErrNotFound, or "" for calls that never return it. failure is the message for
+// unexpected errors, which are logged with the request ID rather than shown to the
+// client.
+func writeDBError(w http.ResponseWriter, err error, notFound, failure string) {
+	switch status := errorStatus(err); {
+	case status == http.StatusNotFound && notFound != "":
+		writeError(w, notFound, status)
+	case status == http.StatusForbidden:
+		writeError(w, "Locked by an administrator", status)
+	case status == http.StatusConflict:
+		writeError(w, "Conflicts with an existing record", status)
+	default:
+		log.Printf("[%s] %s: %v", w.Header().Get(middleware.RequestIDHeader), failure, err)
+		writeError(w, failure, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"links/internal/db"
+	"links/internal/events"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+const (
+	// EventLinkHidden tells everyone watching public links that a public link turned
+	// private. It is only sent on the event stream, not to webhooks.
+	EventLinkHidden = "link.hidden"
+
+	// eventHeartbeat keeps idle streams from being closed by proxies
+	eventHeartbeat = 25 * time.Second
+
+	// maxStreamAge ends streams periodically so a reconnecting client is
+	// authenticated again; nothing is missed thanks to Last-Event-ID
+	maxStreamAge = 30 * time.Minute
+)
+
+var broker *events.Broker
+
+// SetEventBroker enables pushing link events to connected clients
+func SetEventBroker(b *events.Broker) {
+	broker = b
+}
+
+// publishLinkEvent sends event to the link's owner and, for public links, to
+// everyone
+func publishLinkEvent(event string, link *models.Link) {
+	if broker == nil {
+		return
+	}
+	data, err := json.Marshal(link)
+	if err != nil {
+		log.Printf("Failed to encode link %d for event %q: %v", link.ID, event, err)
+		return
+	}
+	broker.Publish(event, link.UserID, !link.IsPrivate, data)
+}
+
+// emitLinkHidden tells everyone that these formerly public links are now private, so
+// public lists can drop them
+func emitLinkHidden(ids ...int) {
+	if broker == nil {
+		return
+	}
+	for _, id := range ids {
+		broker.Publish(EventLinkHidden, 0, true, []byte(`{"id":`+strconv.Itoa(id)+`}`))
+	}
+}
+
+// emitOwnerLinksHidden tells everyone that a suspended account's public links are no
+// longer shown
+func emitOwnerLinksHidden(database *db.Database, userID int) {
+	links, err := database.GetLinksByUserID(userID)
+	if err != nil {
+		log.Printf("Failed to load links of suspended user %d: %v", userID, err)
+		return
+	}
+	var ids []int
+	for _, link := range links {
+		if !link.IsPrivate {
+			ids = append(ids, link.ID)
+		}
+	}
+	emitLinkHidden(ids...)
+}
+
+type EventsHandler struct{}
+
+func NewEventsHandler() *EventsHandler {
+	return &EventsHandler{}
+}
+
+// Stream serves Server-Sent Events: changes to the signed-in user's links and to
+// public links, or only the latter for anonymous clients. A client reconnecting with
+// a Last-Event-ID header (or lastEventId parameter) is first sent what it missed; if
+// that is no longer known it gets a "reset" event and should reload.
+func (h *EventsHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok || broker == nil {
+		writeError(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	userID := 0
+	if user := middleware.GetUserFromContext(r.Context()); user != nil {
+		userID = user.ID
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("lastEventId")
+	}
+	since, _ := strconv.ParseUint(lastID, 10, 64)
+
+	sub, backlog, complete, err := broker.Subscribe(userID, since)
+	if err != nil {
+		w.Header().Set("Retry-After", "30")
+		writeError(w, "Too many open event streams; try again later", http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range backlog {
+		writeEvent(w, event)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	expired := time.After(maxStreamAge)
+
+	for {
+		select {
+		case event, open := <-sub.C:
+			if !open {
+				// Dropped for falling behind; the client resumes from its last ID
+				return
+			}
+			writeEvent(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case <-expired:
+			return
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event events.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"links/internal/auth"
+	"links/internal/feeds"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+const (
+	// feedSize is the number of entries in every feed
+	feedSize = 50
+	// feedTitleLength caps entry titles taken from link descriptions
+	feedTitleLength = 120
+)
+
+type FeedsHandler struct {
+	db FeedsDBInterface
+}
+
+type FeedsDBInterface interface {
+	ListPublicLinks(limit, offset int) ([]models.Link, int, error)
+	UserFeedLinks(username string, limit int) ([]models.Link, error)
+	TagFeedLinks(tag string, limit int) ([]models.Link, error)
+	UnreadFeedLinks(userID, limit int) ([]models.Link, error)
+	SetFeedToken(userID int, tokenHash *string) error
+	GetUserByFeedToken(tokenHash string) (*models.User, error)
+	WriteAudit(entry models.AuditEntry) error
+}
+
+func NewFeedsHandler(db FeedsDBInterface) *FeedsHandler {
+	return &FeedsHandler{db: db}
+}
+
+// FeedTokenResponse carries the private feed URLs; the token inside them is shown once
+type FeedTokenResponse struct {
+	AtomURL string `json:"atom_url"`
+	RSSURL  string `json:"rss_url"`
+}
+
+// Public serves /feeds/public.atom and /feeds/public.rss
+func (h *FeedsHandler) Public(w http.ResponseWriter, r *http.Request) {
+	_, format, ok := splitFeedName(strings.TrimPrefix(r.URL.Path, "/feeds/"))
+	if !ok {
+		writeError(w, "Feed not found", http.StatusNotFound)
+		return
+	}
+
+	links, _, err := h.db.ListPublicLinks(feedSize, 0)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to build feed")
+		return
+	}
+
+	serveFeed(w, r, feeds.Feed{
+		Title:       "Public links",
+		Description: "The newest public links",
+		ID:          appURL("/feeds/public", nil),
+		SelfURL:     appURL("/feeds/public."+format, nil),
+		PageURL:     appURL("/", nil),
+	}, links, format, false)
+}
+
+// User serves /feeds/users/{username}.atom and .rss with a user's public links
+func (h *FeedsHandler) User(w http.ResponseWriter, r *http.Request) {
+	username, format, ok := splitFeedName(r.PathValue("file"))
+	if !ok || !validUsername(username) {
+		writeError(w, "Feed not found", http.StatusNotFound)
+		return
+	}
+
+	links, err := h.db.UserFeedLinks(username, feedSize)
+	if err != nil {
+		writeDBError(w, err, "Feed not found", "Failed to build feed")
+		return
+	}
+
+	serveFeed(w, r, feeds.Feed{
+		Title:       "Links by " + username,
+		Description: "The newest public links saved by " + username,
+		ID:          appURL("/feeds/users/"+username, nil),
+		SelfURL:     appURL("/feeds/users/"+username+"."+format, nil),
+		PageURL:     appURL("/", nil),
+	}, links, format, false)
+}
+
+// Tag serves /feeds/tags/{tag}.atom and .rss with the public links carrying a tag
+func (h *FeedsHandler) Tag(w http.ResponseWriter, r *http.Request) {
+	name, format, ok := splitFeedName(r.PathValue("file"))
+	tag := middleware.Sanitizer.SanitizeTags(name)
+	if !ok || tag == "" || tag != strings.ToLower(name) {
+		writeError(w, "Feed not found", http.StatusNotFound)
+		return
+	}
+
+	links, err := h.db.TagFeedLinks(tag, feedSize)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to build feed")
+		return
+	}
+
+	serveFeed(w, r, feeds.Feed{
+		Title:       "Links tagged " + tag,
+		Description: "The newest public links tagged " + tag,
+		ID:          appURL("/feeds/tags/"+tag, nil),
+		SelfURL:     appURL("/feeds/tags/"+tag+"."+format, nil),
+		PageURL:     appURL("/", nil),
+	}, links, format, false)
+}
+
+// Unread serves /feeds/private/{token}.atom and .rss: the token owner's links that
+// have never been opened, private ones included. Feed readers cannot log in, so the
+// secret token in the URL stands in for a session.
+func (h *FeedsHandler) Unread(w http.ResponseWriter, r *http.Request) {
+	token, format, ok := splitFeedName(r.PathValue("file"))
+	if !ok || token == "" {
+		writeError(w, "Feed not found", http.StatusNotFound)
+		return
+	}
+
+	user, err := h.db.GetUserByFeedToken(auth.HashToken(token))
+	if err != nil {
+		writeDBError(w, err, "Feed not found", "Failed to build feed")
+		return
+	}
+	if rejectIfSuspended(w, user) {
+		return
+	}
+
+	links, err := h.db.UnreadFeedLinks(user.ID, feedSize)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to build feed")
+		return
+	}
+
+	serveFeed(w, r, feeds.Feed{
+		Title:       "Unread links for " + user.Username,
+		Description: "Links saved by " + user.Username + " that have not been opened yet",
+		ID:          appURL("/feeds/private/"+strconv.Itoa(user.ID), nil),
+		SelfURL:     appURL("/feeds/private/"+token+"."+format, nil),
+		PageURL:     appURL("/", nil),
+	}, links, format, true)
+}
+
+// CreateFeedToken issues a new private feed token, invalidating the previous one
+func (h *FeedsHandler) CreateFeedToken(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	token, hash := auth.GenerateOpaqueToken()
+	if err := h.db.SetFeedToken(user.ID, &hash); err != nil {
+		writeDBError(w, err, "", "Failed to create feed token")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "feed_token.create",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(FeedTokenResponse{
+		AtomURL: appURL("/feeds/private/"+token+".atom", nil),
+		RSSURL:  appURL("/feeds/private/"+token+".rss", nil),
+	})
+}
+
+// DeleteFeedToken disables the private feed
+func (h *FeedsHandler) DeleteFeedToken(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	if err := h.db.SetFeedToken(user.ID, nil); err != nil {
+		writeDBError(w, err, "", "Failed to revoke feed token")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "feed_token.revoke",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// splitFeedName splits "name.atom" or "name.rss" into the name and the format
+func splitFeedName(file string) (string, string, bool) {
+	for _, format := range []string{"atom", "rss"} {
+		if name, ok := strings.CutSuffix(file, "."+format); ok {
+			return name, format, true
+		}
+	}
+	return "", "", false
+}
+
+// serveFeed renders links in format and writes them with an ETag and Last-Modified
+// header, answering conditional requests with 304 Not Modified
+func serveFeed(w http.ResponseWriter, r *http.Request, feed feeds.Feed, links []models.Link, format string, private bool) {
+	// The newest entry dates the feed; an empty feed has no Last-Modified
+	var modified time.Time
+	for _, link := range links {
+		entry := feedEntry(link)
+		if entry.Published.After(modified) {
+			modified = entry.Published
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	feed.Updated = modified
+	if modified.IsZero() {
+		feed.Updated = time.Now()
+	}
+
+	render, contentType := feeds.Atom, "application/atom+xml; charset=utf-8"
+	if format == "rss" {
+		render, contentType = feeds.RSS, "application/rss+xml; charset=utf-8"
+	}
+	body, err := render(feed)
+	if err != nil {
+		writeError(w, "Failed to build feed", http.StatusInternalServerError)
+		return
+	}
+
+	// An empty feed carries the render time, so its ETag ignores the body
+	sum := sha256.Sum256(body)
+	if modified.IsZero() {
+		sum = sha256.Sum256([]byte(feed.ID + format))
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	if private {
+		// Entries leave the unread feed when opened, which the newest entry's date
+		// does not reflect, so only the ETag validates it
+		modified = time.Time{}
+		w.Header().Set("Cache-Control", "private, no-cache")
+		w.Header().Set("X-Robots-Tag", "noindex")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	}
+	http.ServeContent(w, r, "", modified, bytes.NewReader(body))
+}
+
+// feedEntry maps a link onto a feed entry. Descriptions are stored HTML-escaped, so
+// they are unescaped here and escaped once more by the XML encoder.
+func feedEntry(link models.Link) feeds.Entry {
+	entry := feeds.Entry{
+		ID:        appURL("/#link-"+strconv.Itoa(link.ID), nil),
+		Title:     link.URL,
+		URL:       link.URL,
+		Author:    link.Username,
+		Published: parseLinkTime(link.CreatedAt),
+	}
+
+	if link.Description != nil && *link.Description != "" {
+		description := html.UnescapeString(*link.Description)
+		entry.Title = description
+		if utf8.RuneCountInString(description) > feedTitleLength {
+			entry.Title = string([]rune(description)[:feedTitleLength-1]) + "…"
+			entry.Summary = description
+		}
+	}
+
+	if link.Tags != nil {
+		for _, tag := range strings.Split(*link.Tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				entry.Categories = append(entry.Categories, tag)
+			}
+		}
+	}
+	return entry
+}
+
+// parseLinkTime reads created_at, which SQLite stores in local time. Imported links
+// may carry an RFC 3339 timestamp instead.
+func parseLinkTime(value string) time.Time {
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err == nil {
+		return t
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
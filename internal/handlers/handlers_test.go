@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"links/internal/auth"
+	"links/internal/db"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+// newTestDB opens a fresh database in a temporary directory and makes it the session
+// store, as main does
+func newTestDB(t *testing.T) *db.Database {
+	t.Helper()
+	database, err := db.New(filepath.Join(t.TempDir(), "links.db"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() {
+		middleware.SetSessionStore(nil)
+		database.Close()
+	})
+	middleware.SetSessionStore(database)
+	return database
+}
+
+// createTestUser registers a user with a password and returns it with a session token
+func createTestUser(t *testing.T, database *db.Database, username string) (*models.User, string) {
+	t.Helper()
+	hash, err := auth.HashPassword("correct horse battery")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := database.CreateUser(username, hash, time.Now().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		t.Fatalf("creating user: %v", err)
+	}
+	user, err := database.GetUserByID(int(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := auth.GenerateJWT(user.ID, user.Username, user.IsAdmin, user.SessionVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, token
+}
+
+// serve sends a request with an optional JSON body and bearer token to handler
+func serve(t *testing.T, handler http.Handler, method, target, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// decode unmarshals a JSON response body into v
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+}
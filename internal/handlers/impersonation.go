@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"links/internal/auth"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+// ImpersonateRequest starts a "view as user" session. Sessions are read-only
+// unless AllowWrites is set.
+type ImpersonateRequest struct {
+	Reason      string `json:"reason"`
+	AllowWrites bool   `json:"allow_writes"`
+}
+
+// Impersonate issues a short-lived token that acts as the target user. Staff,
+// suspended and deleted accounts cannot be impersonated.
+func (h *AdminHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	userID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if userID == user.ID {
+		writeError(w, "Cannot impersonate own account", http.StatusBadRequest)
+		return
+	}
+
+	var req ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > 500 {
+		writeError(w, "A reason of at most 500 characters is required", http.StatusBadRequest)
+		return
+	}
+
+	target, err := h.db.GetUserByID(userID)
+	if err != nil {
+		writeDBError(w, err, "User not found", "Failed to get user")
+		return
+	}
+	if target.IsAdmin || auth.IsStaff(target.Role) {
+		writeError(w, "Staff accounts cannot be impersonated", http.StatusForbidden)
+		return
+	}
+	if target.IsSuspended() {
+		writeError(w, "Suspended accounts cannot be impersonated", http.StatusForbidden)
+		return
+	}
+
+	readOnly := !req.AllowWrites
+	token, sessionID, err := auth.GenerateImpersonationToken(target.ID, target.Username, target.SessionVersion, user.ID, readOnly)
+	if err != nil {
+		writeError(w, "Failed to start impersonation", http.StatusInternalServerError)
+		return
+	}
+	expiresAt := time.Now().Add(auth.ImpersonationTTL).Format("2006-01-02 15:04:05")
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "impersonation.start",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(target.ID),
+		After: auditValue(map[string]interface{}{
+			"session": sessionID, "reason": req.Reason, "read_only": readOnly, "expires_at": expiresAt,
+		}),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ImpersonationResponse{
+		Token:     token,
+		User:      *target,
+		SessionID: sessionID,
+		ReadOnly:  readOnly,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// EndImpersonation records that the admin left an impersonation session. The token
+// itself stays valid until it expires, so the client must also discard it.
+func (h *AdminHandler) EndImpersonation(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+	if user.ImpersonatorID == 0 {
+		writeError(w, "Not an impersonation session", http.StatusBadRequest)
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "impersonation.end",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+		After:      auditValue(map[string]string{"session": user.ImpersonationID}),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -1,13 +1,13 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
+	"links/internal/db"
 	"links/internal/middleware"
 	"links/internal/models"
 )
@@ -20,55 +20,97 @@ type LinksDBInterface interface {
 	CreateLink(userID int, url string, description, tags, category *string, createdAt string, isPrivate bool) (int64, error)
 	GetLinksByUserID(userID int) ([]models.Link, error)
 	GetPublicLinks() ([]models.Link, error)
+	GetLink(linkID, userID int) (*models.Link, error)
+	ListLinks(userID, limit, offset int) ([]models.Link, int, error)
+	ListPublicLinks(limit, offset int) ([]models.Link, int, error)
 	ToggleFavorite(linkID, userID int, isFavorite bool) error
 	TogglePrivacy(linkID, userID int, isPrivate bool) error
 	DeleteLink(linkID, userID int) error
 	IncrementAccessCount(linkID int) error
+	GetTrashedLinks(userID int) ([]models.Link, error)
+	RestoreLink(linkID, userID int) error
+	PurgeLink(linkID, userID int) error
+	EmptyTrash(userID int) (int64, error)
+	CreateReport(linkID, reporterID int, reason, details string) (int64, error)
+	GetDomainRules() ([]models.DomainRule, error)
+	BulkUpdateLinks(userID int, linkIDs []int, update models.BulkLinkUpdate, allowPublic func(url string) bool) ([]models.BulkResult, error)
+	GetLinksByIDs(ids []int) ([]models.Link, error)
+	EnqueueWebhookEvent(event string, ownerID int, public bool, payload string) (int64, error)
 }
 
 func NewLinksHandler(db LinksDBInterface) *LinksHandler {
 	return &LinksHandler{db: db}
 }
 
+// pathID parses the numeric {id} wildcard of the route that matched r
+func pathID(r *http.Request) (int, error) {
+	return strconv.Atoi(r.PathValue("id"))
+}
+
 func (h *LinksHandler) CreateLink(w http.ResponseWriter, r *http.Request) {
 	var link models.Link
 
 	err := json.NewDecoder(r.Body).Decode(&link)
 	if err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeError(w, "Invalid request format", http.StatusBadRequest)
 		return
 	}
 
-	// Validate and sanitize inputs
-	if !h.validateAndSanitizeLink(&link) {
-		http.Error(w, "Invalid link data", http.StatusBadRequest)
+	// A client timestamp is kept if provided
+	link.UserID, _ = strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err := h.saveLink(&link); err != nil {
+		writeSaveLinkError(w, err)
 		return
 	}
 
-	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
-	link.UserID = userID
-	
-	// Use client timestamp if provided, otherwise use server timestamp
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(link)
+}
+
+// errInvalidLink is returned by saveLink for links that fail validation
+var errInvalidLink = errors.New("invalid link data")
+
+// saveLink validates and sanitizes a new link of link.UserID, applies the domain
+// policy, creates it and tells clients and webhooks. CreatedAt defaults to now.
+// Every path that saves a new link goes through here; links that must not be saved
+// return errInvalidLink or errDomainBlocked.
+func (h *LinksHandler) saveLink(link *models.Link) error {
+	if !h.validateAndSanitizeLink(link) {
+		return errInvalidLink
+	}
+	if err := h.applyDomainPolicy(link); err != nil {
+		return err
+	}
+
 	if link.CreatedAt == "" {
 		link.CreatedAt = time.Now().Format("2006-01-02 15:04:05")
 	}
-
 	id, err := h.db.CreateLink(link.UserID, link.URL, link.Description, link.Tags, link.Category, link.CreatedAt, link.IsPrivate)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		return err
 	}
 
 	link.ID = int(id)
+	emitLinkEvent(h.db, models.EventLinkCreated, link.ID)
+	return nil
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(link)
+// writeSaveLinkError reports why saveLink refused or failed to save a link
+func writeSaveLinkError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errInvalidLink):
+		writeError(w, "Invalid link data", http.StatusBadRequest)
+	case errors.Is(err, errDomainBlocked):
+		writeError(w, "Links to this domain are not allowed", http.StatusForbidden)
+	default:
+		writeDBError(w, err, "", "Failed to add link")
+	}
 }
 
 func (h *LinksHandler) GetPublicLinks(w http.ResponseWriter, r *http.Request) {
 	links, err := h.db.GetPublicLinks()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, err, "", "Failed to load public links")
 		return
 	}
 
@@ -87,7 +129,7 @@ func (h *LinksHandler) GetLinks(w http.ResponseWriter, r *http.Request) {
 	
 	links, err := h.db.GetLinksByUserID(userID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, err, "", "Failed to load links")
 		return
 	}
 
@@ -104,17 +146,9 @@ func (h *LinksHandler) GetLinks(w http.ResponseWriter, r *http.Request) {
 func (h *LinksHandler) ToggleFavorite(w http.ResponseWriter, r *http.Request) {
 	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
 	
-	// Extract link ID from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
-		return
-	}
-	
-	linkID, err := strconv.Atoi(parts[3])
+	linkID, err := pathID(r)
 	if err != nil {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
 		return
 	}
 	
@@ -124,19 +158,16 @@ func (h *LinksHandler) ToggleFavorite(w http.ResponseWriter, r *http.Request) {
 	
 	err = json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 	
 	err = h.db.ToggleFavorite(linkID, userID, request.IsFavorite)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Link not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeDBError(w, err, "Link not found", "Failed to update link")
 		return
 	}
+	emitLinkEvent(h.db, models.EventLinkUpdated, linkID)
 	
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -144,17 +175,9 @@ func (h *LinksHandler) ToggleFavorite(w http.ResponseWriter, r *http.Request) {
 func (h *LinksHandler) TogglePrivacy(w http.ResponseWriter, r *http.Request) {
 	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
 	
-	// Extract link ID from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
-		return
-	}
-	
-	linkID, err := strconv.Atoi(parts[3])
+	linkID, err := pathID(r)
 	if err != nil {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
 		return
 	}
 	
@@ -164,21 +187,44 @@ func (h *LinksHandler) TogglePrivacy(w http.ResponseWriter, r *http.Request) {
 	
 	err = json.NewDecoder(r.Body).Decode(&request)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 	
-	err = h.db.TogglePrivacy(linkID, userID, request.IsPrivate)
+	link, err := h.db.GetLink(linkID, userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Link not found", http.StatusNotFound)
-		} else if strings.Contains(err.Error(), "locked by administrator") {
-			http.Error(w, err.Error(), http.StatusForbidden)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, err, "Link not found", "Failed to get link")
+		return
+	}
+
+	// Links to blocked or private-only domains cannot be made public
+	if !request.IsPrivate {
+		action, err := h.domainAction(link.URL)
+		if err != nil {
+			writeError(w, "Failed to check domain policy", http.StatusInternalServerError)
+			return
+		}
+		if action != models.DomainAllow {
+			writeError(w, "Links to this domain cannot be shared publicly", http.StatusForbidden)
+			return
 		}
+	}
+	
+	err = h.db.TogglePrivacy(linkID, userID, request.IsPrivate)
+	if errors.Is(err, db.ErrLocked) {
+		writeError(w, "Link privacy is locked by an administrator", http.StatusForbidden)
 		return
 	}
+	if err != nil {
+		writeDBError(w, err, "Link not found", "Failed to update link")
+		return
+	}
+	emitLinkEvent(h.db, models.EventLinkUpdated, linkID)
+	if link.IsPrivate && !request.IsPrivate {
+		emitLinkEvent(h.db, models.EventLinkMadePublic, linkID)
+	} else if !link.IsPrivate && request.IsPrivate {
+		emitLinkHidden(linkID)
+	}
 	
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -186,51 +232,32 @@ func (h *LinksHandler) TogglePrivacy(w http.ResponseWriter, r *http.Request) {
 func (h *LinksHandler) DeleteLink(w http.ResponseWriter, r *http.Request) {
 	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
 	
-	// Extract link ID from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
-		return
-	}
-	
-	linkID, err := strconv.Atoi(parts[3])
+	linkID, err := pathID(r)
 	if err != nil {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
 		return
 	}
 	
 	err = h.db.DeleteLink(linkID, userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			http.Error(w, "Link not found", http.StatusNotFound)
-		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		writeDBError(w, err, "Link not found", "Failed to delete link")
 		return
 	}
+	emitLinkEvent(h.db, models.EventLinkDeleted, linkID)
 	
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *LinksHandler) IncrementAccess(w http.ResponseWriter, r *http.Request) {
-	// Extract link ID from URL path
-	path := r.URL.Path
-	parts := strings.Split(path, "/")
-	if len(parts) < 4 {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
-		return
-	}
-	
-	linkID, err := strconv.Atoi(parts[3])
+	linkID, err := pathID(r)
 	if err != nil {
-		http.Error(w, "Invalid link ID", http.StatusBadRequest)
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
 		return
 	}
 	
 	err = h.db.IncrementAccessCount(linkID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, err, "Link not found", "Failed to update access count")
 		return
 	}
 	
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"links/internal/events"
+	"links/internal/feeds"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+// Links saved by hand, by quick save and from feeds get the same checks
+func TestSaveLinkPaths(t *testing.T) {
+	database := newTestDB(t)
+	user, token := createTestUser(t, database, "alice")
+	if _, err := database.SaveDomainRule("blocked.example", models.DomainBlock, "", user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.SaveDomainRule("private.example", models.DomainPrivateOnly, "", user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	broker := events.NewBroker(100, 64, 10)
+	SetEventBroker(broker)
+	defer SetEventBroker(nil)
+	sub, _, _, err := broker.Subscribe(user.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	links := NewLinksHandler(database)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/links", middleware.AuthMiddleware(links.CreateLink))
+	mux.HandleFunc("POST /api/quick-save", middleware.AuthMiddleware(NewQuickSaveHandler(links, NewMetadataHandler()).QuickSave))
+
+	tests := []struct {
+		name    string
+		url     string
+		create  int   // Status from POST /api/links
+		quick   int   // Status from POST /api/quick-save
+		feed    error // Result of SaveFeedEntry
+		private bool  // Whether saved links end up private
+	}{
+		{"allowed", "https://example.com/", http.StatusOK, http.StatusCreated, nil, false},
+		{"private only", "https://private.example/", http.StatusOK, http.StatusCreated, nil, true},
+		{"blocked", "https://blocked.example/", http.StatusForbidden, http.StatusForbidden, feeds.ErrSkip, false},
+		{"invalid", "http://localhost/", http.StatusBadRequest, http.StatusBadRequest, feeds.ErrSkip, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := 0
+			request := map[string]interface{}{"url": tt.url, "description": "A link", "tags": "go", "is_private": false}
+			if w := serve(t, mux, "POST", "/api/links", token, request); w.Code != tt.create {
+				t.Errorf("create = %d %s, want %d", w.Code, w.Body, tt.create)
+			} else if w.Code == http.StatusOK {
+				saved++
+			}
+
+			request = map[string]interface{}{"url": tt.url + "quick", "title": "A link", "tags": "go"}
+			if w := serve(t, mux, "POST", "/api/quick-save", token, request); w.Code != tt.quick {
+				t.Errorf("quick save = %d %s, want %d", w.Code, w.Body, tt.quick)
+			} else if w.Code == http.StatusCreated {
+				saved++
+			}
+
+			entry := feeds.Entry{URL: tt.url + "feed", Title: "A link"}
+			if err := links.SaveFeedEntry(&models.FeedSubscription{UserID: user.ID, Name: "Feed"}, entry); err != tt.feed {
+				t.Errorf("feed entry: err = %v, want %v", err, tt.feed)
+			} else if err == nil {
+				saved++
+			}
+
+			// Each saved link is announced once, with the policy applied
+			for i := 0; i < saved; i++ {
+				select {
+				case event := <-sub.C:
+					var link models.Link
+					if err := json.Unmarshal(event.Data, &link); err != nil {
+						t.Fatal(err)
+					}
+					if event.Type != models.EventLinkCreated || link.IsPrivate != tt.private {
+						t.Errorf("event %s for link %d (private %v), want %s (private %v)", event.Type, link.ID, link.IsPrivate, models.EventLinkCreated, tt.private)
+					}
+				default:
+					t.Fatalf("%d events for %d saved links", i, saved)
+				}
+			}
+			if extra := len(sub.C); extra != 0 {
+				t.Errorf("%d events beyond the %d saved links", extra, saved)
+			}
+		})
+	}
+}
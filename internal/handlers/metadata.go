@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -11,9 +11,13 @@ import (
 	"time"
 
 	"golang.org/x/net/html"
+
+	"links/internal/safehttp"
 )
 
-type MetadataHandler struct{}
+type MetadataHandler struct {
+	client *http.Client
+}
 
 type URLMetadata struct {
 	Title       string   `json:"title"`
@@ -23,91 +27,50 @@ type URLMetadata struct {
 }
 
 func NewMetadataHandler() *MetadataHandler {
-	return &MetadataHandler{}
+	return &MetadataHandler{client: safehttp.NewClient(10 * time.Second)}
 }
 
 func (h *MetadataHandler) ExtractMetadata(w http.ResponseWriter, r *http.Request) {
 	targetURL := r.URL.Query().Get("url")
 	if targetURL == "" {
-		http.Error(w, "URL parameter is required", http.StatusBadRequest)
+		writeError(w, "URL parameter is required", http.StatusBadRequest)
 		return
 	}
 
 	// Validate and sanitize URL
 	if !h.isValidURL(targetURL) {
-		http.Error(w, "Invalid or prohibited URL", http.StatusBadRequest)
+		writeError(w, "Invalid or prohibited URL", http.StatusBadRequest)
 		return
 	}
 
-	metadata := h.fetchURLMetadata(targetURL)
+	metadata, err := h.fetchURLMetadata(targetURL)
+	if errors.Is(err, safehttp.ErrBlockedAddress) {
+		writeError(w, "Invalid or prohibited URL", http.StatusBadRequest)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(metadata)
 }
 
+// isValidURL checks what can be judged before connecting; the client's dialer
+// rejects hosts that resolve to internal addresses
 func (h *MetadataHandler) isValidURL(targetURL string) bool {
-	// Parse URL
-	parsedURL, err := url.Parse(targetURL)
-	if err != nil {
-		return false
-	}
-
-	// Only allow HTTP and HTTPS
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return false
-	}
-
-	// Block private/local IP ranges to prevent SSRF
-	hostname := strings.ToLower(parsedURL.Hostname())
-	
-	// Block localhost variations
-	if hostname == "localhost" || hostname == "127.0.0.1" || hostname == "::1" {
-		return false
-	}
-
-	// Block private IPv4 ranges
-	privateIPv4Patterns := []string{
-		"192.168.", // Private Class C
-		"10.",      // Private Class A
-		"172.16.", "172.17.", "172.18.", "172.19.", "172.20.", // Private Class B
-		"172.21.", "172.22.", "172.23.", "172.24.", "172.25.",
-		"172.26.", "172.27.", "172.28.", "172.29.", "172.30.", "172.31.",
-		"169.254.", // Link-local
-		"0.0.0.0",  // This network
-	}
-
-	for _, pattern := range privateIPv4Patterns {
-		if strings.HasPrefix(hostname, pattern) {
-			return false
-		}
-	}
-
-	// Block private IPv6 ranges
-	if strings.HasPrefix(hostname, "fe80:") || strings.HasPrefix(hostname, "fc00:") || strings.HasPrefix(hostname, "fd00:") {
+	if len(targetURL) > 2048 {
 		return false
 	}
 
-	// Block common internal hostnames
-	internalHosts := []string{
-		"internal", "intranet", "admin", "management", 
-		"staging", "dev", "test", "debug",
-	}
-
-	for _, internal := range internalHosts {
-		if strings.Contains(hostname, internal) {
-			return false
-		}
-	}
-
-	// Additional length and format checks
-	if len(targetURL) > 2048 {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
 		return false
 	}
 
-	return true
+	return safehttp.ValidateURL(parsedURL) == nil
 }
 
-func (h *MetadataHandler) fetchURLMetadata(targetURL string) URLMetadata {
+// fetchURLMetadata returns whatever metadata could be extracted; the error is only
+// set when the page could not be fetched
+func (h *MetadataHandler) fetchURLMetadata(targetURL string) (URLMetadata, error) {
 	parsedURL, _ := url.Parse(targetURL)
 	domain := parsedURL.Hostname()
 
@@ -116,37 +79,25 @@ func (h *MetadataHandler) fetchURLMetadata(targetURL string) URLMetadata {
 		Tags:   []string{},
 	}
 
-	// Create HTTP client with timeout and security settings
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			// Limit redirects to prevent abuse
-			if len(via) >= 5 {
-				return fmt.Errorf("too many redirects")
-			}
-			return nil
-		},
-	}
-
 	req, err := http.NewRequest("GET", targetURL, nil)
 	if err != nil {
-		return metadata
+		return metadata, err
 	}
 
 	// Set user agent
 	req.Header.Set("User-Agent", "Links-App/1.0 (+https://github.com/yourapp)")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml")
 
-	resp, err := client.Do(req)
+	resp, err := h.client.Do(req)
 	if err != nil {
-		return metadata
+		return metadata, err
 	}
 	defer resp.Body.Close()
 
 	// Check content type
 	contentType := resp.Header.Get("Content-Type")
 	if !strings.Contains(strings.ToLower(contentType), "text/html") {
-		return metadata
+		return metadata, nil
 	}
 
 	// Limit response size to prevent abuse
@@ -155,14 +106,14 @@ func (h *MetadataHandler) fetchURLMetadata(targetURL string) URLMetadata {
 	// Parse HTML
 	doc, err := html.Parse(limitedBody)
 	if err != nil {
-		return metadata
+		return metadata, nil
 	}
 
 	metadata.Title = h.extractTitle(doc)
 	metadata.Description = h.extractDescription(doc)
 	metadata.Tags = h.inferTags(metadata.Title, metadata.Description, domain)
 
-	return metadata
+	return metadata, nil
 }
 
 func (h *MetadataHandler) extractTitle(n *html.Node) string {
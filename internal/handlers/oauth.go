@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"links/internal/auth"
@@ -19,6 +20,7 @@ type OAuthDBInterface interface {
 	CreateOAuthUser(email, name, googleID, createdAt string) (int64, error)
 	GetUserByGoogleID(googleID string) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	WriteAudit(entry models.AuditEntry) error
 }
 
 func NewOAuthHandler(db OAuthDBInterface) *OAuthHandler {
@@ -26,8 +28,12 @@ func NewOAuthHandler(db OAuthDBInterface) *OAuthHandler {
 }
 
 func (h *OAuthHandler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
-	url := auth.GetGoogleLoginURL()
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	loginURL, err := auth.GetGoogleLoginURL(w, r, r.URL.Query().Get("redirect"))
+	if err != nil {
+		writeError(w, "Failed to start login", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, loginURL, http.StatusTemporaryRedirect)
 }
 
 func (h *OAuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
@@ -35,14 +41,14 @@ func (h *OAuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 	state := r.URL.Query().Get("state")
 
 	if code == "" {
-		http.Error(w, "Code not found", http.StatusBadRequest)
+		writeError(w, "Code not found", http.StatusBadRequest)
 		return
 	}
 
-	googleUser, err := auth.HandleGoogleCallback(code, state)
+	googleUser, redirect, err := auth.HandleGoogleCallback(w, r, code, state)
 	if err != nil {
 		log.Printf("Error handling Google callback: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, "Google login failed", http.StatusBadRequest)
 		return
 	}
 
@@ -51,6 +57,12 @@ func (h *OAuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 	// Try to find user by Google ID first
 	user, err := h.db.GetUserByGoogleID(googleUser.ID)
 	if err != nil {
+		// Only link or create accounts by email once Google has verified it
+		if !googleUser.VerifiedEmail {
+			writeError(w, "Google account email is not verified", http.StatusForbidden)
+			return
+		}
+
 		// If not found by Google ID, try by email
 		user, err = h.db.GetUserByEmail(googleUser.Email)
 		if err != nil {
@@ -59,7 +71,7 @@ func (h *OAuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 			userID, err := h.db.CreateOAuthUser(googleUser.Email, googleUser.Email, googleUser.ID, createdAt)
 			if err != nil {
 				log.Printf("Error creating OAuth user: %v", err)
-				http.Error(w, "Failed to create user", http.StatusInternalServerError)
+				writeError(w, "Failed to create user", http.StatusInternalServerError)
 				return
 			}
 
@@ -67,20 +79,58 @@ func (h *OAuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 				ID:        int(userID),
 				Username:  googleUser.Email,
 				IsAdmin:   false,
+				Role:      auth.RoleUser,
 				CreatedAt: createdAt,
 			}
+			recordAudit(h.db, r, models.AuditEntry{
+				ActorID:       user.ID,
+				ActorUsername: user.Username,
+				Action:        "user.register",
+				TargetType:    "user",
+				TargetID:      strconv.Itoa(user.ID),
+				After:         auditValue(map[string]string{"method": "google"}),
+			})
+		}
+	}
+
+	if user.DeletedAt != nil {
+		writeError(w, "This account has been deleted", http.StatusForbidden)
+		return
+	}
+	if rejectIfSuspended(w, user) {
+		return
+	}
+
+	// Google stands in for the password only: with a second factor enabled the login
+	// page finishes the login like a password login, through /api/login/2fa or a passkey
+	if user.HasSecondFactor() {
+		mfaToken, err := auth.GenerateMFAToken(user.ID, user.Username)
+		if err != nil {
+			writeError(w, "Failed to start two-factor login", http.StatusInternalServerError)
+			return
 		}
+		http.Redirect(w, r, "/login?mfa_token="+url.QueryEscape(mfaToken)+"&redirect="+url.QueryEscape(redirect), http.StatusTemporaryRedirect)
+		return
 	}
 
 	// Generate JWT token
-	token, err := auth.GenerateJWT(user.ID, user.Username, user.IsAdmin)
+	token, err := auth.GenerateJWT(user.ID, user.Username, user.IsAdmin, user.SessionVersion)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		writeError(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
 
-	// Redirect to frontend with token and user data
+	recordAudit(h.db, r, models.AuditEntry{
+		ActorID:       user.ID,
+		ActorUsername: user.Username,
+		Action:        "login.success",
+		TargetType:    "user",
+		TargetID:      strconv.Itoa(user.ID),
+		After:         auditValue(map[string]string{"method": "google"}),
+	})
+
+	// Redirect to the login page, which stores the session and continues to the validated target
 	userJSON, _ := json.Marshal(user)
-	redirectURL := "/?token=" + url.QueryEscape(token) + "&user=" + url.QueryEscape(string(userJSON))
+	redirectURL := "/login?token=" + url.QueryEscape(token) + "&user=" + url.QueryEscape(string(userJSON)) + "&redirect=" + url.QueryEscape(redirect)
 	http.Redirect(w, r, redirectURL, http.StatusTemporaryRedirect)
 }
\ No newline at end of file
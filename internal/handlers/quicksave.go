@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"links/internal/models"
+)
+
+// QuickSaveHandler saves a link in one request from a browser extension or the
+// bookmarklet, filling in whatever the caller left out from the page's metadata
+type QuickSaveHandler struct {
+	links    *LinksHandler
+	metadata *MetadataHandler
+}
+
+func NewQuickSaveHandler(links *LinksHandler, metadata *MetadataHandler) *QuickSaveHandler {
+	return &QuickSaveHandler{links: links, metadata: metadata}
+}
+
+// QuickSaveRequest is accepted as JSON or as a form
+type QuickSaveRequest struct {
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Text      string `json:"text"` // Text selected on the page, saved as the description
+	Tags      string `json:"tags"`
+	Category  string `json:"category"`
+	IsPrivate bool   `json:"is_private"`
+}
+
+func (h *QuickSaveHandler) QuickSave(w http.ResponseWriter, r *http.Request) {
+	var req QuickSaveRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		req.URL = r.PostForm.Get("url")
+		req.Title = r.PostForm.Get("title")
+		req.Text = r.PostForm.Get("text")
+		req.Tags = r.PostForm.Get("tags")
+		req.Category = r.PostForm.Get("category")
+		req.IsPrivate, _ = strconv.ParseBool(r.PostForm.Get("is_private"))
+	}
+
+	if !h.metadata.isValidURL(req.URL) {
+		writeError(w, "A valid http or https URL is required", http.StatusBadRequest)
+		return
+	}
+
+	// Only fetch the page when the caller didn't send a title and tags; a fetch
+	// failure just leaves the link without them
+	description := strings.TrimSpace(req.Text)
+	if description == "" {
+		description = strings.TrimSpace(req.Title)
+	}
+	tags := strings.TrimSpace(req.Tags)
+	if description == "" || tags == "" {
+		metadata, _ := h.metadata.fetchURLMetadata(req.URL)
+		if description == "" {
+			description = metadata.Description
+			if description == "" {
+				description = metadata.Title
+			}
+		}
+		if tags == "" {
+			tags = strings.Join(metadata.Tags, ",")
+		}
+	}
+
+	link := models.Link{
+		URL:         req.URL,
+		Description: &description,
+		Tags:        &tags,
+		Category:    &req.Category,
+		IsPrivate:   req.IsPrivate,
+	}
+	link.UserID, _ = strconv.Atoi(r.Header.Get("X-User-ID"))
+	if err := h.links.saveLink(&link); err != nil {
+		writeSaveLinkError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(link)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"links/internal/auth"
+	"links/internal/db"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+var reportReasons = map[string]bool{"spam": true, "abuse": true, "illegal": true, "other": true}
+
+// ReportLink files a report against another user's public link
+func (h *LinksHandler) ReportLink(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+
+	linkID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !reportReasons[req.Reason] {
+		writeError(w, "Reason must be one of spam, abuse, illegal or other", http.StatusBadRequest)
+		return
+	}
+	details := middleware.Sanitizer.SanitizeText(req.Details)
+	if len(details) > 500 {
+		details = details[:500]
+	}
+
+	id, err := h.db.CreateReport(linkID, userID, req.Reason, details)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, "Link not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, db.ErrDuplicateReport) {
+		writeError(w, "You have already reported this link", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeError(w, "Failed to report link", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"id": id})
+}
+
+// GetReports returns the moderation queue, open reports by default (?status=resolved
+// or ?status=all for history)
+func (h *AdminHandler) GetReports(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = db.ReportStatusOpen
+	case "all":
+		status = ""
+	case db.ReportStatusOpen, db.ReportStatusResolved:
+	default:
+		writeError(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	reports, err := h.db.GetReports(status)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get reports")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+// reportActionPermissions lists the extra permission each moderation action needs
+// beyond reports:manage
+var reportActionPermissions = map[string]auth.Permission{
+	"force_private": auth.PermLinksForcePrivate,
+	"delete":        auth.PermLinksDelete,
+	"suspend":       auth.PermUsersSuspend,
+}
+
+// ResolveReport applies a moderation action to the reported link or its owner and
+// closes the report. Dismissing closes only this report; any other action closes every
+// open report on the link.
+func (h *AdminHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	reportID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid report ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ResolveReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if perm, ok := reportActionPermissions[req.Action]; ok {
+		if !auth.HasPermission(user.Role, perm) {
+			writeError(w, "Insufficient permissions", http.StatusForbidden)
+			return
+		}
+	} else if req.Action != "dismiss" {
+		writeError(w, "Action must be one of dismiss, force_private, delete or suspend", http.StatusBadRequest)
+		return
+	}
+	note := middleware.Sanitizer.SanitizeText(req.Note)
+
+	report, err := h.db.GetReport(reportID)
+	if err != nil {
+		writeDBError(w, err, "Report not found", "Failed to get report")
+		return
+	}
+	if report.Status != db.ReportStatusOpen {
+		writeError(w, "Report is already resolved", http.StatusConflict)
+		return
+	}
+
+	// Whether the link is still public decides if live clients must drop it
+	link, err := h.db.AdminGetLink(report.LinkID)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		writeDBError(w, err, "", "Failed to get link")
+		return
+	}
+
+	switch req.Action {
+	case "force_private":
+		err = h.db.AdminForcePrivateLink(report.LinkID)
+		if err == nil {
+			emitLinkEvent(h.db, models.EventLinkUpdated, report.LinkID)
+			if link != nil && !link.IsPrivate {
+				emitLinkHidden(report.LinkID)
+			}
+		}
+	case "delete":
+		err = h.db.AdminDeleteLink(report.LinkID, user.ID)
+		if err == nil {
+			emitLinkEvent(h.db, models.EventLinkDeleted, report.LinkID)
+		}
+	case "suspend":
+		if report.LinkOwnerID == user.ID {
+			writeError(w, "Cannot suspend own account", http.StatusBadRequest)
+			return
+		}
+		reason := "Reported link: " + report.Reason
+		if note != "" {
+			reason = note
+		}
+		err = h.db.SuspendUser(report.LinkOwnerID, reason, nil)
+		if err == nil {
+			emitOwnerLinksHidden(h.db, report.LinkOwnerID)
+		}
+	}
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		writeError(w, "Failed to apply moderation action", http.StatusInternalServerError)
+		return
+	}
+
+	resolved, err := h.db.ResolveReports(reportID, req.Action != "dismiss", req.Action, user.ID, note)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to resolve report")
+		return
+	}
+
+	targetType, targetID := "link", strconv.Itoa(report.LinkID)
+	if req.Action == "suspend" {
+		targetType, targetID = "user", strconv.Itoa(report.LinkOwnerID)
+	}
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "report." + req.Action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     auditValue(map[string]interface{}{"report_id": report.ID, "reason": report.Reason, "details": report.Details}),
+		After:      auditValue(map[string]interface{}{"resolved_reports": resolved, "note": note}),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{"resolved": resolved})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"links/internal/events"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+// publicEvents drains the link events sub was sent, as sorted "type id" strings. An
+// anonymous subscription sees what everyone watching public links is sent.
+func publicEvents(t *testing.T, sub *events.Subscription) []string {
+	t.Helper()
+	var got []string
+	for {
+		select {
+		case event := <-sub.C:
+			var link struct{ ID int }
+			if err := json.Unmarshal(event.Data, &link); err != nil {
+				t.Fatalf("event %s: %v", event.Type, err)
+			}
+			got = append(got, event.Type+" "+strconv.Itoa(link.ID))
+		default:
+			sort.Strings(got)
+			return got
+		}
+	}
+}
+
+// Resolving a report updates live clients like the admin link endpoints do
+func TestResolveReportEmitsEvents(t *testing.T) {
+	now := time.Now().Format("2006-01-02 15:04:05")
+
+	tests := []struct {
+		action string
+		want   func(reported, other int) []string
+	}{
+		{"dismiss", func(reported, other int) []string { return nil }},
+		{"force_private", func(reported, other int) []string {
+			return []string{EventLinkHidden + " " + strconv.Itoa(reported)}
+		}},
+		{"delete", func(reported, other int) []string {
+			return []string{models.EventLinkDeleted + " " + strconv.Itoa(reported)}
+		}},
+		{"suspend", func(reported, other int) []string {
+			// The owner's other public link goes too; the private one was never shown.
+			// Other was created first, so it sorts first.
+			return []string{EventLinkHidden + " " + strconv.Itoa(other), EventLinkHidden + " " + strconv.Itoa(reported)}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			database := newTestDB(t)
+			admin, token := createTestUser(t, database, "admin")
+			if err := database.AdminSetUserRole(admin.ID, "admin"); err != nil {
+				t.Fatal(err)
+			}
+			owner, _ := createTestUser(t, database, "owner")
+			reporter, _ := createTestUser(t, database, "reporter")
+
+			newLink := func(url string, private bool) int {
+				id, err := database.CreateLink(owner.ID, url, nil, nil, nil, now, private)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return int(id)
+			}
+			other := newLink("https://example.com/other", false)
+			newLink("https://example.com/private", true)
+			reported := newLink("https://example.com/reported", false)
+			reportID, err := database.CreateReport(reported, reporter.ID, "spam", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			broker := events.NewBroker(100, 64, 10)
+			SetEventBroker(broker)
+			defer SetEventBroker(nil)
+			sub, _, _, err := broker.Subscribe(0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sub.Close()
+
+			h := NewAdminHandler(database)
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /api/admin/reports/{id}/resolve", middleware.AuthMiddleware(h.ResolveReport))
+			w := serve(t, mux, "POST", "/api/admin/reports/"+strconv.FormatInt(reportID, 10)+"/resolve", token, models.ResolveReportRequest{Action: tt.action})
+			if w.Code != http.StatusOK {
+				t.Fatalf("resolve = %d %s", w.Code, w.Body)
+			}
+
+			if got, want := publicEvents(t, sub), tt.want(reported, other); !reflect.DeepEqual(got, want) {
+				t.Errorf("public events = %v, want %v", got, want)
+			}
+		})
+	}
+}
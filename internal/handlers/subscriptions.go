@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"links/internal/db"
+	"links/internal/feeds"
+	"links/internal/middleware"
+	"links/internal/models"
+	"links/internal/safehttp"
+)
+
+// maxSubscriptions caps how many feeds one account may follow
+const maxSubscriptions = 50
+
+// SubscriptionsHandler manages the feeds the signed-in user follows. New items are
+// saved as links by the background poller.
+type SubscriptionsHandler struct {
+	db     SubscriptionsDBInterface
+	poller *feeds.Poller
+}
+
+type SubscriptionsDBInterface interface {
+	GetFeedSubscriptions(userID int) ([]models.FeedSubscription, error)
+	GetFeedSubscription(id, userID int) (*models.FeedSubscription, error)
+	CountFeedSubscriptions(userID int) (int, error)
+	CreateFeedSubscription(userID int, url, name string, isPrivate bool, nextCheck time.Time) (*models.FeedSubscription, error)
+	UpdateFeedSubscription(id, userID int, name string, isPrivate, active bool) error
+	DeleteFeedSubscription(id, userID int) error
+	WriteAudit(entry models.AuditEntry) error
+}
+
+func NewSubscriptionsHandler(db SubscriptionsDBInterface, poller *feeds.Poller) *SubscriptionsHandler {
+	return &SubscriptionsHandler{db: db, poller: poller}
+}
+
+// SaveFeedEntry saves a feed entry as a link of the subscriber, tagged with the
+// feed's name and subject to the same checks as links saved by hand
+func (h *LinksHandler) SaveFeedEntry(sub *models.FeedSubscription, entry feeds.Entry) error {
+	tags := feedTag(sub)
+	link := models.Link{
+		UserID:      sub.UserID,
+		URL:         entry.URL,
+		Description: &entry.Title,
+		Tags:        &tags,
+		IsPrivate:   sub.IsPrivate,
+	}
+	err := h.saveLink(&link)
+	if errors.Is(err, errInvalidLink) || errors.Is(err, errDomainBlocked) {
+		return feeds.ErrSkip
+	}
+	return err
+}
+
+// feedTag turns the subscription's name, or failing that the feed's host, into a tag
+func feedTag(sub *models.FeedSubscription) string {
+	words := strings.FieldsFunc(html.UnescapeString(sub.Name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tag := strings.Trim(middleware.Sanitizer.SanitizeTags(strings.Join(words, "-")), "-")
+	if tag == "" {
+		if parsed, err := url.Parse(sub.URL); err == nil {
+			tag = middleware.Sanitizer.SanitizeTags(strings.ReplaceAll(strings.TrimPrefix(parsed.Hostname(), "www."), ".", "-"))
+		}
+	}
+	return tag
+}
+
+// writePollError reports a failed poll with a fixed message, since fetch errors can
+// describe the server's own network. The detail is logged with the request ID.
+func writePollError(w http.ResponseWriter, sub *models.FeedSubscription, err error, status int) {
+	if errors.Is(err, safehttp.ErrBlockedAddress) {
+		writeError(w, "Invalid or prohibited URL", http.StatusBadRequest)
+		return
+	}
+	log.Printf("[%s] Polling feed subscription %d (%s) failed: %v", w.Header().Get(middleware.RequestIDHeader), sub.ID, sub.URL, err)
+	writeError(w, "Could not read the feed", status)
+}
+
+// validSubscriptionName checks the name's length once sanitized
+func validSubscriptionName(name string) (string, bool) {
+	name = middleware.Sanitizer.SanitizeText(name)
+	return name, len(name) <= 100
+}
+
+func (h *SubscriptionsHandler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	subs, err := h.db.GetFeedSubscriptions(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get feed subscriptions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subs)
+}
+
+// CreateSubscription follows a feed and polls it straight away, so a URL that is not
+// a readable feed is rejected. Without a name the feed's own title is used.
+func (h *SubscriptionsHandler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req models.FeedSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	parsed, err := url.Parse(req.URL)
+	if err != nil || len(req.URL) > 2048 || safehttp.ValidateURL(parsed) != nil {
+		writeError(w, "URL must be an http or https address without credentials", http.StatusBadRequest)
+		return
+	}
+	name, ok := validSubscriptionName(req.Name)
+	if !ok {
+		writeError(w, "Name must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.db.CountFeedSubscriptions(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to create feed subscription")
+		return
+	}
+	if count >= maxSubscriptions {
+		writeError(w, "Too many feed subscriptions; remove one first", http.StatusConflict)
+		return
+	}
+
+	// Hold off the background poller while the first poll runs here
+	isPrivate := req.IsPrivate != nil && *req.IsPrivate
+	sub, err := h.db.CreateFeedSubscription(user.ID, parsed.String(), name, isPrivate, time.Now().Add(time.Hour))
+	if errors.Is(err, db.ErrConflict) {
+		writeError(w, "You already follow this feed", http.StatusConflict)
+		return
+	} else if err != nil {
+		writeDBError(w, err, "", "Failed to create feed subscription")
+		return
+	}
+
+	result, err := h.poller.Poll(sub)
+	if err != nil {
+		if err := h.db.DeleteFeedSubscription(sub.ID, user.ID); err != nil {
+			writeDBError(w, err, "", "Failed to create feed subscription")
+			return
+		}
+		writePollError(w, sub, err, http.StatusBadRequest)
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "feed_subscription.create",
+		TargetType: "feed_subscription",
+		TargetID:   strconv.Itoa(sub.ID),
+		After:      auditValue(map[string]interface{}{"url": sub.URL, "name": sub.Name, "is_private": sub.IsPrivate}),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// UpdateSubscription changes whichever of the name, privacy of new links and active
+// flag are given. The URL is fixed; follow the new address instead.
+func (h *SubscriptionsHandler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid feed subscription ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.FeedSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.db.GetFeedSubscription(id, user.ID)
+	if err != nil {
+		writeDBError(w, err, "Feed subscription not found", "Failed to get feed subscription")
+		return
+	}
+	if req.URL != "" && strings.TrimSpace(req.URL) != before.URL {
+		writeError(w, "The feed URL cannot be changed; subscribe to the new URL instead", http.StatusBadRequest)
+		return
+	}
+	name, ok := validSubscriptionName(req.Name)
+	if !ok {
+		writeError(w, "Name must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+	if name == "" {
+		name = before.Name
+	}
+	isPrivate := before.IsPrivate
+	if req.IsPrivate != nil {
+		isPrivate = *req.IsPrivate
+	}
+	active := before.Active
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	err = h.db.UpdateFeedSubscription(id, user.ID, name, isPrivate, active)
+	if err != nil {
+		writeDBError(w, err, "Feed subscription not found", "Failed to update feed subscription")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "feed_subscription.update",
+		TargetType: "feed_subscription",
+		TargetID:   strconv.Itoa(id),
+		Before:     auditValue(map[string]interface{}{"name": before.Name, "is_private": before.IsPrivate, "active": before.Active}),
+		After:      auditValue(map[string]interface{}{"name": name, "is_private": isPrivate, "active": active}),
+	})
+
+	sub, err := h.db.GetFeedSubscription(id, user.ID)
+	if err != nil {
+		writeDBError(w, err, "Feed subscription not found", "Failed to get feed subscription")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sub)
+}
+
+// DeleteSubscription stops following a feed; links it already saved are kept
+func (h *SubscriptionsHandler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid feed subscription ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.DeleteFeedSubscription(id, user.ID)
+	if err != nil {
+		writeDBError(w, err, "Feed subscription not found", "Failed to delete feed subscription")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "feed_subscription.delete",
+		TargetType: "feed_subscription",
+		TargetID:   strconv.Itoa(id),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RefreshSubscription polls a feed right away, whether or not it is active, and
+// returns what was saved
+func (h *SubscriptionsHandler) RefreshSubscription(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid feed subscription ID", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.db.GetFeedSubscription(id, user.ID)
+	if err != nil {
+		writeDBError(w, err, "Feed subscription not found", "Failed to get feed subscription")
+		return
+	}
+
+	result, err := h.poller.Poll(sub)
+	if errors.Is(err, feeds.ErrPollInProgress) {
+		writeError(w, "The feed is being polled; try again shortly", http.StatusConflict)
+		return
+	} else if err != nil {
+		writePollError(w, sub, err, http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"links/internal/db"
+	"links/internal/feeds"
+	"links/internal/middleware"
+	"links/internal/models"
+	"links/internal/safehttp"
+)
+
+// newSubscriptionsMux routes the feed endpoints to a handler whose poller fetches
+// with client
+func newSubscriptionsMux(database *db.Database, client *http.Client) *http.ServeMux {
+	poller := feeds.NewPoller(database, client, NewLinksHandler(database).SaveFeedEntry)
+	h := NewSubscriptionsHandler(database, poller)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/feeds", middleware.AuthMiddleware(h.GetSubscriptions))
+	mux.HandleFunc("POST /api/feeds", middleware.AuthMiddleware(h.CreateSubscription))
+	mux.HandleFunc("POST /api/feeds/{id}/refresh", middleware.AuthMiddleware(h.RefreshSubscription))
+	return mux
+}
+
+// errorMessage returns the message of a JSON error response
+func errorMessage(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var response models.ErrorResponse
+	decode(t, w, &response)
+	return response.Error.Message
+}
+
+// Failed polls answer with fixed messages; the fetch error can describe the
+// server's own network
+func TestSubscriptionPollErrors(t *testing.T) {
+	database := newTestDB(t)
+	user, token := createTestUser(t, database, "alice")
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "secret internal detail", http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+	internal, _, _ := strings.Cut(receiver.Listener.Addr().String(), ":")
+
+	tests := []struct {
+		name    string
+		client  *http.Client
+		create  int // Status when subscribing
+		refresh int // Status when refreshing an existing subscription
+		message string
+	}{
+		{"internal address", safehttp.NewClient(2 * time.Second), http.StatusBadRequest, http.StatusBadRequest, "Invalid or prohibited URL"},
+		{"failing feed", receiver.Client(), http.StatusBadRequest, http.StatusBadGateway, "Could not read the feed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux := newSubscriptionsMux(database, tt.client)
+			before, _ := database.CountFeedSubscriptions(user.ID)
+
+			w := serve(t, mux, "POST", "/api/feeds", token, map[string]string{"url": receiver.URL + "/feed.xml"})
+			if w.Code != tt.create || errorMessage(t, w) != tt.message {
+				t.Errorf("subscribe = %d %s, want %d %q", w.Code, w.Body, tt.create, tt.message)
+			}
+			if count, _ := database.CountFeedSubscriptions(user.ID); count != before {
+				t.Errorf("%d subscriptions kept after a failed first poll", count-before)
+			}
+
+			sub, err := database.CreateFeedSubscription(user.ID, receiver.URL+"/"+strings.ReplaceAll(tt.name, " ", "-"), "Feed", false, time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w = serve(t, mux, "POST", "/api/feeds/"+strconv.Itoa(sub.ID)+"/refresh", token, nil)
+			if w.Code != tt.refresh || errorMessage(t, w) != tt.message {
+				t.Errorf("refresh = %d %s, want %d %q", w.Code, w.Body, tt.refresh, tt.message)
+			}
+		})
+	}
+
+	// The reason kept on the subscriptions does not leak the detail either
+	w := serve(t, newSubscriptionsMux(database, receiver.Client()), "GET", "/api/feeds", token, nil)
+	var subs []models.FeedSubscription
+	decode(t, w, &subs)
+	reasons := map[string]bool{}
+	for _, sub := range subs {
+		if strings.Contains(sub.LastError, internal) || strings.Contains(sub.LastError, "secret internal detail") {
+			t.Errorf("subscription %d exposes the fetch error: %s", sub.ID, sub.LastError)
+		}
+		reasons[sub.LastError] = true
+	}
+	if !reasons["destination address is not allowed"] || !reasons["unexpected status 500 Internal Server Error"] {
+		t.Errorf("last errors = %v", reasons)
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+// SuspendRequest suspends an account. Until is optional; an empty value suspends
+// the account until an admin lifts it.
+type SuspendRequest struct {
+	Reason string `json:"reason"`
+	Until  string `json:"until"`
+}
+
+// suspendUntilLayouts are the accepted formats for SuspendRequest.Until, including
+// the value of an HTML datetime-local input
+var suspendUntilLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+func parseSuspendUntil(value string) (time.Time, bool) {
+	for _, layout := range suspendUntilLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// rejectIfSuspended writes a 403 naming the reason and end of an active suspension
+func rejectIfSuspended(w http.ResponseWriter, user *models.User) bool {
+	if !user.IsSuspended() {
+		return false
+	}
+
+	message := "This account has been suspended"
+	if user.SuspendedUntil != nil {
+		message += " until " + *user.SuspendedUntil
+	}
+	if user.SuspendReason != nil && *user.SuspendReason != "" {
+		message += ": " + *user.SuspendReason
+	}
+	writeError(w, message, http.StatusForbidden)
+	return true
+}
+
+func (h *AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	userID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if userID == user.ID {
+		writeError(w, "Cannot suspend own account", http.StatusBadRequest)
+		return
+	}
+
+	var req SuspendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > 500 {
+		writeError(w, "A reason of at most 500 characters is required", http.StatusBadRequest)
+		return
+	}
+
+	var until *string
+	if req.Until != "" {
+		t, ok := parseSuspendUntil(req.Until)
+		if !ok {
+			writeError(w, "Invalid suspension end date", http.StatusBadRequest)
+			return
+		}
+		if !t.After(time.Now()) {
+			writeError(w, "Suspension end date must be in the future", http.StatusBadRequest)
+			return
+		}
+		formatted := t.Local().Format("2006-01-02 15:04:05")
+		until = &formatted
+	}
+
+	target, err := h.db.GetUserByID(userID)
+	if err != nil {
+		writeDBError(w, err, "User not found", "Failed to get user")
+		return
+	}
+
+	err = h.db.SuspendUser(userID, req.Reason, until)
+	if err != nil {
+		writeDBError(w, err, "User not found", "Failed to suspend user")
+		return
+	}
+	emitOwnerLinksHidden(h.db, userID)
+
+	after := map[string]string{"reason": req.Reason}
+	if until != nil {
+		after["until"] = *until
+	}
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.suspend",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Before:     auditValue(suspensionState(target)),
+		After:      auditValue(after),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AdminHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	target, err := h.db.GetUserByID(userID)
+	if err != nil {
+		writeDBError(w, err, "User not found", "Failed to get user")
+		return
+	}
+
+	err = h.db.UnsuspendUser(userID)
+	if err != nil {
+		writeDBError(w, err, "User is not suspended", "Failed to unsuspend user")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "user.unsuspend",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(userID),
+		Before:     auditValue(suspensionState(target)),
+	})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// suspensionState summarises a user's suspension for the audit log; an expired
+// suspension is still reported since its columns remain set until lifted
+func suspensionState(user *models.User) map[string]string {
+	state := map[string]string{}
+	if user.SuspendedAt == nil {
+		return state
+	}
+	state["suspended_at"] = *user.SuspendedAt
+	if user.SuspendedUntil != nil {
+		state["until"] = *user.SuspendedUntil
+	}
+	if user.SuspendReason != nil {
+		state["reason"] = *user.SuspendReason
+	}
+	return state
+}
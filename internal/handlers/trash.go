@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// GetTrash lists the user's deleted links, which can be restored until they are purged
+func (h *LinksHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+
+	links, err := h.db.GetTrashedLinks(userID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get trash")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+func (h *LinksHandler) RestoreLink(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+
+	linkID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.RestoreLink(linkID, userID)
+	if err != nil {
+		writeDBError(w, err, "Link not found in trash", "Failed to restore link")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PurgeLink permanently deletes a single link from the trash
+func (h *LinksHandler) PurgeLink(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+
+	linkID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.PurgeLink(linkID, userID)
+	if err != nil {
+		writeDBError(w, err, "Link not found in trash", "Failed to delete link")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EmptyTrash permanently deletes everything in the user's trash
+func (h *LinksHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+
+	if _, err := h.db.EmptyTrash(userID); err != nil {
+		writeError(w, "Failed to empty trash", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
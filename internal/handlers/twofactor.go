@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"links/internal/auth"
+	"links/internal/db"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+type TwoFactorHandler struct {
+	db TwoFactorDBInterface
+}
+
+type TwoFactorDBInterface interface {
+	GetUserByUsername(username string) (*models.User, string, error)
+	GetTOTP(userID int) (string, bool, int64, error)
+	SetPendingTOTPSecret(userID int, secret string) error
+	EnableTOTP(userID int, lastStep int64, recoveryCodeHashes []string) error
+	DisableTOTP(userID int) error
+	UpdateTOTPLastStep(userID int, step int64) error
+	ReplaceRecoveryCodes(userID int, codeHashes []string) error
+	CountRecoveryCodes(userID int) (int, error)
+	UseRecoveryCode(userID int, codeHash string) error
+	WriteAudit(entry models.AuditEntry) error
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"` // Render as a QR code for authenticator apps
+}
+
+type TwoFactorCodeRequest struct {
+	Code     string `json:"code"`
+	Password string `json:"password,omitempty"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"`
+}
+
+func NewTwoFactorHandler(db TwoFactorDBInterface) *TwoFactorHandler {
+	return &TwoFactorHandler{db: db}
+}
+
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	_, enabled, _, err := h.db.GetTOTP(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get two-factor status")
+		return
+	}
+
+	remaining, err := h.db.CountRecoveryCodes(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get two-factor status")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TwoFactorStatusResponse{Enabled: enabled, RecoveryCodesRemaining: remaining})
+}
+
+// Setup starts enrollment by generating a new pending secret
+func (h *TwoFactorHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	secret := auth.GenerateTOTPSecret()
+	err := h.db.SetPendingTOTPSecret(user.ID, secret)
+	if errors.Is(err, db.ErrNotFound) {
+		writeError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeError(w, "Failed to start two-factor setup", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: auth.TOTPURI(secret, user.Username),
+	})
+}
+
+// Enable confirms enrollment with a code from the authenticator and returns the
+// recovery codes; they are only ever shown in this response
+func (h *TwoFactorHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	secret, enabled, lastStep, err := h.db.GetTOTP(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to enable two-factor authentication")
+		return
+	}
+	if enabled {
+		writeError(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if secret == "" {
+		writeError(w, "Two-factor setup has not been started", http.StatusBadRequest)
+		return
+	}
+
+	step, ok := auth.VerifyTOTP(secret, req.Code, lastStep)
+	if !ok {
+		writeError(w, "Invalid two-factor code", http.StatusUnauthorized)
+		return
+	}
+
+	codes := auth.GenerateRecoveryCodes()
+	if err := h.db.EnableTOTP(user.ID, step, hashRecoveryCodes(codes)); err != nil {
+		writeDBError(w, err, "", "Failed to enable two-factor authentication")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "2fa.enable",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable turns 2FA off after re-checking the password and a current code. OAuth-only
+// accounts have no password to confirm; they send a current code or an unused
+// recovery code alone.
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	_, hashedPassword, err := h.db.GetUserByUsername(user.Username)
+	if err != nil {
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if hashedPassword != "" {
+		if auth.CheckPassword(hashedPassword, req.Password) != nil {
+			writeError(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+		if !h.verifyTOTP(user.ID, req.Code) {
+			writeError(w, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+	} else if !h.verifyTOTP(user.ID, req.Code) && h.db.UseRecoveryCode(user.ID, auth.HashRecoveryCode(req.Code)) != nil {
+		writeError(w, "Invalid two-factor code", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.db.DisableTOTP(user.ID); err != nil {
+		writeDBError(w, err, "", "Failed to disable two-factor authentication")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "2fa.disable",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes invalidates all previous recovery codes and issues a new set
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.verifyTOTP(user.ID, req.Code) {
+		writeError(w, "Invalid two-factor code", http.StatusUnauthorized)
+		return
+	}
+
+	codes := auth.GenerateRecoveryCodes()
+	if err := h.db.ReplaceRecoveryCodes(user.ID, hashRecoveryCodes(codes)); err != nil {
+		writeDBError(w, err, "", "Failed to regenerate recovery codes")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "2fa.recovery_codes",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *TwoFactorHandler) verifyTOTP(userID int, code string) bool {
+	secret, enabled, lastStep, err := h.db.GetTOTP(userID)
+	if err != nil || !enabled {
+		return false
+	}
+
+	step, ok := auth.VerifyTOTP(secret, code, lastStep)
+	if !ok {
+		return false
+	}
+
+	return h.db.UpdateTOTPLastStep(userID, step) == nil
+}
+
+func hashRecoveryCodes(codes []string) []string {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return hashes
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"links/internal/auth"
+	"links/internal/middleware"
+)
+
+// Accounts without a password can turn 2FA off with a code alone; the others must
+// still confirm their password and a current code
+func TestDisableTwoFactor(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string // "" for an OAuth-only account
+		request  func(codes []string) TwoFactorCodeRequest
+		status   int
+	}{
+		{"oauth-only with a recovery code", "", func(codes []string) TwoFactorCodeRequest {
+			return TwoFactorCodeRequest{Code: codes[0]}
+		}, http.StatusNoContent},
+		{"oauth-only with a wrong code", "", func(codes []string) TwoFactorCodeRequest {
+			return TwoFactorCodeRequest{Code: "aaaaa-aaaaa"}
+		}, http.StatusUnauthorized},
+		{"password without the password", "correct horse battery", func(codes []string) TwoFactorCodeRequest {
+			return TwoFactorCodeRequest{Code: codes[0]}
+		}, http.StatusUnauthorized},
+		{"password with a recovery code", "correct horse battery", func(codes []string) TwoFactorCodeRequest {
+			return TwoFactorCodeRequest{Code: codes[0], Password: "correct horse battery"}
+		}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := newTestDB(t)
+			hash := ""
+			if tt.password != "" {
+				var err error
+				if hash, err = auth.HashPassword(tt.password); err != nil {
+					t.Fatal(err)
+				}
+			}
+			id, err := database.CreateUser("alice", hash, time.Now().Format("2006-01-02 15:04:05"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			user, err := database.GetUserByID(int(id))
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := auth.GenerateJWT(user.ID, user.Username, user.IsAdmin, user.SessionVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			codes := auth.GenerateRecoveryCodes()
+			if err := database.SetPendingTOTPSecret(user.ID, auth.GenerateTOTPSecret()); err != nil {
+				t.Fatal(err)
+			}
+			if err := database.EnableTOTP(user.ID, 0, hashRecoveryCodes(codes)); err != nil {
+				t.Fatal(err)
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /api/2fa/disable", middleware.AuthMiddleware(NewTwoFactorHandler(database).Disable))
+			if w := serve(t, mux, "POST", "/api/2fa/disable", token, tt.request(codes)); w.Code != tt.status {
+				t.Fatalf("disable = %d %s, want %d", w.Code, w.Body, tt.status)
+			}
+
+			_, enabled, _, err := database.GetTOTP(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if enabled != (tt.status != http.StatusNoContent) {
+				t.Errorf("2FA enabled = %v after a %d", enabled, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"links/internal/models"
+)
+
+// The /api/v1 endpoints below keep their response shapes stable: lists are pages of
+// items with paging fields, never date-keyed maps. The other /api/v1 routes reuse
+// the unversioned handlers, whose responses are already stable. The contract lives in
+// internal/openapi/openapi.json.
+
+// ListLinksV1 returns a page of the user's links, newest first (?limit=, ?offset=)
+func (h *LinksHandler) ListLinksV1(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+	limit, offset, _, _ := listParams(r.URL.Query())
+
+	links, total, err := h.db.ListLinks(userID, limit, offset)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to load links")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.LinkPage{Links: links, Total: total, Limit: limit, Offset: offset})
+}
+
+// GetLinkV1 returns one of the user's links
+func (h *LinksHandler) GetLinkV1(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.Atoi(r.Header.Get("X-User-ID"))
+	linkID, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid link ID", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.db.GetLink(linkID, userID)
+	if err != nil {
+		writeDBError(w, err, "Link not found", "Failed to get link")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(link)
+}
+
+// ListPublicLinksV1 returns a page of public links, newest first (?limit=, ?offset=)
+func (h *LinksHandler) ListPublicLinksV1(w http.ResponseWriter, r *http.Request) {
+	limit, offset, _, _ := listParams(r.URL.Query())
+
+	links, total, err := h.db.ListPublicLinks(limit, offset)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to load public links")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.LinkPage{Links: links, Total: total, Limit: limit, Offset: offset})
+}
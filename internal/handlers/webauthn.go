@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"links/internal/auth"
+	"links/internal/db"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+const (
+	webAuthnTimeout = 5 * time.Minute
+
+	webAuthnPurposeRegister = "register"
+	webAuthnPurposeLogin    = "login" // Passwordless
+	webAuthnPurposeMFA      = "mfa"   // Second factor after password
+)
+
+type WebAuthnHandler struct {
+	db WebAuthnDBInterface
+}
+
+type WebAuthnDBInterface interface {
+	GetUserByID(userID int) (*models.User, error)
+	RequireAdmin2FA() (bool, error)
+	CreateWebAuthnChallenge(challenge string, userID int, purpose string, ttl time.Duration) error
+	ConsumeWebAuthnChallenge(challenge, purpose string) (int, error)
+	CreateWebAuthnCredential(userID int, credentialID string, publicKey []byte, signCount uint32, name, createdAt string) (int64, error)
+	GetWebAuthnCredential(credentialID string) (*models.WebAuthnCredential, error)
+	GetWebAuthnCredentialsByUserID(userID int) ([]models.WebAuthnCredential, error)
+	UpdateWebAuthnSignCount(id int, signCount uint32) error
+	DeleteWebAuthnCredential(id, userID int) error
+	WriteAudit(entry models.AuditEntry) error
+}
+
+// PublicKeyCredentialJSON is a credential as serialized by the browser, with binary
+// fields base64url-encoded
+type PublicKeyCredentialJSON struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject,omitempty"`
+		AuthenticatorData string `json:"authenticatorData,omitempty"`
+		Signature         string `json:"signature,omitempty"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+type WebAuthnRegisterRequest struct {
+	Name       string                  `json:"name"`
+	Credential PublicKeyCredentialJSON `json:"credential"`
+}
+
+type WebAuthnLoginRequest struct {
+	MFAToken   string                  `json:"mfa_token,omitempty"` // Set when the passkey is a second factor
+	Credential PublicKeyCredentialJSON `json:"credential"`
+}
+
+type credentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+func NewWebAuthnHandler(db WebAuthnDBInterface) *WebAuthnHandler {
+	return &WebAuthnHandler{db: db}
+}
+
+// userHandle is the opaque WebAuthn user.id for a user
+func userHandle(userID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(userID)))
+}
+
+// BeginRegistration returns PublicKeyCredentialCreationOptions for navigator.credentials.create()
+func (h *WebAuthnHandler) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	existing, err := h.db.GetWebAuthnCredentialsByUserID(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to start passkey registration")
+		return
+	}
+
+	challenge := auth.NewWebAuthnChallenge()
+	if err := h.db.CreateWebAuthnChallenge(challenge, user.ID, webAuthnPurposeRegister, webAuthnTimeout); err != nil {
+		writeDBError(w, err, "", "Failed to start passkey registration")
+		return
+	}
+
+	exclude := []credentialDescriptor{}
+	for _, cred := range existing {
+		exclude = append(exclude, credentialDescriptor{Type: "public-key", ID: cred.CredentialID})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"publicKey": map[string]interface{}{
+			"challenge": challenge,
+			"rp": map[string]string{
+				"id":   auth.WebAuthnRPID(),
+				"name": auth.WebAuthnRPName(),
+			},
+			"user": map[string]string{
+				"id":          userHandle(user.ID),
+				"name":        user.Username,
+				"displayName": user.Username,
+			},
+			"pubKeyCredParams": []map[string]interface{}{
+				{"type": "public-key", "alg": auth.COSEAlgES256},
+				{"type": "public-key", "alg": auth.COSEAlgEdDSA},
+				{"type": "public-key", "alg": auth.COSEAlgRS256},
+			},
+			"excludeCredentials": exclude,
+			"authenticatorSelection": map[string]string{
+				"residentKey":      "preferred",
+				"userVerification": "preferred",
+			},
+			"attestation": "none",
+			"timeout":     webAuthnTimeout.Milliseconds(),
+		},
+	})
+}
+
+func (h *WebAuthnHandler) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	var req WebAuthnRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	clientDataJSON, err1 := auth.DecodeWebAuthnBase64(req.Credential.Response.ClientDataJSON)
+	attestationObject, err2 := auth.DecodeWebAuthnBase64(req.Credential.Response.AttestationObject)
+	if err1 != nil || err2 != nil {
+		writeError(w, "Invalid credential encoding", http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := auth.ClientDataChallenge(clientDataJSON)
+	if err != nil {
+		writeError(w, "Invalid client data", http.StatusBadRequest)
+		return
+	}
+
+	challengeUserID, err := h.db.ConsumeWebAuthnChallenge(challenge, webAuthnPurposeRegister)
+	if err != nil || challengeUserID != user.ID {
+		writeError(w, "Unknown or expired registration challenge", http.StatusBadRequest)
+		return
+	}
+
+	cred, err := auth.VerifyRegistration(clientDataJSON, attestationObject, challenge)
+	if err != nil {
+		log.Printf("Passkey registration failed for user %d: %v", user.ID, err)
+		writeError(w, "Passkey registration could not be verified", http.StatusBadRequest)
+		return
+	}
+
+	name := middleware.Sanitizer.SanitizeText(req.Name)
+	if len(name) > 50 {
+		name = name[:50]
+	}
+	if name == "" {
+		name = "Passkey"
+	}
+
+	credentialID := base64.RawURLEncoding.EncodeToString(cred.ID)
+	createdAt := time.Now().Format("2006-01-02 15:04:05")
+	id, err := h.db.CreateWebAuthnCredential(user.ID, credentialID, cred.PublicKey, cred.SignCount, name, createdAt)
+	if errors.Is(err, db.ErrConflict) {
+		writeError(w, "Passkey is already registered", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		writeDBError(w, err, "", "Failed to save passkey")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "passkey.register",
+		TargetType: "passkey",
+		TargetID:   strconv.FormatInt(id, 10),
+		After:      auditValue(map[string]string{"name": name}),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.WebAuthnCredential{
+		ID:           int(id),
+		UserID:       user.ID,
+		CredentialID: credentialID,
+		Name:         name,
+		CreatedAt:    createdAt,
+	})
+}
+
+func (h *WebAuthnHandler) GetCredentials(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	creds, err := h.db.GetWebAuthnCredentialsByUserID(user.ID)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get passkeys")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(creds)
+}
+
+func (h *WebAuthnHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUserFromContext(r.Context())
+
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid passkey ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.DeleteWebAuthnCredential(id, user.ID)
+	if err != nil {
+		writeDBError(w, err, "Passkey not found", "Failed to delete passkey")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "passkey.delete",
+		TargetType: "passkey",
+		TargetID:   strconv.Itoa(id),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BeginLogin returns PublicKeyCredentialRequestOptions. With an mfa_token from
+// /api/login the passkey is a second factor; without one it is a passwordless login
+// using a discoverable credential.
+func (h *WebAuthnHandler) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MFAToken string `json:"mfa_token"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	userID := 0
+	purpose := webAuthnPurposeLogin
+	userVerification := "required"
+	allow := []credentialDescriptor{}
+
+	if req.MFAToken != "" {
+		claims, err := auth.ValidateMFAToken(req.MFAToken)
+		if err != nil {
+			writeError(w, "Invalid or expired two-factor session", http.StatusUnauthorized)
+			return
+		}
+
+		creds, err := h.db.GetWebAuthnCredentialsByUserID(claims.UserID)
+		if err != nil {
+			writeDBError(w, err, "", "Failed to start passkey login")
+			return
+		}
+		if len(creds) == 0 {
+			writeError(w, "No passkeys registered", http.StatusBadRequest)
+			return
+		}
+		for _, cred := range creds {
+			allow = append(allow, credentialDescriptor{Type: "public-key", ID: cred.CredentialID})
+		}
+
+		userID = claims.UserID
+		purpose = webAuthnPurposeMFA
+		userVerification = "preferred"
+	}
+
+	challenge := auth.NewWebAuthnChallenge()
+	if err := h.db.CreateWebAuthnChallenge(challenge, userID, purpose, webAuthnTimeout); err != nil {
+		writeDBError(w, err, "", "Failed to start passkey login")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"publicKey": map[string]interface{}{
+			"challenge":        challenge,
+			"rpId":             auth.WebAuthnRPID(),
+			"allowCredentials": allow,
+			"userVerification": userVerification,
+			"timeout":          webAuthnTimeout.Milliseconds(),
+		},
+	})
+}
+
+func (h *WebAuthnHandler) FinishLogin(w http.ResponseWriter, r *http.Request) {
+	var req WebAuthnLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	purpose := webAuthnPurposeLogin
+	mfaUserID := 0
+	if req.MFAToken != "" {
+		claims, err := auth.ValidateMFAToken(req.MFAToken)
+		if err != nil {
+			writeError(w, "Invalid or expired two-factor session", http.StatusUnauthorized)
+			return
+		}
+		purpose = webAuthnPurposeMFA
+		mfaUserID = claims.UserID
+	}
+
+	rawID, err := auth.DecodeWebAuthnBase64(req.Credential.RawID)
+	clientDataJSON, err1 := auth.DecodeWebAuthnBase64(req.Credential.Response.ClientDataJSON)
+	authenticatorData, err2 := auth.DecodeWebAuthnBase64(req.Credential.Response.AuthenticatorData)
+	signature, err3 := auth.DecodeWebAuthnBase64(req.Credential.Response.Signature)
+	if err != nil || err1 != nil || err2 != nil || err3 != nil {
+		writeError(w, "Invalid credential encoding", http.StatusBadRequest)
+		return
+	}
+
+	challenge, err := auth.ClientDataChallenge(clientDataJSON)
+	if err != nil {
+		writeError(w, "Invalid client data", http.StatusBadRequest)
+		return
+	}
+
+	challengeUserID, err := h.db.ConsumeWebAuthnChallenge(challenge, purpose)
+	if err != nil || challengeUserID != mfaUserID {
+		writeError(w, "Unknown or expired login challenge", http.StatusUnauthorized)
+		return
+	}
+
+	cred, err := h.db.GetWebAuthnCredential(base64.RawURLEncoding.EncodeToString(rawID))
+	if err != nil {
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if purpose == webAuthnPurposeMFA && cred.UserID != mfaUserID {
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	if handle := req.Credential.Response.UserHandle; handle != "" && strings.TrimRight(handle, "=") != userHandle(cred.UserID) {
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	signCount, err := auth.VerifyAssertion(clientDataJSON, authenticatorData, signature, challenge, cred.PublicKey, cred.SignCount, purpose == webAuthnPurposeLogin)
+	if err != nil {
+		log.Printf("Passkey assertion failed for credential %d: %v", cred.ID, err)
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.db.UpdateWebAuthnSignCount(cred.ID, signCount); err != nil {
+		writeDBError(w, err, "", "Failed to complete login")
+		return
+	}
+
+	user, err := h.db.GetUserByID(cred.UserID)
+	if err != nil {
+		writeError(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+
+	if rejectIfSuspended(w, user) {
+		return
+	}
+
+	writeSession(w, r, h.db, user, "webauthn")
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"links/internal/auth"
+	"links/internal/auth/webauthntest"
+	"links/internal/middleware"
+	"links/internal/models"
+)
+
+type webAuthnOptions struct {
+	PublicKey struct {
+		Challenge        string `json:"challenge"`
+		UserVerification string `json:"userVerification"`
+		User             struct {
+			ID string `json:"id"`
+		} `json:"user"`
+	} `json:"publicKey"`
+}
+
+func newWebAuthnTest(t *testing.T) (*WebAuthnHandler, *models.User, string) {
+	t.Helper()
+	t.Setenv("WEBAUTHN_RP_ID", "links.test")
+	t.Setenv("WEBAUTHN_ORIGINS", "https://links.test")
+	auth.InitWebAuthn()
+
+	database := newTestDB(t)
+	user, token := createTestUser(t, database, "alice")
+	return NewWebAuthnHandler(database), user, token
+}
+
+// registerPasskey runs both registration steps with a and returns the finish response
+func registerPasskey(t *testing.T, h *WebAuthnHandler, token string, a *webauthntest.Authenticator) int {
+	t.Helper()
+	w := serve(t, middleware.AuthMiddleware(h.BeginRegistration), "POST", "/api/webauthn/register/begin", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("register/begin = %d %s", w.Code, w.Body)
+	}
+	var options webAuthnOptions
+	decode(t, w, &options)
+	a.UserHandle = options.PublicKey.User.ID
+
+	w = serve(t, middleware.AuthMiddleware(h.FinishRegistration), "POST", "/api/webauthn/register/finish", token, map[string]interface{}{
+		"name":       "Test key",
+		"credential": a.CreateCredential(options.PublicKey.Challenge),
+	})
+	return w.Code
+}
+
+// beginLogin starts a passkey login, as a second factor when mfaToken is set
+func beginLogin(t *testing.T, h *WebAuthnHandler, mfaToken string) webAuthnOptions {
+	t.Helper()
+	var body interface{}
+	if mfaToken != "" {
+		body = map[string]string{"mfa_token": mfaToken}
+	}
+	w := serve(t, http.HandlerFunc(h.BeginLogin), "POST", "/api/webauthn/login/begin", "", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login/begin = %d %s", w.Code, w.Body)
+	}
+	var options webAuthnOptions
+	decode(t, w, &options)
+	return options
+}
+
+func finishLogin(t *testing.T, h *WebAuthnHandler, mfaToken string, credential webauthntest.Credential) (int, models.AuthResponse) {
+	t.Helper()
+	w := serve(t, http.HandlerFunc(h.FinishLogin), "POST", "/api/webauthn/login/finish", "", map[string]interface{}{
+		"mfa_token":  mfaToken,
+		"credential": credential,
+	})
+	var response models.AuthResponse
+	if w.Code == http.StatusOK {
+		decode(t, w, &response)
+	}
+	return w.Code, response
+}
+
+func TestWebAuthnPasswordlessLogin(t *testing.T) {
+	h, user, token := newWebAuthnTest(t)
+	a := webauthntest.New("links.test", "https://links.test")
+	if code := registerPasskey(t, h, token, a); code != http.StatusOK {
+		t.Fatalf("register/finish = %d", code)
+	}
+
+	options := beginLogin(t, h, "")
+	if options.PublicKey.UserVerification != "required" {
+		t.Errorf("passwordless userVerification = %q, want required", options.PublicKey.UserVerification)
+	}
+	credential := a.GetCredential(options.PublicKey.Challenge)
+	code, response := finishLogin(t, h, "", credential)
+	if code != http.StatusOK || response.User.ID != user.ID || response.Token == "" {
+		t.Fatalf("login/finish = %d, user %d", code, response.User.ID)
+	}
+	if _, err := auth.ValidateJWT(response.Token); err != nil {
+		t.Errorf("issued token is not a session token: %v", err)
+	}
+
+	// The challenge is single use
+	if code, _ := finishLogin(t, h, "", credential); code != http.StatusUnauthorized {
+		t.Errorf("replayed assertion = %d, want 401", code)
+	}
+}
+
+func TestWebAuthnSecondFactorLogin(t *testing.T) {
+	h, user, token := newWebAuthnTest(t)
+	a := webauthntest.New("links.test", "https://links.test")
+	a.UserVerified = false // A security key without a PIN is fine as a second factor
+	if code := registerPasskey(t, h, token, a); code != http.StatusOK {
+		t.Fatalf("register/finish = %d", code)
+	}
+
+	mfaToken, _ := auth.GenerateMFAToken(user.ID, user.Username)
+	options := beginLogin(t, h, mfaToken)
+	code, response := finishLogin(t, h, mfaToken, a.GetCredential(options.PublicKey.Challenge))
+	if code != http.StatusOK || response.User.ID != user.ID {
+		t.Fatalf("login/finish = %d, user %d", code, response.User.ID)
+	}
+
+	// Without UV the same key cannot log in on its own
+	options = beginLogin(t, h, "")
+	if code, _ := finishLogin(t, h, "", a.GetCredential(options.PublicKey.Challenge)); code != http.StatusUnauthorized {
+		t.Errorf("passwordless login without UV = %d, want 401", code)
+	}
+
+	// A second-factor challenge cannot be finished as a passwordless login
+	options = beginLogin(t, h, mfaToken)
+	if code, _ := finishLogin(t, h, "", a.GetCredential(options.PublicKey.Challenge)); code != http.StatusUnauthorized {
+		t.Errorf("MFA challenge finished without mfa_token = %d, want 401", code)
+	}
+}
+
+func TestWebAuthnLoginRejected(t *testing.T) {
+	h, _, token := newWebAuthnTest(t)
+	a := webauthntest.New("links.test", "https://links.test")
+	if code := registerPasskey(t, h, token, a); code != http.StatusOK {
+		t.Fatalf("register/finish = %d", code)
+	}
+
+	t.Run("bad challenge", func(t *testing.T) {
+		beginLogin(t, h, "")
+		if code, _ := finishLogin(t, h, "", a.GetCredential(auth.NewWebAuthnChallenge())); code != http.StatusUnauthorized {
+			t.Errorf("unknown challenge = %d, want 401", code)
+		}
+	})
+
+	t.Run("bad origin", func(t *testing.T) {
+		options := beginLogin(t, h, "")
+		a.Origin = "https://evil.test"
+		defer func() { a.Origin = "https://links.test" }()
+		if code, _ := finishLogin(t, h, "", a.GetCredential(options.PublicKey.Challenge)); code != http.StatusUnauthorized {
+			t.Errorf("wrong origin = %d, want 401", code)
+		}
+	})
+
+	t.Run("sign count regression", func(t *testing.T) {
+		options := beginLogin(t, h, "")
+		if code, _ := finishLogin(t, h, "", a.GetCredential(options.PublicKey.Challenge)); code != http.StatusOK {
+			t.Fatalf("login = %d", code)
+		}
+		// A clone of the authenticator still holds the older counter
+		a.SignCount -= 2
+		options = beginLogin(t, h, "")
+		if code, _ := finishLogin(t, h, "", a.GetCredential(options.PublicKey.Challenge)); code != http.StatusUnauthorized {
+			t.Errorf("regressed counter = %d, want 401", code)
+		}
+	})
+
+	t.Run("malformed authenticator data", func(t *testing.T) {
+		options := beginLogin(t, h, "")
+		credential := a.GetCredential(options.PublicKey.Challenge)
+		credential.Response["authenticatorData"] = credential.Response["authenticatorData"][:20]
+		if code, _ := finishLogin(t, h, "", credential); code != http.StatusUnauthorized {
+			t.Errorf("truncated authenticator data = %d, want 401", code)
+		}
+	})
+}
+
+func TestWebAuthnRegistrationRejected(t *testing.T) {
+	h, _, token := newWebAuthnTest(t)
+
+	t.Run("bad origin", func(t *testing.T) {
+		a := webauthntest.New("links.test", "https://evil.test")
+		if code := registerPasskey(t, h, token, a); code != http.StatusBadRequest {
+			t.Errorf("register/finish from another origin = %d, want 400", code)
+		}
+	})
+
+	t.Run("malformed attestation", func(t *testing.T) {
+		a := webauthntest.New("links.test", "https://links.test")
+		w := serve(t, middleware.AuthMiddleware(h.BeginRegistration), "POST", "/api/webauthn/register/begin", token, nil)
+		var options webAuthnOptions
+		decode(t, w, &options)
+
+		credential := a.CreateCredential(options.PublicKey.Challenge)
+		credential.Response["attestationObject"] = credential.Response["attestationObject"][:40]
+		w = serve(t, middleware.AuthMiddleware(h.FinishRegistration), "POST", "/api/webauthn/register/finish", token, map[string]interface{}{
+			"credential": credential,
+		})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("truncated attestation object = %d, want 400", w.Code)
+		}
+	})
+
+	t.Run("duplicate credential", func(t *testing.T) {
+		a := webauthntest.New("links.test", "https://links.test")
+		if code := registerPasskey(t, h, token, a); code != http.StatusOK {
+			t.Fatalf("first registration = %d", code)
+		}
+		if code := registerPasskey(t, h, token, a); code != http.StatusConflict {
+			t.Errorf("second registration of the same credential = %d, want 409", code)
+		}
+	})
+}
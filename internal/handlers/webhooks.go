@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"links/internal/auth"
+	"links/internal/middleware"
+	"links/internal/models"
+	"links/internal/safehttp"
+	"links/internal/webhooks"
+)
+
+// maxWebhooks caps how many webhooks a user, or the site, may have
+const maxWebhooks = 10
+
+// webhookQueue is the subset of the database needed to queue link events
+type webhookQueue interface {
+	GetLinksByIDs(ids []int) ([]models.Link, error)
+	EnqueueWebhookEvent(event string, ownerID int, public bool, payload string) (int64, error)
+}
+
+// emitLinkEvent queues event for the owner's webhooks and, for public links, the
+// site-wide ones, and pushes it to connected clients the same way. Deleted links are
+// described as they were when trashed. Failures are logged, not surfaced.
+func emitLinkEvent(store webhookQueue, event string, ids ...int) {
+	links, err := store.GetLinksByIDs(ids)
+	if err != nil {
+		log.Printf("Failed to load links for webhook event %q: %v", event, err)
+		return
+	}
+	for i := range links {
+		link := &links[i]
+		publishLinkEvent(event, link)
+		payload := auditValue(models.WebhookPayload{Event: event, CreatedAt: time.Now().Format(time.RFC3339), Link: link})
+		if _, err := store.EnqueueWebhookEvent(event, link.UserID, !link.IsPrivate, payload); err != nil {
+			log.Printf("Failed to queue webhook event %q for link %d: %v", event, link.ID, err)
+		}
+	}
+}
+
+// WebhooksHandler manages either the signed-in user's webhooks or, when siteWide is
+// set, the admin-managed webhooks that receive events for all public links
+type WebhooksHandler struct {
+	db         WebhooksDBInterface
+	dispatcher *webhooks.Dispatcher
+	siteWide   bool
+}
+
+type WebhooksDBInterface interface {
+	GetWebhooks(ownerID int) ([]models.Webhook, error)
+	GetWebhook(id, ownerID int) (*models.Webhook, error)
+	CountWebhooks(ownerID int) (int, error)
+	CreateWebhook(ownerID int, url, secret string, events []string) (*models.Webhook, error)
+	UpdateWebhook(id, ownerID int, url string, events []string, active bool) error
+	DeleteWebhook(id, ownerID int) error
+	GetWebhookDeliveries(webhookID, limit int) ([]models.WebhookDelivery, error)
+	CreateWebhookDelivery(webhookID int, event, payload string, claimUntil time.Time) (*models.WebhookDelivery, error)
+	WriteAudit(entry models.AuditEntry) error
+}
+
+func NewWebhooksHandler(db WebhooksDBInterface, dispatcher *webhooks.Dispatcher, siteWide bool) *WebhooksHandler {
+	return &WebhooksHandler{db: db, dispatcher: dispatcher, siteWide: siteWide}
+}
+
+// owner returns the owner ID webhooks are scoped to; 0 for site-wide webhooks
+func (h *WebhooksHandler) owner(r *http.Request) int {
+	if h.siteWide {
+		return 0
+	}
+	return middleware.GetUserFromContext(r.Context()).ID
+}
+
+// validWebhook normalizes and checks a webhook's URL and events
+func validWebhook(rawURL string, events []string) (string, []string, string) {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || len(rawURL) > 2048 || safehttp.ValidateURL(parsed) != nil {
+		return "", nil, "URL must be an http or https address without credentials"
+	}
+
+	known := map[string]bool{}
+	for _, event := range models.WebhookEvents {
+		known[event] = true
+	}
+	var valid []string
+	seen := map[string]bool{}
+	for _, event := range events {
+		if !known[event] {
+			return "", nil, "Unknown event " + strconv.Quote(event) + "; use " + strings.Join(models.WebhookEvents, ", ")
+		}
+		if !seen[event] {
+			seen[event] = true
+			valid = append(valid, event)
+		}
+	}
+	if len(valid) == 0 {
+		return "", nil, "Subscribe to at least one of " + strings.Join(models.WebhookEvents, ", ")
+	}
+	return parsed.String(), valid, ""
+}
+
+func (h *WebhooksHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.db.GetWebhooks(h.owner(r))
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get webhooks")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hooks)
+}
+
+// CreateWebhook registers a webhook and returns its signing secret, which is not
+// shown again
+func (h *WebhooksHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	owner := h.owner(r)
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	hookURL, events, problem := validWebhook(req.URL, req.Events)
+	if problem != "" {
+		writeError(w, problem, http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.db.CountWebhooks(owner)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to create webhook")
+		return
+	}
+	if count >= maxWebhooks {
+		writeError(w, "Too many webhooks; delete one first", http.StatusConflict)
+		return
+	}
+
+	secret, _ := auth.GenerateOpaqueToken()
+	hook, err := h.db.CreateWebhook(owner, hookURL, "whsec_"+secret, events)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to create webhook")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "webhook.create",
+		TargetType: "webhook",
+		TargetID:   strconv.Itoa(hook.ID),
+		After:      auditValue(map[string]interface{}{"url": hook.URL, "events": hook.Events, "site_wide": h.siteWide}),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+// UpdateWebhook replaces the URL and events and, when given, the active flag
+func (h *WebhooksHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	owner := h.owner(r)
+
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	var req models.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.db.GetWebhook(id, owner)
+	if err != nil {
+		writeDBError(w, err, "Webhook not found", "Failed to get webhook")
+		return
+	}
+	if req.URL == "" {
+		req.URL = before.URL
+	}
+	if req.Events == nil {
+		req.Events = before.Events
+	}
+	active := before.Active
+	if req.Active != nil {
+		active = *req.Active
+	}
+	hookURL, events, problem := validWebhook(req.URL, req.Events)
+	if problem != "" {
+		writeError(w, problem, http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.UpdateWebhook(id, owner, hookURL, events, active)
+	if err != nil {
+		writeDBError(w, err, "Webhook not found", "Failed to update webhook")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "webhook.update",
+		TargetType: "webhook",
+		TargetID:   strconv.Itoa(id),
+		Before:     auditValue(map[string]interface{}{"url": before.URL, "events": before.Events, "active": before.Active}),
+		After:      auditValue(map[string]interface{}{"url": hookURL, "events": events, "active": active}),
+	})
+
+	hook, err := h.db.GetWebhook(id, owner)
+	if err != nil {
+		writeDBError(w, err, "Webhook not found", "Failed to get webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hook)
+}
+
+func (h *WebhooksHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.db.DeleteWebhook(id, h.owner(r))
+	if err != nil {
+		writeDBError(w, err, "Webhook not found", "Failed to delete webhook")
+		return
+	}
+
+	recordAudit(h.db, r, models.AuditEntry{
+		Action:     "webhook.delete",
+		TargetType: "webhook",
+		TargetID:   strconv.Itoa(id),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries returns the webhook's delivery log, newest first (?limit=, default 50)
+func (h *WebhooksHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.db.GetWebhook(id, h.owner(r)); err != nil {
+		writeDBError(w, err, "Webhook not found", "Failed to get webhook")
+		return
+	}
+
+	limit, _, _, _ := listParams(r.URL.Query())
+	deliveries, err := h.db.GetWebhookDeliveries(id, limit)
+	if err != nil {
+		writeDBError(w, err, "", "Failed to get deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// TestWebhook sends a ping right away, whether or not the webhook is active, and
+// returns the logged delivery. Pings are not retried.
+func (h *WebhooksHandler) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, "Invalid webhook ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.db.GetWebhook(id, h.owner(r)); err != nil {
+		writeDBError(w, err, "Webhook not found", "Failed to get webhook")
+		return
+	}
+
+	payload := auditValue(models.WebhookPayload{Event: models.EventPing, CreatedAt: time.Now().Format(time.RFC3339)})
+	delivery, err := h.db.CreateWebhookDelivery(id, models.EventPing, payload, time.Now().Add(time.Hour))
+	if err != nil {
+		writeDBError(w, err, "", "Failed to queue test delivery")
+		return
+	}
+	h.dispatcher.Deliver(delivery, false)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
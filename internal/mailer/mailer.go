@@ -0,0 +1,116 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns the mailer selected by MAILER ("smtp" or "log", default "log")
+func New() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "links@localhost"
+	}
+
+	if os.Getenv("MAILER") == "smtp" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+
+	return &LogMailer{Path: os.Getenv("MAIL_LOG_FILE"), From: from}
+}
+
+// SMTPMailer sends through an SMTP server, upgrading with STARTTLS when offered
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(m.Host+":"+m.Port, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// LogMailer writes messages to a file (or the server log when Path is empty) so
+// reset and verification links can be followed during local development
+type LogMailer struct {
+	Path string
+	From string
+	mu   sync.Mutex
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	if m.Path == "" {
+		log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(format(m.From, msg), "\r\n.\r\n"...))
+	return err
+}
+
+func format(from string, msg Message) []byte {
+	// Strip CR/LF from header values to prevent header injection
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", clean.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", clean.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", clean.Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// SendAsync delivers in the background so request latency does not reveal whether
+// an address exists; failures are logged
+func SendAsync(m Mailer, msg Message) {
+	go func() {
+		if err := m.Send(msg); err != nil {
+			log.Printf("Failed to send mail to %s: %v", msg.To, err)
+		}
+	}()
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"links/internal/auth"
+	"links/internal/models"
+)
+
+// apiTokenPrefixes are the only endpoints personal API tokens may call: saving,
+// managing and watching links. Account, security and admin endpoints need a
+// signed-in session.
+var apiTokenPrefixes = []string{"/api/links", "/api/v1/", "/api/quick-save", "/api/metadata", "/api/events"}
+
+// authenticateAPIToken resolves a personal API token to its owner. The user always
+// gets the plain user role, whatever their account's role, so a leaked token can
+// never reach admin endpoints. It writes an error response and returns false when
+// the request is refused.
+func authenticateAPIToken(w http.ResponseWriter, r *http.Request, token string) (*models.User, bool) {
+	if sessionStore == nil {
+		WriteError(w, "Invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	allowed := false
+	for _, prefix := range apiTokenPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		WriteError(w, "API tokens cannot be used for this endpoint", http.StatusForbidden)
+		return nil, false
+	}
+
+	current, err := sessionStore.GetUserByAPIToken(auth.HashToken(token))
+	if err != nil {
+		WriteError(w, "Invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+	if current.IsSuspended() {
+		WriteError(w, "This account has been suspended", http.StatusForbidden)
+		return nil, false
+	}
+
+	return &models.User{
+		ID:       current.ID,
+		Username: current.Username,
+		Role:     auth.RoleUser,
+	}, true
+}
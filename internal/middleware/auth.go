@@ -10,41 +10,115 @@ import (
 	"links/internal/models"
 )
 
+// SessionStore looks up the current state of a token's user so revoked sessions and
+// deleted or suspended accounts are rejected even while the token is unexpired
+type SessionStore interface {
+	GetUserByID(userID int) (*models.User, error)
+	RequireAdmin2FA() (bool, error)
+	WriteAudit(entry models.AuditEntry) error
+	GetUserByAPIToken(tokenHash string) (*models.User, error)
+}
+
+var sessionStore SessionStore
+
+// SetSessionStore enables per-request session checks against the database
+func SetSessionStore(store SessionStore) {
+	sessionStore = store
+}
+
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			WriteError(w, "Authorization header required", http.StatusUnauthorized)
 			return
 		}
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+			WriteError(w, "Invalid authorization header format", http.StatusUnauthorized)
+			return
+		}
+
+		if strings.HasPrefix(parts[1], auth.APITokenPrefix) {
+			if user, ok := authenticateAPIToken(w, r, parts[1]); ok {
+				next(w, withUser(r, user))
+			}
 			return
 		}
 
 		claims, err := auth.ValidateJWT(parts[1])
 		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			WriteError(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		r.Header.Set("X-User-ID", strconv.Itoa(claims.UserID))
-		r.Header.Set("X-Username", claims.Username)
-		r.Header.Set("X-Is-Admin", strconv.FormatBool(claims.IsAdmin))
+		var current *models.User
+		if sessionStore != nil {
+			current, err = sessionStore.GetUserByID(claims.UserID)
+			if err != nil || current.SessionVersion != claims.SessionVersion {
+				WriteError(w, "Session expired", http.StatusUnauthorized)
+				return
+			}
+			if current.IsSuspended() {
+				WriteError(w, "This account has been suspended", http.StatusForbidden)
+				return
+			}
+
+			// Prefer current account state over what was true when the token was issued
+			claims.Username = current.Username
+			claims.IsAdmin = current.IsAdmin
+		}
 
 		// Add user to context for handlers
 		user := &models.User{
 			ID:       claims.UserID,
 			Username: claims.Username,
 			IsAdmin:  claims.IsAdmin,
+			Role:     auth.RoleUser,
+		}
+		if current != nil {
+			user.Role = current.Role
+			user.TOTPEnabled = current.TOTPEnabled
+			user.PasskeyEnabled = current.PasskeyEnabled
+		} else if claims.IsAdmin {
+			user.Role = auth.RoleAdmin
 		}
-		ctx := context.WithValue(r.Context(), "user", user)
-		next(w, r.WithContext(ctx))
+		if claims.ImpersonatorID != 0 && !checkImpersonation(w, r, claims, user) {
+			return
+		}
+
+		next(w, withUser(r, user))
 	}
 }
 
+// OptionalAuth authenticates requests that carry an Authorization header like
+// AuthMiddleware, and passes anonymous ones through without a user in the context
+func OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	authed := AuthMiddleware(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			// Handlers read these as set by withUser; never trust client-sent copies
+			r.Header.Del("X-User-ID")
+			r.Header.Del("X-Username")
+			r.Header.Del("X-Is-Admin")
+			next(w, r)
+			return
+		}
+		authed(w, r)
+	}
+}
+
+// withUser hands the authenticated user to handlers through headers and the context
+func withUser(r *http.Request, user *models.User) *http.Request {
+	r.Header.Set("X-User-ID", strconv.Itoa(user.ID))
+	r.Header.Set("X-Username", user.Username)
+	r.Header.Set("X-Is-Admin", strconv.FormatBool(user.IsAdmin))
+
+	ctx := context.WithValue(r.Context(), "user", user)
+	return r.WithContext(ctx)
+}
+
 func GetUserFromContext(ctx context.Context) *models.User {
 	user, ok := ctx.Value("user").(*models.User)
 	if !ok {
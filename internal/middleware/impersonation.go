@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"links/internal/auth"
+	"links/internal/models"
+)
+
+// ImpersonationEndPath ends an impersonation session. It is the one write a
+// read-only impersonation token may make.
+const ImpersonationEndPath = "/api/impersonation/end"
+
+// impersonationBlockedPaths are account, security, webhook and admin endpoints that stay
+// off-limits to impersonation tokens even when writes are allowed. Each covers the
+// path itself and everything below it, so "/api/me" does not block "/api/metadata".
+var impersonationBlockedPaths = []string{"/api/me", "/api/2fa", "/api/webauthn", "/api/email", "/api/auth", "/api/admin", "/api/webhooks"}
+
+// impersonationBlocked reports whether path is one of impersonationBlockedPaths or below one
+func impersonationBlocked(path string) bool {
+	for _, blocked := range impersonationBlockedPaths {
+		if path == blocked || strings.HasPrefix(path, blocked+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkImpersonation verifies that the admin behind an impersonation token may
+// still impersonate, applies the session's restrictions and audits every write.
+// It writes an error response and returns false when the request is refused.
+func checkImpersonation(w http.ResponseWriter, r *http.Request, claims *models.JWTClaims, user *models.User) bool {
+	if sessionStore == nil {
+		WriteError(w, "Impersonation is not available", http.StatusUnauthorized)
+		return false
+	}
+	admin, err := sessionStore.GetUserByID(claims.ImpersonatorID)
+	if err != nil || admin.IsSuspended() || !auth.HasPermission(admin.Role, auth.PermUsersImpersonate) {
+		WriteError(w, "Impersonation session expired", http.StatusUnauthorized)
+		return false
+	}
+
+	if !(r.URL.Path == "/api/me" && r.Method == "GET") && impersonationBlocked(r.URL.Path) {
+		WriteError(w, "Not available while viewing as another user", http.StatusForbidden)
+		return false
+	}
+
+	write := r.Method != "GET" && r.Method != "HEAD"
+	if write && claims.ReadOnly && r.URL.Path != ImpersonationEndPath {
+		WriteError(w, "This impersonation session is read-only", http.StatusForbidden)
+		return false
+	}
+
+	// Never grant the impersonated session any staff permissions
+	user.Role = auth.RoleUser
+	user.IsAdmin = false
+	claims.IsAdmin = false
+	user.ImpersonatorID = admin.ID
+	user.ImpersonatorUsername = admin.Username
+	user.ImpersonationID = claims.ImpersonationID
+
+	if write && r.URL.Path != ImpersonationEndPath {
+		after, _ := json.Marshal(map[string]string{
+			"session": claims.ImpersonationID, "method": r.Method, "path": r.URL.Path,
+		})
+		err := sessionStore.WriteAudit(models.AuditEntry{
+			ActorID:       admin.ID,
+			ActorUsername: admin.Username,
+			Action:        "impersonation.request",
+			TargetType:    "user",
+			TargetID:      strconv.Itoa(user.ID),
+			After:         string(after),
+			IP:            ClientIP(r),
+			UserAgent:     r.UserAgent(),
+		})
+		if err != nil {
+			log.Printf("Failed to record audit entry %q: %v", "impersonation.request", err)
+		}
+	}
+	return true
+}
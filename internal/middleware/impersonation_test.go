@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"links/internal/auth"
+	"links/internal/models"
+)
+
+// memoryStore serves fixed users in place of the database
+type memoryStore struct {
+	users map[int]*models.User
+}
+
+func (s *memoryStore) GetUserByID(userID int) (*models.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	clone := *user
+	return &clone, nil
+}
+
+func (s *memoryStore) RequireAdmin2FA() (bool, error) { return false, nil }
+
+func (s *memoryStore) WriteAudit(entry models.AuditEntry) error { return nil }
+
+func (s *memoryStore) GetUserByAPIToken(tokenHash string) (*models.User, error) {
+	return nil, sql.ErrNoRows
+}
+
+// Impersonation tokens are kept off account and security endpoints, and only those
+func TestImpersonationBlockedPaths(t *testing.T) {
+	SetSessionStore(&memoryStore{users: map[int]*models.User{
+		1: {ID: 1, Username: "admin", IsAdmin: true, Role: auth.RoleAdmin},
+		2: {ID: 2, Username: "alice", Role: auth.RoleUser},
+	}})
+	defer SetSessionStore(nil)
+	token, _, err := auth.GenerateImpersonationToken(2, "alice", 0, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{"GET", "/api/me", http.StatusOK},
+		{"PUT", "/api/me", http.StatusForbidden},
+		{"GET", "/api/me/tokens", http.StatusForbidden},
+		{"PUT", "/api/me/password", http.StatusForbidden},
+		{"GET", "/api/metadata", http.StatusOK},
+		{"GET", "/api/links", http.StatusOK},
+		{"POST", "/api/links", http.StatusOK},
+		{"POST", "/api/2fa/disable", http.StatusForbidden},
+		{"GET", "/api/webhooks", http.StatusForbidden},
+		{"DELETE", "/api/webhooks/3", http.StatusForbidden},
+		{"GET", "/api/admin/users", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
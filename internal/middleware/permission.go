@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"links/internal/auth"
+)
+
+// RequirePermission rejects requests whose user's role does not grant perm. It must run
+// after AuthMiddleware. When the admin 2FA policy is on, any staff role must also have
+// enrolled TOTP or a passkey.
+func RequirePermission(perm auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := GetUserFromContext(r.Context())
+		if user == nil || !auth.HasPermission(user.Role, perm) {
+			WriteError(w, "Insufficient permissions", http.StatusForbidden)
+			return
+		}
+
+		if sessionStore != nil {
+			required, err := sessionStore.RequireAdmin2FA()
+			if err != nil {
+				WriteError(w, "Failed to check admin policy", http.StatusInternalServerError)
+				return
+			}
+			if required && !user.HasSecondFactor() {
+				WriteError(w, "Two-factor authentication is required for staff accounts", http.StatusForbidden)
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
@@ -100,20 +100,14 @@ func (rl *RateLimiter) cleanupVisitors() {
 
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Get client IP
-		ip := r.Header.Get("X-Forwarded-For")
-		if ip == "" {
-			ip = r.Header.Get("X-Real-IP")
-		}
-		if ip == "" {
-			ip = r.RemoteAddr
-		}
+		// Get client IP (forwarding headers only count from trusted proxies)
+		ip := ClientIP(r)
 
 		// Get limiter for this IP
 		limiter := rl.GetVisitor(ip)
 		
 		if !limiter.Allow() {
-			http.Error(w, "Rate limit exceeded. Please try again later.", http.StatusTooManyRequests)
+			WriteError(w, "Rate limit exceeded. Please try again later.", http.StatusTooManyRequests)
 			return
 		}
 
@@ -0,0 +1,15 @@
+package models
+
+// APIToken is a personal access token. The secret is only returned once, when the
+// token is created.
+type APIToken struct {
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	CreatedAt  string  `json:"created_at"`
+	LastUsedAt *string `json:"last_used_at"`
+}
+
+type CreateAPITokenResponse struct {
+	APIToken
+	Token string `json:"token"`
+}
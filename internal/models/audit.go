@@ -0,0 +1,36 @@
+package models
+
+// AuditEntry is one row of the append-only audit log. Before and After hold JSON
+// snapshots of the changed values and are empty when not applicable.
+type AuditEntry struct {
+	ID            int    `json:"id"`
+	ActorID       int    `json:"actor_id,omitempty"` // 0 for anonymous actions such as failed logins
+	ActorUsername string `json:"actor_username"`
+	Action        string `json:"action"`
+	TargetType    string `json:"target_type"`
+	TargetID      string `json:"target_id"`
+	Before        string `json:"before,omitempty"`
+	After         string `json:"after,omitempty"`
+	IP            string `json:"ip"`
+	UserAgent     string `json:"user_agent"`
+	CreatedAt     string `json:"created_at"`
+}
+
+// AuditFilter narrows an audit log query; empty fields match everything
+type AuditFilter struct {
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      string
+	Until      string
+	Limit      int
+	Offset     int
+}
+
+type AuditPage struct {
+	Entries []AuditEntry `json:"entries"`
+	Total   int          `json:"total"`
+	Limit   int          `json:"limit"`
+	Offset  int          `json:"offset"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// BulkResult is the outcome of a bulk operation for one item
+type BulkResult struct {
+	ID    int    `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type BulkResponse struct {
+	Results   []BulkResult `json:"results"`
+	Succeeded int          `json:"succeeded"`
+	Failed    int          `json:"failed"`
+}
+
+// NewBulkResponse tallies the results of a bulk operation
+func NewBulkResponse(results []BulkResult) BulkResponse {
+	response := BulkResponse{Results: results}
+	for _, result := range results {
+		if result.OK {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	return response
+}
+
+// BulkLinkUpdate is a change a user applies to several of their own links
+type BulkLinkUpdate struct {
+	Action    string // "tag", "category", "privacy" or "delete"
+	Tags      string // Sanitized tags added to each link's existing tags
+	Category  string
+	IsPrivate bool
+}
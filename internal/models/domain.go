@@ -0,0 +1,48 @@
+package models
+
+import "strings"
+
+// Domain policy actions, from most to least restrictive
+const (
+	DomainBlock       = "block"        // Links to the domain cannot be saved
+	DomainPrivateOnly = "private_only" // Links may be saved but never shared publicly
+	DomainAllow       = "allow"        // Exception to a broader block or private_only rule
+)
+
+// DomainRule is an admin-managed policy for links to a host. A pattern of
+// "*.example.com" covers example.com and all of its subdomains; any other pattern
+// matches that host exactly.
+type DomainRule struct {
+	ID        int    `json:"id"`
+	Pattern   string `json:"pattern"`
+	Action    string `json:"action"`
+	Note      string `json:"note"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+}
+
+// matchLength returns how specific a match of the rule against host is, or -1 if
+// the rule does not apply. Exact rules outrank wildcards on the same domain.
+func (r DomainRule) matchLength(host string) int {
+	base, wildcard := strings.CutPrefix(r.Pattern, "*.")
+	switch {
+	case host == base && !wildcard:
+		return len(base) + 1
+	case host == base, wildcard && strings.HasSuffix(host, "."+base):
+		return len(base)
+	}
+	return -1
+}
+
+// DomainAction resolves the policy for host: the most specific matching rule wins
+// and hosts without a matching rule are allowed
+func DomainAction(rules []DomainRule, host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	action, best := DomainAllow, -1
+	for _, rule := range rules {
+		if n := rule.matchLength(host); n > best {
+			action, best = rule.Action, n
+		}
+	}
+	return action
+}
@@ -0,0 +1,12 @@
+package models
+
+// ErrorResponse is the body of every API error response
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+type ErrorDetail struct {
+	Code      string `json:"code"`       // Stable machine-readable code such as "not_found"
+	Message   string `json:"message"`    // Human-readable, safe to show to the user
+	RequestID string `json:"request_id"` // Matches the X-Request-ID response header and server logs
+}
@@ -13,4 +13,5 @@ type Link struct {
 	AccessCount int     `json:"access_count"`
 	IsLocked    bool    `json:"is_locked"` // Admin can lock link privacy
 	Username    string  `json:"username,omitempty"`
+	DeletedAt   *string `json:"deleted_at,omitempty"` // Set for links in the trash
 }
\ No newline at end of file
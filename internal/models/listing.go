@@ -0,0 +1,50 @@
+package models
+
+// AdminUser is a user row in the admin listing
+type AdminUser struct {
+	User
+	LinkCount   int     `json:"link_count"`
+	LastLoginAt *string `json:"last_login_at"` // nil if the user has never signed in
+}
+
+// UserFilter narrows the admin user listing; empty fields match everything
+type UserFilter struct {
+	Query   string // Username substring
+	Role    string
+	IsAdmin *bool
+	Since   string // Created at or after
+	Until   string // Created before
+	Sort    string // created_at, username, link_count or last_login
+	Desc    bool
+	Limit   int
+	Offset  int
+}
+
+type UserPage struct {
+	Users  []AdminUser `json:"users"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// LinkFilter narrows the admin link listing; empty fields match everything
+type LinkFilter struct {
+	Query     string // URL or description substring
+	Username  string
+	Domain    string // Host, also matching its subdomains
+	IsPrivate *bool
+	IsLocked  *bool
+	Since     string // Created at or after
+	Until     string // Created before
+	Sort      string // created_at, access_count, username or url
+	Desc      bool
+	Limit     int
+	Offset    int
+}
+
+type LinkPage struct {
+	Links  []Link `json:"links"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
@@ -0,0 +1,17 @@
+package models
+
+type LoginLockout struct {
+	Username     string  `json:"username"`
+	FailedCount  int     `json:"failed_count"`
+	LockedUntil  int64   `json:"locked_until"` // Unix seconds, 0 if not locked
+	LastFailedAt *string `json:"last_failed_at"`
+}
+
+type FailedLogin struct {
+	ID        int     `json:"id"`
+	Username  string  `json:"username"`
+	IP        string  `json:"ip"`
+	UserAgent *string `json:"user_agent"`
+	Reason    string  `json:"reason"`
+	CreatedAt string  `json:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Report is a user's complaint about a public link, joined with the link and the
+// usernames involved for the moderation queue
+type Report struct {
+	ID          int     `json:"id"`
+	LinkID      int     `json:"link_id"`
+	LinkURL     string  `json:"link_url"`
+	LinkOwnerID int     `json:"link_owner_id"`
+	LinkOwner   string  `json:"link_owner"`
+	ReporterID  int     `json:"reporter_id"`
+	Reporter    string  `json:"reporter"`
+	Reason      string  `json:"reason"`
+	Details     string  `json:"details"`
+	Status      string  `json:"status"`     // "open" or "resolved"
+	Resolution  *string `json:"resolution"` // Moderation action taken, set once resolved
+	ResolvedBy  *string `json:"resolved_by"`
+	ResolvedAt  *string `json:"resolved_at"`
+	Note        string  `json:"note"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+type ReportRequest struct {
+	Reason  string `json:"reason"` // "spam", "abuse", "illegal" or "other"
+	Details string `json:"details"`
+}
+
+type ResolveReportRequest struct {
+	Action string `json:"action"` // "dismiss", "force_private", "delete" or "suspend"
+	Note   string `json:"note"`
+}
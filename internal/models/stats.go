@@ -0,0 +1,31 @@
+package models
+
+type DailyCount struct {
+	Date  string `json:"date"` // YYYY-MM-DD
+	Count int    `json:"count"`
+}
+
+type NamedCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Stats summarises the instance for the admin dashboard. Series cover the last
+// Days days, oldest first, with zero-count days included.
+type Stats struct {
+	Days           int          `json:"days"`
+	TotalUsers     int          `json:"total_users"`
+	TotalLinks     int          `json:"total_links"`
+	PublicLinks    int          `json:"public_links"`
+	PrivateLinks   int          `json:"private_links"`
+	PublicRatio    float64      `json:"public_ratio"` // Public share of all links, 0 when there are none
+	ActiveUsers7d  int          `json:"active_users_7d"`
+	ActiveUsers30d int          `json:"active_users_30d"`
+	NewUsers       []DailyCount `json:"new_users"`
+	NewLinks       []DailyCount `json:"new_links"`
+	TopDomains     []NamedCount `json:"top_domains"`
+	TopTags        []NamedCount `json:"top_tags"`
+	MostAccessed   []Link       `json:"most_accessed"`
+	StorageBytes   int64        `json:"storage_bytes"` // SQLite file size, from page_count * page_size
+	FreeBytes      int64        `json:"free_bytes"`    // Unused pages reclaimable by VACUUM
+}
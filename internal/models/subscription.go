@@ -0,0 +1,33 @@
+package models
+
+// FeedSubscription is an RSS, Atom or JSON Feed that is polled for new items, each
+// saved as a link of the subscriber tagged with the feed's name
+type FeedSubscription struct {
+	ID            int     `json:"id"`
+	UserID        int     `json:"user_id"`
+	URL           string  `json:"url"`
+	Name          string  `json:"name"`
+	IsPrivate     bool    `json:"is_private"` // Save new links as private
+	Active        bool    `json:"active"`
+	LastCheckedAt *string `json:"last_checked_at"`
+	LastError     string  `json:"last_error,omitempty"`
+	NextCheckAt   string  `json:"next_check_at"`
+	CreatedAt     string  `json:"created_at"`
+	Failures      int     `json:"-"` // Consecutive failed polls, for backoff
+	ETag          string  `json:"-"`
+	LastModified  string  `json:"-"`
+}
+
+type FeedSubscriptionRequest struct {
+	URL       string `json:"url"`
+	Name      string `json:"name"`
+	IsPrivate *bool  `json:"is_private"`
+	Active    *bool  `json:"active"`
+}
+
+// FeedPollResult reports what one poll of a subscription did
+type FeedPollResult struct {
+	Subscription *FeedSubscription `json:"subscription"`
+	Saved        int               `json:"saved"`   // New links created
+	Skipped      int               `json:"skipped"` // Items already seen or already saved
+}
@@ -1,26 +1,130 @@
 package models
 
+import "time"
+
 type User struct {
-	ID        int    `json:"id"`
-	Username  string `json:"username"`
-	Password  string `json:"-"` // Never expose password in JSON
-	IsAdmin   bool   `json:"isAdmin"`
-	CreatedAt string `json:"createdAt"`
+	ID             int     `json:"id"`
+	Username       string  `json:"username"`
+	Password       string  `json:"-"` // Never expose password in JSON
+	IsAdmin        bool    `json:"isAdmin"`
+	Role           string  `json:"role"` // "user", "moderator" or "admin"
+	TOTPEnabled    bool    `json:"totp_enabled"`
+	PasskeyEnabled bool    `json:"passkey_enabled"`
+	CreatedAt      string  `json:"createdAt"`
+	SessionVersion int     `json:"-"`
+	DeletedAt      *string `json:"deleted_at,omitempty"`
+	SuspendedAt    *string `json:"suspended_at,omitempty"`
+	SuspendedUntil *string `json:"suspended_until,omitempty"` // nil for an indefinite suspension
+	SuspendReason  *string `json:"suspend_reason,omitempty"`
+
+	// Set on requests made with an impersonation token; the audit log attributes
+	// their actions to the impersonating admin
+	ImpersonatorID       int    `json:"-"`
+	ImpersonatorUsername string `json:"-"`
+	ImpersonationID      string `json:"-"`
+}
+
+// IsSuspended reports whether a suspension is in effect. Timestamps use the database
+// format, so they compare correctly as strings.
+func (u *User) IsSuspended() bool {
+	if u.SuspendedAt == nil {
+		return false
+	}
+	return u.SuspendedUntil == nil || *u.SuspendedUntil > time.Now().Format("2006-01-02 15:04:05")
+}
+
+// HasSecondFactor reports whether the user has enrolled TOTP or a passkey
+func (u *User) HasSecondFactor() bool {
+	return u.TOTPEnabled || u.PasskeyEnabled
 }
 
 type AuthRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Email    string `json:"email,omitempty"` // Optional on register; verified by email
 }
 
-type AuthResponse struct {
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type VerifyEmailRequest struct {
 	Token string `json:"token"`
-	User  User   `json:"user"`
+}
+
+type AuthResponse struct {
+	Token                  string `json:"token"`
+	User                   User   `json:"user"`
+	TwoFactorSetupRequired bool   `json:"two_factor_setup_required,omitempty"`
+}
+
+// TwoFactorChallenge is returned by login when a second factor is still required
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool     `json:"two_factor_required"`
+	MFAToken          string   `json:"mfa_token"`
+	Methods           []string `json:"methods"` // "totp", "recovery_code", "webauthn"
+}
+
+type TwoFactorLoginRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
 }
 
 type JWTClaims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	IsAdmin  bool   `json:"is_admin"`
-	Exp      int64  `json:"exp"`
-}
\ No newline at end of file
+	UserID         int    `json:"user_id"`
+	Username       string `json:"username"`
+	IsAdmin        bool   `json:"is_admin"`
+	SessionVersion int    `json:"sv"`
+	Purpose        string `json:"purpose,omitempty"` // Set on non-session tokens (e.g. "mfa")
+	Exp            int64  `json:"exp"`
+
+	// Impersonation tokens let an admin view the app as UserID
+	ImpersonatorID  int    `json:"imp,omitempty"`
+	ImpersonationID string `json:"imp_sid,omitempty"` // Correlates the session's audit entries
+	ReadOnly        bool   `json:"ro,omitempty"`
+}
+
+// ImpersonationResponse is returned when an admin starts viewing as another user
+type ImpersonationResponse struct {
+	Token     string `json:"token"`
+	User      User   `json:"user"`
+	SessionID string `json:"session_id"`
+	ReadOnly  bool   `json:"read_only"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// Profile is the signed-in user's own view of their account
+type Profile struct {
+	User
+	Email         *string `json:"email"`
+	EmailVerified bool    `json:"email_verified"`
+	HasPassword   bool    `json:"has_password"`
+	GoogleLinked  bool    `json:"google_linked"`
+}
+
+type UpdateProfileRequest struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"` // Not required when setting a first password on an OAuth-only account
+	NewPassword     string `json:"new_password"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"` // Required for accounts that have a password
+	Export   bool   `json:"export"`   // Return the account export in the response before deleting
+}
+
+// AccountExport is a portable copy of a user's data
+type AccountExport struct {
+	Profile    Profile `json:"profile"`
+	Links      []Link  `json:"links"`
+	ExportedAt string  `json:"exported_at"`
+}
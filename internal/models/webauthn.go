@@ -0,0 +1,12 @@
+package models
+
+type WebAuthnCredential struct {
+	ID           int     `json:"id"`
+	UserID       int     `json:"user_id"`
+	CredentialID string  `json:"credential_id"` // base64url
+	PublicKey    []byte  `json:"-"`             // COSE_Key
+	SignCount    uint32  `json:"-"`
+	Name         string  `json:"name"`
+	CreatedAt    string  `json:"created_at"`
+	LastUsedAt   *string `json:"last_used_at"`
+}
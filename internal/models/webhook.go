@@ -0,0 +1,62 @@
+package models
+
+// Webhook event names
+const (
+	EventLinkCreated    = "link.created"
+	EventLinkUpdated    = "link.updated"
+	EventLinkDeleted    = "link.deleted"
+	EventLinkMadePublic = "link.made_public"
+	EventPing           = "ping" // Sent only by the test endpoint
+)
+
+// WebhookEvents are the events a webhook can subscribe to
+var WebhookEvents = []string{EventLinkCreated, EventLinkUpdated, EventLinkDeleted, EventLinkMadePublic}
+
+// Webhook posts link events to URL. A webhook without an owner is site-wide: it is
+// managed by admins and receives events for every user's public links.
+type Webhook struct {
+	ID        int      `json:"id"`
+	UserID    *int     `json:"user_id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Active    bool     `json:"active"`
+	Secret    string   `json:"secret,omitempty"` // Only returned when the webhook is created
+	CreatedAt string   `json:"created_at"`
+}
+
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Active *bool    `json:"active"`
+}
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event queued for one webhook, with the outcome of its
+// latest attempt
+type WebhookDelivery struct {
+	ID            int     `json:"id"`
+	WebhookID     int     `json:"webhook_id"`
+	Event         string  `json:"event"`
+	Payload       string  `json:"payload"`
+	Status        string  `json:"status"`
+	Attempts      int     `json:"attempts"`
+	ResponseCode  int     `json:"response_code,omitempty"`
+	Error         string  `json:"error,omitempty"`
+	CreatedAt     string  `json:"created_at"`
+	LastAttemptAt *string `json:"last_attempt_at"`
+	URL           string  `json:"-"`
+	Secret        string  `json:"-"`
+}
+
+// WebhookPayload is the JSON body posted to webhooks
+type WebhookPayload struct {
+	Event     string `json:"event"`
+	CreatedAt string `json:"created_at"` // RFC 3339
+	Link      *Link  `json:"link,omitempty"`
+}
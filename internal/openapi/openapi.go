@@ -0,0 +1,46 @@
+// Package openapi serves the OpenAPI 3 description of the /api/v1 API. The router
+// tests check it against the routes and responses the server actually produces, so
+// the two cannot drift apart.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var spec []byte
+
+var methods = []string{"get", "put", "post", "delete", "patch", "head", "options"}
+
+// Handler serves the OpenAPI document
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+// Operations returns every operation in the document as a ServeMux pattern such as
+// "GET /api/v1/links/{id}", sorted
+func Operations() ([]string, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse openapi.json: %w", err)
+	}
+
+	var ops []string
+	for path, item := range doc.Paths {
+		for _, method := range methods {
+			if _, ok := item[method]; ok {
+				ops = append(ops, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(ops)
+	return ops, nil
+}
@@ -0,0 +1,314 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"links/internal/auth"
+	"links/internal/middleware"
+)
+
+// document is the parsed OpenAPI document as served by the router
+type document map[string]interface{}
+
+func loadDocument(t *testing.T, routes http.Handler) document {
+	t.Helper()
+	w := httptest.NewRecorder()
+	routes.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json = %d", w.Code)
+	}
+	var doc document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("parsing openapi.json: %v", err)
+	}
+	return doc
+}
+
+// resolve follows a local $ref such as "#/components/schemas/Link"
+func (doc document) resolve(node map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var target interface{} = map[string]interface{}(doc)
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, _ := target.(map[string]interface{})
+			target = m[part]
+		}
+		next, ok := target.(map[string]interface{})
+		if !ok {
+			panic("unresolved $ref " + ref)
+		}
+		node = next
+	}
+}
+
+// operation returns the documented operation for a pattern such as "GET /api/v1/me"
+func (doc document) operation(pattern string) map[string]interface{} {
+	method, path, _ := strings.Cut(pattern, " ")
+	paths, _ := doc["paths"].(map[string]interface{})
+	item, _ := paths[path].(map[string]interface{})
+	op, _ := item[strings.ToLower(method)].(map[string]interface{})
+	return op
+}
+
+// response returns the documented response for an operation and status, or nil
+func (doc document) response(pattern string, status int) map[string]interface{} {
+	responses, _ := doc.operation(pattern)["responses"].(map[string]interface{})
+	response, ok := responses[strconv.Itoa(status)].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return doc.resolve(response)
+}
+
+// validate checks a decoded JSON value against the subset of JSON Schema the document
+// uses and returns every mismatch. Properties the schema does not list count as
+// mismatches so new response fields get documented.
+func (doc document) validate(schema map[string]interface{}, value interface{}, at string) []string {
+	schema = doc.resolve(schema)
+	if value == nil {
+		if schema["nullable"] == true {
+			return nil
+		}
+		return []string{at + ": null"}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T, want object", at, value)}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, at+"."+name.(string)+": missing")
+			}
+		}
+		for name, v := range object {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				problems = append(problems, at+"."+name+": not in the schema")
+				continue
+			}
+			problems = append(problems, doc.validate(property, v, at+"."+name)...)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T, want array", at, value)}
+		}
+		for i, item := range items {
+			problems = append(problems, doc.validate(schema["items"].(map[string]interface{}), item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T, want string", at, value)}
+		}
+		if enum, ok := schema["enum"].([]interface{}); ok {
+			found := false
+			for _, e := range enum {
+				found = found || e == s
+			}
+			if !found {
+				problems = append(problems, fmt.Sprintf("%s: %q not in %v", at, s, enum))
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return []string{fmt.Sprintf("%s: %v, want integer", at, value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: %T, want boolean", at, value)}
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%s: unsupported schema type %v", at, schema["type"]))
+	}
+	return problems
+}
+
+// checkRequest validates a test's request body against the operation's documented
+// request body, so the cases exercise the API as documented
+func (doc document) checkRequest(t *testing.T, pattern string, body interface{}) {
+	t.Helper()
+	requestBody, ok := doc.operation(pattern)["requestBody"].(map[string]interface{})
+	if !ok {
+		if body != nil {
+			t.Fatalf("%s takes no request body", pattern)
+		}
+		return
+	}
+	if body == nil {
+		if requestBody["required"] == true {
+			t.Fatalf("%s requires a request body", pattern)
+		}
+		return
+	}
+	content, _ := requestBody["content"].(map[string]interface{})
+	media, _ := content["application/json"].(map[string]interface{})
+	var decoded interface{}
+	encoded, _ := json.Marshal(body)
+	json.Unmarshal(encoded, &decoded)
+	for _, problem := range doc.validate(media["schema"].(map[string]interface{}), decoded, "request") {
+		t.Fatalf("%s: %s", pattern, problem)
+	}
+}
+
+// checkResponse validates a recorded response against the operation's documented
+// response for its status
+func (doc document) checkResponse(t *testing.T, pattern string, w *httptest.ResponseRecorder) {
+	t.Helper()
+	response := doc.response(pattern, w.Code)
+	if response == nil {
+		t.Errorf("%s answered %d, which openapi.json does not document: %s", pattern, w.Code, w.Body)
+		return
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	media, ok := content["application/json"].(map[string]interface{})
+	if !ok {
+		if w.Body.Len() != 0 {
+			t.Errorf("%s %d: documented without a body, got %s", pattern, w.Code, w.Body)
+		}
+		return
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("%s %d: Content-Type = %q", pattern, w.Code, ct)
+	}
+	var body interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Errorf("%s %d: body is not JSON: %v", pattern, w.Code, err)
+		return
+	}
+	for _, problem := range doc.validate(media["schema"].(map[string]interface{}), body, "body") {
+		t.Errorf("%s %d: %s", pattern, w.Code, problem)
+	}
+}
+
+// The /api/v1 handlers answer with the statuses and bodies openapi.json describes
+func TestV1ResponsesMatchDocument(t *testing.T) {
+	routes := newTestRouter(t)
+	doc := loadDocument(t, routes)
+	server := middleware.RequestID(routes)
+
+	now := time.Now().Format("2006-01-02 15:04:05")
+	newUser := func(username string) (int, string) {
+		id, err := routes.db.CreateUser(username, "hash", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user, err := routes.db.GetUserByID(int(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := auth.GenerateJWT(user.ID, user.Username, user.IsAdmin, user.SessionVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return user.ID, token
+	}
+	newLink := func(userID int, url string, private bool) int {
+		description, tags := "A link", "go,testing"
+		id, err := routes.db.CreateLink(userID, url, &description, &tags, nil, now, private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int(id)
+	}
+
+	aliceID, alice := newUser("alice")
+	bobID, _ := newUser("bob")
+	own := newLink(aliceID, "https://example.com/own", true)
+	locked := newLink(aliceID, "https://example.com/locked", true)
+	if err := routes.db.AdminToggleLinkLock(locked, true); err != nil {
+		t.Fatal(err)
+	}
+	others := newLink(bobID, "https://example.com/public", false)
+
+	link := func(id int, suffix string) string { return "/api/v1/links/" + strconv.Itoa(id) + suffix }
+	tests := []struct {
+		pattern string // Documented operation
+		target  string
+		token   string
+		body    interface{}
+		status  int
+	}{
+		{"GET /api/v1/links", "/api/v1/links?limit=1", alice, nil, http.StatusOK},
+		{"GET /api/v1/links", "/api/v1/links", "", nil, http.StatusUnauthorized},
+		{"POST /api/v1/links", "/api/v1/links", alice, map[string]interface{}{"url": "https://example.com/new", "tags": "go", "is_private": true}, http.StatusOK},
+		{"POST /api/v1/links", "/api/v1/links", alice, map[string]interface{}{"url": ""}, http.StatusBadRequest},
+		{"POST /api/v1/links", "/api/v1/links", "", map[string]interface{}{"url": "https://example.com/"}, http.StatusUnauthorized},
+		{"GET /api/v1/links/{id}", link(own, ""), alice, nil, http.StatusOK},
+		{"GET /api/v1/links/{id}", "/api/v1/links/abc", alice, nil, http.StatusBadRequest},
+		{"GET /api/v1/links/{id}", link(others, ""), alice, nil, http.StatusNotFound},
+		{"GET /api/v1/links/{id}", link(own, ""), "", nil, http.StatusUnauthorized},
+		{"PUT /api/v1/links/{id}/favorite", link(own, "/favorite"), alice, map[string]interface{}{"is_favorite": true}, http.StatusNoContent},
+		{"PUT /api/v1/links/{id}/favorite", link(others, "/favorite"), alice, map[string]interface{}{"is_favorite": true}, http.StatusNotFound},
+		{"PUT /api/v1/links/{id}/privacy", link(locked, "/privacy"), alice, map[string]interface{}{"is_private": false}, http.StatusForbidden},
+		{"PUT /api/v1/links/{id}/privacy", link(own, "/privacy"), alice, map[string]interface{}{"is_private": false}, http.StatusNoContent},
+		{"PUT /api/v1/links/{id}/privacy", "/api/v1/links/abc/privacy", alice, map[string]interface{}{"is_private": false}, http.StatusBadRequest},
+		{"GET /api/v1/public-links", "/api/v1/public-links", "", nil, http.StatusOK},
+		{"GET /api/v1/me", "/api/v1/me", alice, nil, http.StatusOK},
+		{"GET /api/v1/me", "/api/v1/me", "", nil, http.StatusUnauthorized},
+		{"DELETE /api/v1/links/{id}", link(own, ""), alice, nil, http.StatusNoContent},
+		{"DELETE /api/v1/links/{id}", link(own, ""), alice, nil, http.StatusNotFound},
+		{"DELETE /api/v1/links/{id}", link(others, ""), "", nil, http.StatusUnauthorized},
+	}
+
+	exercised := map[string]bool{}
+	for _, tt := range tests {
+		method, _, _ := strings.Cut(tt.pattern, " ")
+		t.Run(fmt.Sprintf("%s %s %d", method, tt.target, tt.status), func(t *testing.T) {
+			doc.checkRequest(t, tt.pattern, tt.body)
+			var body bytes.Buffer
+			if tt.body != nil {
+				json.NewEncoder(&body).Encode(tt.body)
+			}
+			r := httptest.NewRequest(method, tt.target, &body)
+			r.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if _, pattern := routes.mux.Handler(r); pattern != tt.pattern {
+				t.Fatalf("matched %q, want %q", pattern, tt.pattern)
+			}
+
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, r)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			doc.checkResponse(t, tt.pattern, w)
+			exercised[tt.pattern] = true
+		})
+	}
+
+	// Every documented operation is covered above
+	var missing []string
+	for _, pattern := range routes.v1Routes {
+		if !exercised[pattern] {
+			missing = append(missing, pattern)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Errorf("no passing contract case for %s", strings.Join(missing, ", "))
+	}
+}
@@ -0,0 +1,302 @@
+// Package router registers every HTTP route of the server on a method-aware
+// http.ServeMux.
+package router
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"links/internal/auth"
+	"links/internal/db"
+	"links/internal/feeds"
+	"links/internal/handlers"
+	"links/internal/mailer"
+	"links/internal/middleware"
+	"links/internal/openapi"
+	"links/internal/webhooks"
+)
+
+// Config holds what the handlers need, created once at startup
+type Config struct {
+	DB        *db.Database
+	Mailer    mailer.Mailer
+	Webhooks  *webhooks.Dispatcher
+	Poller    *feeds.Poller
+	StaticDir string // Pages and assets served outside /api
+}
+
+// noCache marks a response as never cacheable so page and asset updates show up immediately
+func noCache(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+}
+
+// servePage serves one of the HTML entry points from the static directory
+func servePage(staticDir, name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		noCache(w)
+		http.ServeFile(w, r, filepath.Join(staticDir, name))
+	}
+}
+
+// statusRecorder captures the status and headers a handler writes, discarding the body
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (rec *statusRecorder) Header() http.Header         { return rec.header }
+func (rec *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (rec *statusRecorder) WriteHeader(status int)      { rec.status = status }
+
+// jsonFallback sends requests no route accepts through the mux's own 404 or 405
+// handling but replaces its plain-text body with the JSON error envelope, keeping the
+// Allow header
+func jsonFallback(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, pattern := mux.Handler(r)
+		if pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		rec := &statusRecorder{header: http.Header{}, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		if allow := rec.header.Get("Allow"); allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		middleware.WriteError(w, http.StatusText(rec.status), rec.status)
+	})
+}
+
+// New constructs the handlers once and returns the router for every route. Requests
+// no route accepts get a JSON 404, or a 405 with an Allow header when only the method
+// is wrong.
+func New(cfg Config) http.Handler {
+	mux, _ := newMux(cfg)
+	return jsonFallback(mux)
+}
+
+// deprecated marks a route kept for the web app and older clients that has a
+// replacement at the same path under /api/v1
+func deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "</api/v1"+strings.TrimPrefix(r.URL.Path, "/api")+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
+
+// newMux registers every route on a fresh mux and returns it with the /api/v1 route
+// patterns
+func newMux(cfg Config) (mux *http.ServeMux, v1Routes []string) {
+	database, mail, hooks := cfg.DB, cfg.Mailer, cfg.Webhooks
+	authHandler := handlers.NewAuthHandler(database, mail)
+	linksHandler := handlers.NewLinksHandler(database)
+	oauthHandler := handlers.NewOAuthHandler(database)
+	adminHandler := handlers.NewAdminHandler(database)
+	metadataHandler := handlers.NewMetadataHandler()
+	twoFactorHandler := handlers.NewTwoFactorHandler(database)
+	webAuthnHandler := handlers.NewWebAuthnHandler(database)
+	accountHandler := handlers.NewAccountHandler(database, mail)
+	emailHandler := handlers.NewEmailHandler(database, mail)
+	quickSaveHandler := handlers.NewQuickSaveHandler(linksHandler, metadataHandler)
+	webhooksHandler := handlers.NewWebhooksHandler(database, hooks, false)
+	siteWebhooksHandler := handlers.NewWebhooksHandler(database, hooks, true)
+	feedsHandler := handlers.NewFeedsHandler(database)
+	subscriptionsHandler := handlers.NewSubscriptionsHandler(database, cfg.Poller)
+	eventsHandler := handlers.NewEventsHandler()
+
+	authed := middleware.AuthMiddleware
+	rateLimited := func(next http.HandlerFunc) http.Handler {
+		return middleware.AuthRateLimit(next)
+	}
+	// Admin endpoints require a role granting the route's permission
+	admin := func(perm auth.Permission, next http.HandlerFunc) http.HandlerFunc {
+		return middleware.AuthMiddleware(middleware.RequirePermission(perm, next))
+	}
+
+	mux = http.NewServeMux()
+
+	// Auth endpoints (no auth required) - with rate limiting
+	mux.Handle("POST /api/register", rateLimited(authHandler.Register))
+	mux.Handle("POST /api/login", rateLimited(authHandler.Login))
+	mux.Handle("POST /api/login/2fa", rateLimited(authHandler.LoginTwoFactor))
+
+	// Two-factor management endpoints
+	mux.HandleFunc("GET /api/2fa/status", authed(twoFactorHandler.Status))
+	mux.HandleFunc("POST /api/2fa/setup", authed(twoFactorHandler.Setup))
+	mux.Handle("POST /api/2fa/enable", rateLimited(authed(twoFactorHandler.Enable)))
+	mux.Handle("POST /api/2fa/disable", rateLimited(authed(twoFactorHandler.Disable)))
+	mux.Handle("POST /api/2fa/recovery-codes", rateLimited(authed(twoFactorHandler.RegenerateRecoveryCodes)))
+
+	// WebAuthn (passkey) endpoints
+	mux.Handle("POST /api/webauthn/login/begin", rateLimited(webAuthnHandler.BeginLogin))
+	mux.Handle("POST /api/webauthn/login/finish", rateLimited(webAuthnHandler.FinishLogin))
+	mux.HandleFunc("POST /api/webauthn/register/begin", authed(webAuthnHandler.BeginRegistration))
+	mux.HandleFunc("POST /api/webauthn/register/finish", authed(webAuthnHandler.FinishRegistration))
+	mux.HandleFunc("GET /api/webauthn/credentials", authed(webAuthnHandler.GetCredentials))
+	mux.HandleFunc("DELETE /api/webauthn/credentials/{id}", authed(webAuthnHandler.DeleteCredential))
+
+	// OAuth endpoints
+	mux.HandleFunc("GET /api/auth/google", oauthHandler.GoogleLogin)
+	mux.HandleFunc("GET /api/auth/google/callback", oauthHandler.GoogleCallback)
+
+	// Links. Routes with a /api/v1 equivalent are deprecated for integrations.
+	mux.HandleFunc("GET /api/links", deprecated(authed(linksHandler.GetLinks)))
+	mux.HandleFunc("POST /api/links", deprecated(authed(linksHandler.CreateLink)))
+	mux.HandleFunc("POST /api/links/bulk", authed(linksHandler.BulkUpdate))
+	mux.HandleFunc("DELETE /api/links/{id}", deprecated(authed(linksHandler.DeleteLink)))
+	mux.HandleFunc("PUT /api/links/{id}/favorite", deprecated(authed(linksHandler.ToggleFavorite)))
+	mux.HandleFunc("PUT /api/links/{id}/access", authed(linksHandler.IncrementAccess))
+	mux.HandleFunc("PUT /api/links/{id}/privacy", deprecated(authed(linksHandler.TogglePrivacy)))
+	mux.HandleFunc("POST /api/links/{id}/report", authed(linksHandler.ReportLink))
+
+	// Trash: deleted links can be restored or purged until the retention period ends
+	mux.HandleFunc("GET /api/links/trash", authed(linksHandler.GetTrash))
+	mux.HandleFunc("DELETE /api/links/trash", authed(linksHandler.EmptyTrash))
+	mux.HandleFunc("PUT /api/links/{id}/restore", authed(linksHandler.RestoreLink))
+	mux.HandleFunc("DELETE /api/links/{id}/purge", authed(linksHandler.PurgeLink))
+
+	// Password reset and email verification (no auth required) - with rate limiting
+	mux.Handle("POST /api/password/forgot", rateLimited(emailHandler.ForgotPassword))
+	mux.Handle("POST /api/password/reset", rateLimited(emailHandler.ResetPassword))
+	mux.Handle("POST /api/email/verify", rateLimited(emailHandler.VerifyEmail))
+	mux.Handle("POST /api/email/verify/resend", rateLimited(authed(emailHandler.ResendVerification)))
+
+	// Self-service account endpoints
+	mux.HandleFunc("GET /api/me", deprecated(authed(accountHandler.GetProfile)))
+	mux.HandleFunc("PUT /api/me", authed(accountHandler.UpdateProfile))
+	mux.Handle("DELETE /api/me", rateLimited(authed(accountHandler.DeleteAccount)))
+	mux.Handle("PUT /api/me/password", rateLimited(authed(accountHandler.ChangePassword)))
+	mux.HandleFunc("GET /api/me/export", authed(accountHandler.Export))
+	mux.HandleFunc("GET /api/me/tokens", authed(accountHandler.GetAPITokens))
+	mux.HandleFunc("POST /api/me/tokens", authed(accountHandler.CreateAPIToken))
+	mux.HandleFunc("DELETE /api/me/tokens/{id}", authed(accountHandler.DeleteAPIToken))
+	mux.HandleFunc("POST /api/me/feed-token", authed(feedsHandler.CreateFeedToken))
+	mux.HandleFunc("DELETE /api/me/feed-token", authed(feedsHandler.DeleteFeedToken))
+
+	// Webhooks for events on the user's own links
+	mux.HandleFunc("GET /api/webhooks", authed(webhooksHandler.GetWebhooks))
+	mux.HandleFunc("POST /api/webhooks", authed(webhooksHandler.CreateWebhook))
+	mux.HandleFunc("PUT /api/webhooks/{id}", authed(webhooksHandler.UpdateWebhook))
+	mux.HandleFunc("DELETE /api/webhooks/{id}", authed(webhooksHandler.DeleteWebhook))
+	mux.HandleFunc("GET /api/webhooks/{id}/deliveries", authed(webhooksHandler.GetDeliveries))
+	mux.Handle("POST /api/webhooks/{id}/test", middleware.MetadataRateLimit(authed(webhooksHandler.TestWebhook)))
+
+	// Feed subscriptions; creating or refreshing one fetches the feed, so both share
+	// the metadata rate limit
+	mux.HandleFunc("GET /api/feeds", authed(subscriptionsHandler.GetSubscriptions))
+	mux.Handle("POST /api/feeds", middleware.MetadataRateLimit(authed(subscriptionsHandler.CreateSubscription)))
+	mux.HandleFunc("PUT /api/feeds/{id}", authed(subscriptionsHandler.UpdateSubscription))
+	mux.HandleFunc("DELETE /api/feeds/{id}", authed(subscriptionsHandler.DeleteSubscription))
+	mux.Handle("POST /api/feeds/{id}/refresh", middleware.MetadataRateLimit(authed(subscriptionsHandler.RefreshSubscription)))
+
+	// Lets an admin leave a "view as user" session (called with the impersonation token)
+	mux.HandleFunc("POST "+middleware.ImpersonationEndPath, authed(adminHandler.EndImpersonation))
+
+	// Metadata extraction endpoint - with rate limiting and auth
+	mux.Handle("GET /api/metadata", middleware.MetadataRateLimit(authed(metadataHandler.ExtractMetadata)))
+
+	// One-request save for browser extensions and the bookmarklet (session or API token);
+	// it may fetch the page, so it shares the metadata rate limit
+	mux.Handle("POST /api/quick-save", middleware.MetadataRateLimit(authed(quickSaveHandler.QuickSave)))
+
+	// Public links endpoint (no auth required)
+	mux.HandleFunc("GET /api/public-links", deprecated(linksHandler.GetPublicLinks))
+
+	// Server-Sent Events: changes to the caller's own links and to public links, or
+	// only public ones without credentials
+	mux.HandleFunc("GET /api/events", middleware.OptionalAuth(eventsHandler.Stream))
+
+	// Atom and RSS feeds; the private feed is authenticated by the token in its URL
+	mux.HandleFunc("GET /feeds/public.atom", feedsHandler.Public)
+	mux.HandleFunc("GET /feeds/public.rss", feedsHandler.Public)
+	mux.HandleFunc("GET /feeds/users/{file}", feedsHandler.User)
+	mux.HandleFunc("GET /feeds/tags/{file}", feedsHandler.Tag)
+	mux.HandleFunc("GET /feeds/private/{file}", feedsHandler.Unread)
+
+	// Versioned API with stable response shapes. Every route here must be described in
+	// internal/openapi/openapi.json; the router tests check the routes and responses
+	// against it.
+	v1 := func(pattern string, next http.HandlerFunc) {
+		v1Routes = append(v1Routes, pattern)
+		mux.HandleFunc(pattern, next)
+	}
+	v1("GET /api/v1/links", authed(linksHandler.ListLinksV1))
+	v1("POST /api/v1/links", authed(linksHandler.CreateLink))
+	v1("GET /api/v1/links/{id}", authed(linksHandler.GetLinkV1))
+	v1("DELETE /api/v1/links/{id}", authed(linksHandler.DeleteLink))
+	v1("PUT /api/v1/links/{id}/favorite", authed(linksHandler.ToggleFavorite))
+	v1("PUT /api/v1/links/{id}/privacy", authed(linksHandler.TogglePrivacy))
+	v1("GET /api/v1/public-links", linksHandler.ListPublicLinksV1)
+	v1("GET /api/v1/me", authed(accountHandler.GetProfile))
+	mux.HandleFunc("GET /api/openapi.json", openapi.Handler)
+
+	// Admin endpoints
+	mux.HandleFunc("GET /api/admin/roles", admin(auth.PermUsersRead, adminHandler.GetRoles))
+	mux.HandleFunc("GET /api/admin/stats", admin(auth.PermUsersRead, adminHandler.GetStats))
+	mux.HandleFunc("GET /api/admin/policy", admin(auth.PermSettingsManage, adminHandler.GetPolicy))
+	mux.HandleFunc("PUT /api/admin/policy", admin(auth.PermSettingsManage, adminHandler.UpdatePolicy))
+	mux.HandleFunc("GET /api/admin/lockouts", admin(auth.PermSecurityRead, adminHandler.GetLockouts))
+	mux.HandleFunc("GET /api/admin/audit", admin(auth.PermSecurityRead, adminHandler.GetAuditLog))
+	mux.HandleFunc("GET /api/admin/failed-logins", admin(auth.PermSecurityRead, adminHandler.GetFailedLogins))
+
+	mux.HandleFunc("GET /api/admin/links", admin(auth.PermLinksRead, adminHandler.GetAllLinks))
+	mux.HandleFunc("POST /api/admin/links/bulk", admin(auth.PermLinksRead, adminHandler.BulkLinks))
+	mux.HandleFunc("DELETE /api/admin/links/{id}/delete", admin(auth.PermLinksDelete, adminHandler.DeleteLink))
+	mux.HandleFunc("PUT /api/admin/links/{id}/restore", admin(auth.PermLinksDelete, adminHandler.RestoreLink))
+	mux.HandleFunc("PUT /api/admin/links/{id}/lock", admin(auth.PermLinksLock, adminHandler.ToggleLinkLock))
+	mux.HandleFunc("PUT /api/admin/links/{id}/force-private", admin(auth.PermLinksForcePrivate, adminHandler.ForcePrivateLink))
+
+	mux.HandleFunc("GET /api/admin/reports", admin(auth.PermReportsManage, adminHandler.GetReports))
+	mux.HandleFunc("POST /api/admin/reports/{id}/resolve", admin(auth.PermReportsManage, adminHandler.ResolveReport))
+
+	mux.HandleFunc("GET /api/admin/domains", admin(auth.PermSettingsManage, adminHandler.GetDomainRules))
+	mux.HandleFunc("POST /api/admin/domains", admin(auth.PermSettingsManage, adminHandler.SaveDomainRule))
+	mux.HandleFunc("DELETE /api/admin/domains/{id}", admin(auth.PermSettingsManage, adminHandler.DeleteDomainRule))
+
+	// Site-wide webhooks receive events for every user's public links
+	mux.HandleFunc("GET /api/admin/webhooks", admin(auth.PermSettingsManage, siteWebhooksHandler.GetWebhooks))
+	mux.HandleFunc("POST /api/admin/webhooks", admin(auth.PermSettingsManage, siteWebhooksHandler.CreateWebhook))
+	mux.HandleFunc("PUT /api/admin/webhooks/{id}", admin(auth.PermSettingsManage, siteWebhooksHandler.UpdateWebhook))
+	mux.HandleFunc("DELETE /api/admin/webhooks/{id}", admin(auth.PermSettingsManage, siteWebhooksHandler.DeleteWebhook))
+	mux.HandleFunc("GET /api/admin/webhooks/{id}/deliveries", admin(auth.PermSettingsManage, siteWebhooksHandler.GetDeliveries))
+	mux.HandleFunc("POST /api/admin/webhooks/{id}/test", admin(auth.PermSettingsManage, siteWebhooksHandler.TestWebhook))
+
+	mux.HandleFunc("GET /api/admin/users", admin(auth.PermUsersRead, adminHandler.GetAllUsers))
+	mux.HandleFunc("GET /api/admin/users/deleted", admin(auth.PermUsersRead, adminHandler.GetDeletedUsers))
+	mux.HandleFunc("PUT /api/admin/users/{id}/unlock", admin(auth.PermUsersUnlock, adminHandler.UnlockUser))
+	mux.HandleFunc("PUT /api/admin/users/{id}/admin", admin(auth.PermUsersManageRoles, adminHandler.ToggleUserAdmin))
+	mux.HandleFunc("PUT /api/admin/users/{id}/role", admin(auth.PermUsersManageRoles, adminHandler.SetUserRole))
+	mux.HandleFunc("PUT /api/admin/users/{id}/suspend", admin(auth.PermUsersSuspend, adminHandler.SuspendUser))
+	mux.HandleFunc("PUT /api/admin/users/{id}/unsuspend", admin(auth.PermUsersSuspend, adminHandler.UnsuspendUser))
+	mux.HandleFunc("POST /api/admin/users/{id}/impersonate", admin(auth.PermUsersImpersonate, adminHandler.Impersonate))
+	mux.HandleFunc("PUT /api/admin/users/{id}/restore", admin(auth.PermUsersDelete, adminHandler.RestoreUser))
+	mux.HandleFunc("DELETE /api/admin/users/{id}/purge", admin(auth.PermUsersDelete, adminHandler.PurgeUser))
+	mux.HandleFunc("DELETE /api/admin/users/{id}/delete", admin(auth.PermUsersDelete, adminHandler.DeleteUser))
+
+	// Pages
+	mux.HandleFunc("GET /{$}", servePage(cfg.StaticDir, "index.html"))
+	mux.HandleFunc("GET /login", servePage(cfg.StaticDir, "login.html"))
+	mux.HandleFunc("GET /admin", servePage(cfg.StaticDir, "admin.html"))
+	mux.HandleFunc("GET /save", servePage(cfg.StaticDir, "save.html"))
+
+	// For other static files, use file server with conditional caching
+	fileServer := http.FileServer(http.Dir(cfg.StaticDir))
+	mux.HandleFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			middleware.WriteError(w, "Not found", http.StatusNotFound)
+			return
+		}
+		// Disable cache for JS, CSS, and HTML files to see updates immediately
+		path := r.URL.Path
+		if strings.HasSuffix(path, ".js") || strings.HasSuffix(path, ".css") || strings.HasSuffix(path, ".html") {
+			noCache(w)
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+
+	return mux, v1Routes
+}
@@ -0,0 +1,172 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"links/internal/db"
+	"links/internal/middleware"
+	"links/internal/openapi"
+)
+
+// testRouter is the full router over a fresh database, with the mux behind it for
+// checking which pattern a request matches
+type testRouter struct {
+	http.Handler
+	mux      *http.ServeMux
+	v1Routes []string
+	db       *db.Database
+}
+
+func newTestRouter(t *testing.T) *testRouter {
+	t.Helper()
+	database, err := db.New(filepath.Join(t.TempDir(), "links.db"))
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() {
+		middleware.SetSessionStore(nil)
+		database.Close()
+	})
+	middleware.SetSessionStore(database)
+
+	cfg := Config{DB: database, StaticDir: t.TempDir()}
+	mux, v1Routes := newMux(cfg)
+	return &testRouter{Handler: New(cfg), mux: mux, v1Routes: v1Routes, db: database}
+}
+
+func TestV1Routes(t *testing.T) {
+	mux := newTestRouter(t).mux
+
+	tests := []struct {
+		method  string
+		path    string
+		pattern string
+	}{
+		{"GET", "/api/v1/links", "GET /api/v1/links"},
+		{"POST", "/api/v1/links", "POST /api/v1/links"},
+		{"GET", "/api/v1/links/7", "GET /api/v1/links/{id}"},
+		{"DELETE", "/api/v1/links/7", "DELETE /api/v1/links/{id}"},
+		{"PUT", "/api/v1/links/7/favorite", "PUT /api/v1/links/{id}/favorite"},
+		{"PUT", "/api/v1/links/7/privacy", "PUT /api/v1/links/{id}/privacy"},
+		{"GET", "/api/v1/public-links", "GET /api/v1/public-links"},
+		{"GET", "/api/v1/me", "GET /api/v1/me"},
+		{"GET", "/api/openapi.json", "GET /api/openapi.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			_, pattern := mux.Handler(httptest.NewRequest(tt.method, tt.path, nil))
+			if pattern != tt.pattern {
+				t.Errorf("%s %s matched %q, want %q", tt.method, tt.path, pattern, tt.pattern)
+			}
+		})
+	}
+}
+
+// Every /api/v1 route is documented and every documented operation has a route
+func TestV1RoutesDocumented(t *testing.T) {
+	ops, err := openapi.Operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	documented := map[string]bool{}
+	for _, op := range ops {
+		documented[op] = true
+	}
+
+	registered := map[string]bool{}
+	for _, route := range newTestRouter(t).v1Routes {
+		registered[route] = true
+		if !documented[route] {
+			t.Errorf("route %s is missing from openapi.json", route)
+		}
+	}
+	for _, op := range ops {
+		if !registered[op] {
+			t.Errorf("documented operation %s has no route", op)
+		}
+	}
+}
+
+// Legacy aliases still work but point integrations at their /api/v1 successor
+func TestLegacyRoutesDeprecated(t *testing.T) {
+	routes := newTestRouter(t)
+
+	tests := []struct {
+		method    string
+		path      string
+		pattern   string
+		successor string // Empty when the route has no /api/v1 equivalent
+	}{
+		{"GET", "/api/links", "GET /api/links", "/api/v1/links"},
+		{"POST", "/api/links", "POST /api/links", "/api/v1/links"},
+		{"DELETE", "/api/links/7", "DELETE /api/links/{id}", "/api/v1/links/7"},
+		{"PUT", "/api/links/7/favorite", "PUT /api/links/{id}/favorite", "/api/v1/links/7/favorite"},
+		{"PUT", "/api/links/7/privacy", "PUT /api/links/{id}/privacy", "/api/v1/links/7/privacy"},
+		{"GET", "/api/public-links", "GET /api/public-links", "/api/v1/public-links"},
+		{"GET", "/api/me", "GET /api/me", "/api/v1/me"},
+		{"POST", "/api/links/bulk", "POST /api/links/bulk", ""},
+		{"PUT", "/api/links/7/access", "PUT /api/links/{id}/access", ""},
+		{"GET", "/api/v1/links", "GET /api/v1/links", ""},
+		{"GET", "/api/v1/public-links", "GET /api/v1/public-links", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if _, pattern := routes.mux.Handler(r); pattern != tt.pattern {
+				t.Fatalf("matched %q, want %q", pattern, tt.pattern)
+			}
+
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, r)
+			deprecation, link := w.Header().Get("Deprecation"), w.Header().Get("Link")
+			if tt.successor == "" {
+				if deprecation != "" || link != "" {
+					t.Errorf("Deprecation = %q, Link = %q on a current route", deprecation, link)
+				}
+				return
+			}
+			if deprecation != "true" {
+				t.Errorf("Deprecation = %q, want true", deprecation)
+			}
+			if want := "<" + tt.successor + `>; rel="successor-version"`; link != want {
+				t.Errorf("Link = %q, want %q", link, want)
+			}
+		})
+	}
+}
+
+func TestUnmatchedRoutes(t *testing.T) {
+	routes := newTestRouter(t)
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		allow  string
+	}{
+		{"GET", "/api/v1/nothing", http.StatusNotFound, ""},
+		{"PATCH", "/api/v1/links/7", http.StatusMethodNotAllowed, "DELETE, GET, HEAD"},
+		{"DELETE", "/api/v1/me", http.StatusMethodNotAllowed, "GET, HEAD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			routes.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Allow = %q, want %q", got, tt.allow)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want JSON", ct)
+			}
+		})
+	}
+}
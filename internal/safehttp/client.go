@@ -0,0 +1,155 @@
+// Package safehttp provides the HTTP client used for every outbound request to a
+// user-supplied URL. Its dialer checks the address actually being connected to, so
+// DNS names resolving to internal addresses, alternative IP encodings and redirects
+// to internal hosts are all refused.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned (wrapped) when a request would connect to a
+// loopback, private, link-local or otherwise non-public address
+var ErrBlockedAddress = errors.New("destination address is not allowed")
+
+// MaxRedirects is the number of redirects a client follows before giving up
+const MaxRedirects = 5
+
+// blockedPrefixes are non-public ranges not already covered by the netip.Addr
+// predicates used in IsBlocked
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // TEST-NET-1
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // TEST-NET-2
+	netip.MustParsePrefix("203.0.113.0/24"),  // TEST-NET-3
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved, including broadcast
+	netip.MustParsePrefix("::/96"),           // Deprecated IPv4-compatible IPv6
+	netip.MustParsePrefix("100::/64"),        // Discard-only
+	netip.MustParsePrefix("2001::/32"),       // Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+}
+
+// embeddedIPv4Prefixes are IPv6 translation ranges whose last 32 bits (or bits
+// 16-48 for 6to4) carry an IPv4 address that must itself be public
+var embeddedIPv4Prefixes = []netip.Prefix{
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"), // Local-use NAT64
+}
+
+var sixToFour = netip.MustParsePrefix("2002::/16")
+
+// IsBlocked reports whether addr is anything other than a public unicast address
+func IsBlocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	if addr.Is6() {
+		b := addr.As16()
+		for _, prefix := range embeddedIPv4Prefixes {
+			if prefix.Contains(addr) {
+				return IsBlocked(netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]}))
+			}
+		}
+		if sixToFour.Contains(addr) {
+			return IsBlocked(netip.AddrFrom4([4]byte{b[2], b[3], b[4], b[5]}))
+		}
+	}
+	return false
+}
+
+// ValidateURL checks the parts of a URL that can be judged without connecting: an
+// http or https scheme, a host and no embedded credentials
+func ValidateURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("missing host")
+	}
+	if u.User != nil {
+		return errors.New("credentials in URL are not allowed")
+	}
+	return nil
+}
+
+// checkConn runs after DNS resolution, just before each connection is made, so it
+// sees the real destination of every request and redirect
+func checkConn(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+	if IsBlocked(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// guardedTransport validates every request URL, including each redirect, before
+// handing it to the underlying transport
+type guardedTransport struct {
+	next http.RoundTripper
+}
+
+func (t guardedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := ValidateURL(req.URL); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// NewClient returns an HTTP client that refuses non-public destinations. It never
+// uses a proxy from the environment, since the proxy would make the connection.
+func NewClient(timeout time.Duration) *http.Client {
+	return newClient(timeout, checkConn)
+}
+
+// newClient builds the client around the dialer's Control hook; tests substitute one
+// that treats a local server as public
+func newClient(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: timeout,
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: guardedTransport{next: transport},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= MaxRedirects {
+				return errors.New("too many redirects")
+			}
+			return nil
+		},
+	}
+}
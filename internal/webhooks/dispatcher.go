@@ -0,0 +1,163 @@
+// Package webhooks delivers queued link events to webhook URLs. Deliveries live in
+// the database, so events survive restarts; a background dispatcher posts them,
+// retrying failures with exponential backoff.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"links/internal/models"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is marked failed
+	MaxAttempts = 6
+
+	// Retries wait backoffBase, then twice as long each time, up to backoffMax
+	backoffBase = 30 * time.Second
+	backoffMax  = time.Hour
+
+	// pollInterval is how often the dispatcher looks for due deliveries, and lease
+	// how long a claimed delivery is hidden from the next poll
+	pollInterval = 5 * time.Second
+	lease        = time.Minute
+	batchSize    = 20
+
+	// Finished deliveries are kept in the log for this long
+	retention = 30 * 24 * time.Hour
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Links-Event"
+	HeaderDelivery  = "X-Links-Delivery"
+	HeaderSignature = "X-Links-Signature"
+)
+
+// Store is the part of the database the dispatcher needs
+type Store interface {
+	ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	RecordWebhookAttempt(d *models.WebhookDelivery, nextAttempt time.Time) error
+	PruneWebhookDeliveries(before time.Time) (int64, error)
+}
+
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+}
+
+// NewDispatcher returns a dispatcher that posts with client. In production client
+// should come from safehttp so webhooks cannot reach internal addresses.
+func NewDispatcher(store Store, client *http.Client) *Dispatcher {
+	return &Dispatcher{store: store, client: client}
+}
+
+// Backoff returns how long to wait after the given number of failed attempts
+func Backoff(attempts int) time.Duration {
+	wait := backoffBase
+	for i := 1; i < attempts && wait < backoffMax; i++ {
+		wait *= 2
+	}
+	if wait > backoffMax {
+		wait = backoffMax
+	}
+	return wait
+}
+
+// Sign returns the X-Links-Signature value for body sent at timestamp: the
+// timestamp and a hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret.
+// Receivers should recompute it and reject stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Run delivers due events until the process exits
+func (d *Dispatcher) Run() {
+	lastPrune := time.Time{}
+	for {
+		now := time.Now()
+		deliveries, err := d.store.ClaimWebhookDeliveries(now, lease, batchSize)
+		if err != nil {
+			log.Printf("Webhook dispatcher failed to load deliveries: %v", err)
+		}
+		for i := range deliveries {
+			d.Deliver(&deliveries[i], true)
+		}
+
+		if now.Sub(lastPrune) > time.Hour {
+			if _, err := d.store.PruneWebhookDeliveries(now.Add(-retention)); err != nil {
+				log.Printf("Webhook delivery prune failed: %v", err)
+			}
+			lastPrune = now
+		}
+
+		// Keep going straight away while there is a backlog
+		if len(deliveries) < batchSize {
+			time.Sleep(pollInterval)
+		}
+	}
+}
+
+// Deliver makes one attempt and records the outcome on the delivery and in the
+// store. With retry false a failure is final, as for test pings.
+func (d *Dispatcher) Deliver(delivery *models.WebhookDelivery, retry bool) {
+	now := time.Now()
+	attemptedAt := now.Format("2006-01-02 15:04:05")
+	delivery.Attempts++
+	delivery.LastAttemptAt = &attemptedAt
+	delivery.ResponseCode, delivery.Error = d.post(delivery, now)
+
+	next := now
+	switch {
+	case delivery.Error == "":
+		delivery.Status = models.DeliveryDelivered
+	case retry && delivery.Attempts < MaxAttempts:
+		delivery.Status = models.DeliveryPending
+		next = now.Add(Backoff(delivery.Attempts))
+	default:
+		delivery.Status = models.DeliveryFailed
+	}
+
+	if err := d.store.RecordWebhookAttempt(delivery, next); err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// post sends the delivery and returns the response status and, unless it was a
+// 2xx, what went wrong
+func (d *Dispatcher) post(delivery *models.WebhookDelivery, now time.Time) (int, string) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest("POST", delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Links-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, now.Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, "unexpected status " + resp.Status
+	}
+	return resp.StatusCode, ""
+}
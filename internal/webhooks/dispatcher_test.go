@@ -0,0 +1,252 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"links/internal/models"
+	"links/internal/safehttp"
+)
+
+// memoryStore records the attempts the dispatcher reports
+type memoryStore struct {
+	attempts []models.WebhookDelivery
+	next     []time.Time
+}
+
+func (s *memoryStore) ClaimWebhookDeliveries(now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (s *memoryStore) RecordWebhookAttempt(d *models.WebhookDelivery, nextAttempt time.Time) error {
+	s.attempts = append(s.attempts, *d)
+	s.next = append(s.next, nextAttempt)
+	return nil
+}
+
+func (s *memoryStore) PruneWebhookDeliveries(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func newDelivery(url string) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:      42,
+		Event:   "link.created",
+		Payload: `{"event":"link.created","created_at":"2025-01-31T18:04:05Z","link":{"id":7,"url":"https://example.com/é"}}`,
+		Status:  models.DeliveryPending,
+		URL:     url,
+		Secret:  "s3cret",
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// The receiver can verify the signature over the exact bytes it received
+func TestDeliverSignsBody(t *testing.T) {
+	var body []byte
+	var header http.Header
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header.Clone()
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{}
+	delivery := newDelivery(receiver.URL)
+	NewDispatcher(store, receiver.Client()).Deliver(delivery, true)
+
+	if delivery.Status != models.DeliveryDelivered || delivery.ResponseCode != http.StatusOK {
+		t.Fatalf("delivery = %s %d %q, want delivered", delivery.Status, delivery.ResponseCode, delivery.Error)
+	}
+	if string(body) != delivery.Payload {
+		t.Errorf("body = %s, want the payload unchanged", body)
+	}
+	if header.Get(HeaderEvent) != "link.created" || header.Get(HeaderDelivery) != "42" {
+		t.Errorf("event headers = %q, %q", header.Get(HeaderEvent), header.Get(HeaderDelivery))
+	}
+	if ct := header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	timestamp, signature, ok := strings.Cut(header.Get(HeaderSignature), ",v1=")
+	if !ok || !strings.HasPrefix(timestamp, "t=") {
+		t.Fatalf("%s = %q", HeaderSignature, header.Get(HeaderSignature))
+	}
+	sent, err := strconv.ParseInt(strings.TrimPrefix(timestamp, "t="), 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Errorf("signature timestamp %q is not current", timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(strings.TrimPrefix(timestamp, "t=") + "."))
+	mac.Write(body)
+	if want := hex.EncodeToString(mac.Sum(nil)); !hmac.Equal([]byte(signature), []byte(want)) {
+		t.Errorf("signature = %s, want %s", signature, want)
+	}
+
+	// A signature over any other body does not match
+	if Sign("s3cret", sent, append(body, ' ')) == header.Get(HeaderSignature) {
+		t.Error("signature does not depend on the exact body")
+	}
+	if Sign("other", sent, body) == header.Get(HeaderSignature) {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int // Zero for a receiver that never answers
+		error  string
+	}{
+		{"server error", http.StatusInternalServerError, "unexpected status 500"},
+		{"unavailable", http.StatusServiceUnavailable, "unexpected status 503"},
+		{"bad gateway", http.StatusBadGateway, "unexpected status 502"},
+		{"timeout", 0, "Timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan struct{})
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status == 0 {
+					<-done
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer receiver.Close()
+			defer close(done)
+			client := receiver.Client()
+			client.Timeout = 100 * time.Millisecond
+
+			store := &memoryStore{}
+			delivery := newDelivery(receiver.URL)
+			before := time.Now()
+			NewDispatcher(store, client).Deliver(delivery, true)
+
+			if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 {
+				t.Fatalf("delivery = %s after %d attempts, want pending after 1", delivery.Status, delivery.Attempts)
+			}
+			if delivery.ResponseCode != tt.status || !strings.Contains(delivery.Error, tt.error) {
+				t.Errorf("outcome = %d %q, want %d %q", delivery.ResponseCode, delivery.Error, tt.status, tt.error)
+			}
+			if len(store.next) != 1 {
+				t.Fatalf("%d attempts recorded, want 1", len(store.next))
+			}
+			if wait := store.next[0].Sub(before); wait < Backoff(1) || wait > Backoff(1)+5*time.Second {
+				t.Errorf("next attempt in %v, want %v", wait, Backoff(1))
+			}
+		})
+	}
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	store := &memoryStore{}
+	dispatcher := NewDispatcher(store, receiver.Client())
+	delivery := newDelivery(receiver.URL)
+	for i := 1; i <= MaxAttempts; i++ {
+		before := time.Now()
+		dispatcher.Deliver(delivery, true)
+		wait := store.next[i-1].Sub(before)
+
+		if i < MaxAttempts {
+			if delivery.Status != models.DeliveryPending {
+				t.Fatalf("attempt %d: status %s, want pending", i, delivery.Status)
+			}
+			if wait < Backoff(i) || wait > Backoff(i)+5*time.Second {
+				t.Errorf("attempt %d: next attempt in %v, want %v", i, wait, Backoff(i))
+			}
+			continue
+		}
+		if delivery.Status != models.DeliveryFailed {
+			t.Fatalf("attempt %d: status %s, want failed", i, delivery.Status)
+		}
+		if wait > 5*time.Second {
+			t.Errorf("failed delivery scheduled %v ahead", wait)
+		}
+	}
+	if hits.Load() != MaxAttempts || delivery.Attempts != MaxAttempts {
+		t.Errorf("%d requests, %d attempts; want %d", hits.Load(), delivery.Attempts, MaxAttempts)
+	}
+}
+
+// Test pings are not retried
+func TestDeliverWithoutRetry(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	delivery := newDelivery(receiver.URL)
+	NewDispatcher(&memoryStore{}, receiver.Client()).Deliver(delivery, false)
+	if delivery.Status != models.DeliveryFailed || delivery.Attempts != 1 {
+		t.Errorf("delivery = %s after %d attempts, want failed after 1", delivery.Status, delivery.Attempts)
+	}
+}
+
+// With the production client webhooks cannot reach the server's own network
+func TestDeliverBlocksInternalTargets(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer receiver.Close()
+	port := receiver.URL[strings.LastIndex(receiver.URL, ":"):]
+
+	dispatcher := NewDispatcher(&memoryStore{}, safehttp.NewClient(2*time.Second))
+	for _, target := range []string{
+		receiver.URL,                          // Loopback
+		"http://localhost" + port,             // Name resolving to loopback
+		"http://[::ffff:127.0.0.1]" + port,    // IPv4-mapped IPv6
+		"http://10.0.0.1/hook",                // RFC 1918
+		"http://169.254.169.254/latest/meta/", // Cloud metadata
+		"http://[fd00::1]/hook",               // IPv6 ULA
+	} {
+		t.Run(target, func(t *testing.T) {
+			delivery := newDelivery(target)
+			dispatcher.Deliver(delivery, true)
+			if delivery.Status == models.DeliveryDelivered || !strings.Contains(delivery.Error, safehttp.ErrBlockedAddress.Error()) {
+				t.Errorf("delivery = %s %q, want blocked", delivery.Status, delivery.Error)
+			}
+		})
+	}
+	if hits.Load() != 0 {
+		t.Errorf("%d deliveries reached the internal receiver", hits.Load())
+	}
+}
@@ -3,180 +3,105 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
+	"time"
 
 	"links/internal/auth"
 	"links/internal/db"
+	"links/internal/events"
+	"links/internal/feeds"
 	"links/internal/handlers"
+	"links/internal/mailer"
 	"links/internal/middleware"
+	"links/internal/models"
+	"links/internal/router"
+	"links/internal/safehttp"
+	"links/internal/webhooks"
 )
 
 var (
 	database  *db.Database
+	mail      mailer.Mailer
+	hooks     *webhooks.Dispatcher
+	poller    *feeds.Poller
 	staticDir string
 	dataDir   string
 )
 
-func handler(w http.ResponseWriter, r *http.Request) {
-	authHandler := handlers.NewAuthHandler(database)
-	linksHandler := handlers.NewLinksHandler(database)
-	oauthHandler := handlers.NewOAuthHandler(database)
-	adminHandler := handlers.NewAdminHandler(database)
-	metadataHandler := handlers.NewMetadataHandler()
-
-	// Auth endpoints (no auth required) - with rate limiting
-	if r.URL.Path == "/api/register" && r.Method == "POST" {
-		middleware.AuthRateLimit(http.HandlerFunc(authHandler.Register)).ServeHTTP(w, r)
-		return
-	}
-	if r.URL.Path == "/api/login" && r.Method == "POST" {
-		middleware.AuthRateLimit(http.HandlerFunc(authHandler.Login)).ServeHTTP(w, r)
-		return
-	}
-
-	// OAuth endpoints
-	if r.URL.Path == "/api/auth/google" && r.Method == "GET" {
-		oauthHandler.GoogleLogin(w, r)
-		return
+func initDB() error {
+	// Ensure data directory exists
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return err
 	}
-	if r.URL.Path == "/api/auth/google/callback" && r.Method == "GET" {
-		oauthHandler.GoogleCallback(w, r)
-		return
+	
+	var err error
+	database, err = db.New(filepath.Join(dataDir, "links.db"))
+	if err != nil {
+		return err
 	}
+	return nil
+}
 
-	// Protected endpoints
-	if r.URL.Path == "/api/links" {
-		switch r.Method {
-		case "POST":
-			middleware.AuthMiddleware(linksHandler.CreateLink)(w, r)
-		case "GET":
-			middleware.AuthMiddleware(linksHandler.GetLinks)(w, r)
-		default:
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// purgeTrash hard-deletes soft-deleted links and users older than TRASH_RETENTION_DAYS
+// (default 30), checking hourly. A value of 0 keeps the trash forever.
+func purgeTrash() {
+	days := 30
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			log.Printf("Invalid TRASH_RETENTION_DAYS %q, using %d", value, days)
+		} else {
+			days = parsed
 		}
-		return
-	}
-
-	// Handle DELETE /api/links/:id
-	if strings.HasPrefix(r.URL.Path, "/api/links/") && r.Method == "DELETE" {
-		middleware.AuthMiddleware(linksHandler.DeleteLink)(w, r)
-		return
-	}
-
-	// Handle PUT /api/links/:id/favorite
-	if strings.HasPrefix(r.URL.Path, "/api/links/") && strings.HasSuffix(r.URL.Path, "/favorite") && r.Method == "PUT" {
-		middleware.AuthMiddleware(linksHandler.ToggleFavorite)(w, r)
-		return
-	}
-
-	// Handle PUT /api/links/:id/access
-	if strings.HasPrefix(r.URL.Path, "/api/links/") && strings.HasSuffix(r.URL.Path, "/access") && r.Method == "PUT" {
-		middleware.AuthMiddleware(linksHandler.IncrementAccess)(w, r)
-		return
-	}
-
-	// Handle PUT /api/links/:id/privacy
-	if strings.HasPrefix(r.URL.Path, "/api/links/") && strings.HasSuffix(r.URL.Path, "/privacy") && r.Method == "PUT" {
-		middleware.AuthMiddleware(linksHandler.TogglePrivacy)(w, r)
-		return
-	}
-
-	// Metadata extraction endpoint - with rate limiting and auth
-	if r.URL.Path == "/api/metadata" && r.Method == "GET" {
-		middleware.MetadataRateLimit(
-			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				middleware.AuthMiddleware(metadataHandler.ExtractMetadata)(w, r)
-			}),
-		).ServeHTTP(w, r)
-		return
 	}
-
-	// Public links endpoint (no auth required)
-	if r.URL.Path == "/api/public-links" && r.Method == "GET" {
-		linksHandler.GetPublicLinks(w, r)
+	if days == 0 {
 		return
 	}
 
-	// Admin endpoints - require admin authentication
-	if strings.HasPrefix(r.URL.Path, "/api/admin/") {
-		switch {
-		case r.URL.Path == "/api/admin/links" && r.Method == "GET":
-			middleware.AuthMiddleware(adminHandler.GetAllLinks)(w, r)
-		case r.URL.Path == "/api/admin/users" && r.Method == "GET":
-			middleware.AuthMiddleware(adminHandler.GetAllUsers)(w, r)
-		case strings.HasPrefix(r.URL.Path, "/api/admin/links/") && strings.HasSuffix(r.URL.Path, "/delete") && r.Method == "DELETE":
-			middleware.AuthMiddleware(adminHandler.DeleteLink)(w, r)
-		case strings.HasPrefix(r.URL.Path, "/api/admin/links/") && strings.HasSuffix(r.URL.Path, "/lock") && r.Method == "PUT":
-			middleware.AuthMiddleware(adminHandler.ToggleLinkLock)(w, r)
-		case strings.HasPrefix(r.URL.Path, "/api/admin/links/") && strings.HasSuffix(r.URL.Path, "/force-private") && r.Method == "PUT":
-			middleware.AuthMiddleware(adminHandler.ForcePrivateLink)(w, r)
-		case strings.HasPrefix(r.URL.Path, "/api/admin/users/") && strings.HasSuffix(r.URL.Path, "/admin") && r.Method == "PUT":
-			middleware.AuthMiddleware(adminHandler.ToggleUserAdmin)(w, r)
-		case strings.HasPrefix(r.URL.Path, "/api/admin/users/") && strings.HasSuffix(r.URL.Path, "/delete") && r.Method == "DELETE":
-			middleware.AuthMiddleware(adminHandler.DeleteUser)(w, r)
-		default:
-			http.Error(w, "Not found", http.StatusNotFound)
+	for {
+		links, users, err := database.PurgeDeleted(time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log.Printf("Trash purge failed: %v", err)
+		} else if links > 0 || users > 0 {
+			log.Printf("Trash purge removed %d links and %d users", links, users)
+			database.WriteAudit(models.AuditEntry{
+				ActorUsername: "system",
+				Action:        "trash.purge",
+				After:         fmt.Sprintf(`{"links":%d,"users":%d}`, links, users),
+			})
 		}
-		return
-	}
-
-	// Serve login page
-	if r.URL.Path == "/login" {
-		// Set no-cache headers for login page
-		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-		w.Header().Set("Pragma", "no-cache")
-		w.Header().Set("Expires", "0")
-		http.ServeFile(w, r, filepath.Join(staticDir, "login.html"))
-		return
-	}
-
-	// Serve admin page
-	if r.URL.Path == "/admin" {
-		// Set no-cache headers for admin page
-		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-		w.Header().Set("Pragma", "no-cache")
-		w.Header().Set("Expires", "0")
-		http.ServeFile(w, r, filepath.Join(staticDir, "admin.html"))
-		return
+		time.Sleep(time.Hour)
 	}
-
-	// Serve static files
-	if r.URL.Path == "/" {
-		// Set no-cache headers for main page
-		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-		w.Header().Set("Pragma", "no-cache")
-		w.Header().Set("Expires", "0")
-		http.ServeFile(w, r, filepath.Join(staticDir, "index.html"))
-		return
-	}
-
-	// For other static files, use file server with conditional caching
-	path := r.URL.Path
-	// Disable cache for JS, CSS, and HTML files to see updates immediately
-	if strings.HasSuffix(path, ".js") || strings.HasSuffix(path, ".css") || strings.HasSuffix(path, ".html") {
-		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-		w.Header().Set("Pragma", "no-cache")
-		w.Header().Set("Expires", "0")
-	}
-	fileServer := http.FileServer(http.Dir(staticDir))
-	fileServer.ServeHTTP(w, r)
 }
 
-func initDB() error {
-	// Ensure data directory exists
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return err
+// pruneLoginFailures deletes failed login records older than
+// FAILED_LOGIN_RETENTION_DAYS (default 90) and counters that no longer count towards
+// a lockout, checking hourly. Failed logins are recorded for any username, so without
+// this the table only grows.
+func pruneLoginFailures() {
+	days := 90
+	if value := os.Getenv("FAILED_LOGIN_RETENTION_DAYS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			log.Printf("Invalid FAILED_LOGIN_RETENTION_DAYS %q, using %d", value, days)
+		} else {
+			days = parsed
+		}
 	}
-	
-	var err error
-	database, err = db.New(filepath.Join(dataDir, "links.db"))
-	if err != nil {
-		return err
+
+	for {
+		deleted, err := database.PruneLoginFailures(time.Now().AddDate(0, 0, -days), time.Now().Add(-auth.LockoutWindow))
+		if err != nil {
+			log.Printf("Failed login pruning failed: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Pruned %d failed login records", deleted)
+		}
+		time.Sleep(time.Hour)
 	}
-	return nil
 }
 
 func initPaths() error {
@@ -222,15 +147,40 @@ func main() {
 		panic("Failed to initialize paths: " + err.Error())
 	}
 
-	// Initialize OAuth
+	// Initialize OAuth and WebAuthn relying party settings
 	auth.InitOAuth()
+	auth.InitWebAuthn()
+
+	// Outgoing mail for password resets and email verification
+	mail = mailer.New()
 
 	if err := initDB(); err != nil {
 		panic("Failed to connect to database: " + err.Error())
 	}
 	defer database.Close()
 
-	http.Handle("/", middleware.CorsMiddleware(middleware.GeneralRateLimit(http.HandlerFunc(handler))))
+	// Reject tokens for deleted users or revoked sessions
+	middleware.SetSessionStore(database)
+
+	// Permanently remove trashed links and users after the retention period
+	go purgeTrash()
+
+	// Forget old failed logins and stale lockout counters
+	go pruneLoginFailures()
+
+	// Push link events to connected clients, keeping the last 1000 for reconnects
+	handlers.SetEventBroker(events.NewBroker(1000, 64, 1000))
+
+	// Deliver queued webhook events in the background, retrying failures
+	hooks = webhooks.NewDispatcher(database, safehttp.NewClient(10*time.Second))
+	go hooks.Run()
+
+	// Poll subscribed feeds in the background, saving new items as links
+	poller = feeds.NewPoller(database, safehttp.NewClient(15*time.Second), handlers.NewLinksHandler(database).SaveFeedEntry)
+	go poller.Run()
+
+	routes := router.New(router.Config{DB: database, Mailer: mail, Webhooks: hooks, Poller: poller, StaticDir: staticDir})
+	http.Handle("/", middleware.RequestID(middleware.CorsMiddleware(middleware.GeneralRateLimit(routes))))
 
 	fmt.Printf("Server started at port %v\n", *port)
 	fmt.Printf("Static files: %s\n", staticDir)
@@ -8,10 +8,23 @@ const AdminApp = {
     const token = ref(localStorage.getItem('token'))
     const allUsers = ref([])
     const allLinks = ref([])
+    const userFilters = ref({ q: '', role: '', sort: 'created_at', order: 'desc' })
+    const linkFilters = ref({ q: '', username: '', domain: '', private: '', locked: '', since: '', until: '', sort: 'created_at', order: 'desc' })
+    const userPage = ref({ total: 0, limit: 50, offset: 0 })
+    const linkPage = ref({ total: 0, limit: 50, offset: 0 })
+    const selectedLinks = ref([])
+    const deletedUsers = ref([])
+    const reports = ref([])
+    const domainRules = ref([])
+    const stats = ref(null)
+    const domainForm = ref({ pattern: '', action: 'block', note: '', apply_existing: false })
     const activeTab = ref('users')
     const loading = ref(false)
     const error = ref('')
 
+    // Moderators only see the links tab; user management needs the admin role
+    const isAdmin = () => user.value?.role === 'admin' || user.value?.isAdmin
+
     const checkAuth = () => {
       if (!token.value) {
         window.location.href = '/login'
@@ -22,7 +35,7 @@ const AdminApp = {
         const userData = localStorage.getItem('user')
         if (userData) {
           user.value = JSON.parse(userData)
-          if (!user.value.isAdmin) {
+          if (!isAdmin() && user.value.role !== 'moderator') {
             error.value = 'Admin access required'
             setTimeout(() => {
               window.location.href = '/'
@@ -39,12 +52,101 @@ const AdminApp = {
       return true
     }
 
+    // Query string for a paginated admin listing, skipping empty filters
+    const listQuery = (filters, page) => {
+      const params = new URLSearchParams()
+      Object.entries(filters).forEach(([key, value]) => {
+        if (value !== '') params.set(key, value)
+      })
+      params.set('limit', page.limit)
+      params.set('offset', page.offset)
+      return params.toString()
+    }
+
+    // Message from the API's JSON error body, or fallback when there is none
+    const errorMessage = async (response, fallback) => {
+      try {
+        const body = await response.json()
+        return body.error?.message || fallback
+      } catch (e) {
+        return fallback
+      }
+    }
+
+    const searchUsers = () => {
+      userPage.value.offset = 0
+      fetchUsers()
+    }
+
+    // Applies a moderation action to the selected links, or to every link matching
+    // the current filters when nothing is selected
+    const bulkLinks = async (action) => {
+      const body = { action }
+      if (selectedLinks.value.length > 0) {
+        body.ids = selectedLinks.value
+      } else {
+        const { sort, order, ...filter } = linkFilters.value
+        Object.keys(filter).forEach(key => {
+          if (filter[key] === '') delete filter[key]
+          else if (key === 'private' || key === 'locked') filter[key] = filter[key] === 'true'
+        })
+        if (Object.keys(filter).length === 0) {
+          error.value = 'Select links or set a filter first'
+          return
+        }
+        if (!confirm(`Apply "${action}" to all ${linkPage.value.total} links matching the filters?`)) return
+        body.filter = filter
+      }
+      
+      if (!token.value) return
+      
+      try {
+        const response = await fetch('/api/admin/links/bulk', {
+          method: 'POST',
+          headers: {
+            'Authorization': `Bearer ${token.value}`,
+            'Content-Type': 'application/json'
+          },
+          body: JSON.stringify(body)
+        })
+        
+        if (response.ok) {
+          const result = await response.json()
+          if (result.failed > 0) {
+            error.value = `${result.succeeded} links updated, ${result.failed} failed`
+          }
+          selectedLinks.value = []
+          await fetchLinks()
+        } else {
+          error.value = await errorMessage(response, 'Failed to apply bulk action')
+        }
+      } catch (err) {
+        error.value = 'Network error while applying bulk action'
+      }
+    }
+
+    const searchLinks = () => {
+      linkPage.value.offset = 0
+      selectedLinks.value = []
+      fetchLinks()
+    }
+
+    const pageUsers = (direction) => {
+      userPage.value.offset = Math.max(0, userPage.value.offset + direction * userPage.value.limit)
+      fetchUsers()
+    }
+
+    const pageLinks = (direction) => {
+      linkPage.value.offset = Math.max(0, linkPage.value.offset + direction * linkPage.value.limit)
+      fetchLinks()
+    }
+
     const fetchUsers = async () => {
       if (!token.value) return
       
       try {
         loading.value = true
-        const response = await fetch('/api/admin/users', {
+        const response = await fetch(`/api/admin/users?${listQuery(userFilters.value, userPage.value)}`, {
           headers: {
             'Authorization': `Bearer ${token.value}`,
             'Content-Type': 'application/json'
@@ -52,7 +154,9 @@ const AdminApp = {
         })
         
         if (response.ok) {
-          allUsers.value = await response.json()
+          const page = await response.json()
+          allUsers.value = page.users
+          userPage.value = { total: page.total, limit: page.limit, offset: page.offset }
         } else {
           error.value = 'Failed to fetch users'
         }
@@ -68,7 +172,7 @@ const AdminApp = {
       
       try {
         loading.value = true
-        const response = await fetch('/api/admin/links', {
+        const response = await fetch(`/api/admin/links?${listQuery(linkFilters.value, linkPage.value)}`, {
           headers: {
             'Authorization': `Bearer ${token.value}`,
             'Content-Type': 'application/json'
@@ -76,7 +180,9 @@ const AdminApp = {
         })
         
         if (response.ok) {
-          allLinks.value = await response.json()
+          const page = await response.json()
+          allLinks.value = page.links
+          linkPage.value = { total: page.total, limit: page.limit, offset: page.offset }
         } else {
           error.value = 'Failed to fetch links'
         }
@@ -87,31 +193,261 @@ const AdminApp = {
       }
     }
 
-    const toggleUserAdmin = async (userId, isAdmin) => {
+    const setUserRole = async (userId, role) => {
       if (!token.value) return
       
       try {
-        const response = await fetch(`/api/admin/users/${userId}/admin`, {
+        const response = await fetch(`/api/admin/users/${userId}/role`, {
           method: 'PUT',
           headers: {
             'Authorization': `Bearer ${token.value}`,
             'Content-Type': 'application/json'
           },
-          body: JSON.stringify({ is_admin: isAdmin })
+          body: JSON.stringify({ role })
         })
         
         if (response.ok) {
           await fetchUsers()
         } else {
-          error.value = 'Failed to toggle admin status'
+          error.value = 'Failed to update role'
         }
       } catch (err) {
         error.value = 'Network error while updating user'
       }
     }
 
+    const fetchReports = async () => {
+      if (!token.value) return
+      
+      try {
+        loading.value = true
+        const response = await fetch('/api/admin/reports', {
+          headers: {
+            'Authorization': `Bearer ${token.value}`
+          }
+        })
+        
+        if (response.ok) {
+          reports.value = await response.json()
+        } else {
+          error.value = 'Failed to fetch reports'
+        }
+      } catch (err) {
+        error.value = 'Network error while fetching reports'
+      } finally {
+        loading.value = false
+      }
+    }
+
+    const resolveReport = async (reportId, action) => {
+      if (action === 'suspend' && !confirm('Suspend the owner of this link?')) {
+        return
+      }
+      
+      if (!token.value) return
+      
+      try {
+        const response = await fetch(`/api/admin/reports/${reportId}/resolve`, {
+          method: 'POST',
+          headers: {
+            'Authorization': `Bearer ${token.value}`,
+            'Content-Type': 'application/json'
+          },
+          body: JSON.stringify({ action })
+        })
+        
+        if (response.ok) {
+          await fetchReports()
+        } else {
+          error.value = 'Failed to resolve report'
+        }
+      } catch (err) {
+        error.value = 'Network error while resolving report'
+      }
+    }
+
+    const fetchStats = async () => {
+      if (!token.value) return
+      
+      try {
+        loading.value = true
+        const response = await fetch('/api/admin/stats', {
+          headers: {
+            'Authorization': `Bearer ${token.value}`
+          }
+        })
+        
+        if (response.ok) {
+          stats.value = await response.json()
+        } else {
+          error.value = 'Failed to fetch stats'
+        }
+      } catch (err) {
+        error.value = 'Network error while fetching stats'
+      } finally {
+        loading.value = false
+      }
+    }
+
+    // Bar height for a day in a series, relative to the busiest day
+    const barHeight = (series, count) => {
+      const max = Math.max(1, ...series.map(d => d.count))
+      return `${Math.round((count / max) * 100)}%`
+    }
+
+    const formatBytes = (bytes) => {
+      if (bytes < 1024) return `${bytes} B`
+      if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`
+      return `${(bytes / (1024 * 1024)).toFixed(1)} MB`
+    }
+
+    const fetchDomainRules = async () => {
+      if (!token.value) return
+      
+      try {
+        loading.value = true
+        const response = await fetch('/api/admin/domains', {
+          headers: {
+            'Authorization': `Bearer ${token.value}`
+          }
+        })
+        
+        if (response.ok) {
+          domainRules.value = await response.json()
+        } else {
+          error.value = 'Failed to fetch domain rules'
+        }
+      } catch (err) {
+        error.value = 'Network error while fetching domain rules'
+      } finally {
+        loading.value = false
+      }
+    }
+
+    const saveDomainRule = async () => {
+      if (!token.value || !domainForm.value.pattern.trim()) return
+      
+      try {
+        const response = await fetch('/api/admin/domains', {
+          method: 'POST',
+          headers: {
+            'Authorization': `Bearer ${token.value}`,
+            'Content-Type': 'application/json'
+          },
+          body: JSON.stringify(domainForm.value)
+        })
+        
+        if (response.ok) {
+          const result = await response.json()
+          if (domainForm.value.apply_existing) {
+            alert(`${result.forced_private} existing link(s) forced private`)
+          }
+          domainForm.value = { pattern: '', action: 'block', note: '', apply_existing: false }
+          await fetchDomainRules()
+        } else {
+          error.value = await errorMessage(response, 'Failed to save domain rule')
+        }
+      } catch (err) {
+        error.value = 'Network error while saving domain rule'
+      }
+    }
+
+    const deleteDomainRule = async (ruleId) => {
+      if (!confirm('Delete this domain rule?')) {
+        return
+      }
+      
+      if (!token.value) return
+      
+      try {
+        const response = await fetch(`/api/admin/domains/${ruleId}`, {
+          method: 'DELETE',
+          headers: {
+            'Authorization': `Bearer ${token.value}`
+          }
+        })
+        
+        if (response.ok) {
+          await fetchDomainRules()
+        } else {
+          error.value = 'Failed to delete domain rule'
+        }
+      } catch (err) {
+        error.value = 'Network error while deleting domain rule'
+      }
+    }
+
+    const fetchDeletedUsers = async () => {
+      if (!token.value) return
+      
+      try {
+        loading.value = true
+        const response = await fetch('/api/admin/users/deleted', {
+          headers: {
+            'Authorization': `Bearer ${token.value}`
+          }
+        })
+        
+        if (response.ok) {
+          deletedUsers.value = await response.json()
+        } else {
+          error.value = 'Failed to fetch deleted users'
+        }
+      } catch (err) {
+        error.value = 'Network error while fetching deleted users'
+      } finally {
+        loading.value = false
+      }
+    }
+
+    const restoreUser = async (userId) => {
+      if (!token.value) return
+      
+      try {
+        const response = await fetch(`/api/admin/users/${userId}/restore`, {
+          method: 'PUT',
+          headers: {
+            'Authorization': `Bearer ${token.value}`
+          }
+        })
+        
+        if (response.ok) {
+          await fetchDeletedUsers()
+        } else {
+          error.value = 'Failed to restore user'
+        }
+      } catch (err) {
+        error.value = 'Network error while restoring user'
+      }
+    }
+
+    const purgeUser = async (userId) => {
+      if (!confirm('Permanently delete this user and all their links? This cannot be undone.')) {
+        return
+      }
+      
+      if (!token.value) return
+      
+      try {
+        const response = await fetch(`/api/admin/users/${userId}/purge`, {
+          method: 'DELETE',
+          headers: {
+            'Authorization': `Bearer ${token.value}`
+          }
+        })
+        
+        if (response.ok) {
+          await fetchDeletedUsers()
+        } else {
+          error.value = 'Failed to purge user'
+        }
+      } catch (err) {
+        error.value = 'Network error while purging user'
+      }
+    }
+
     const deleteUser = async (userId) => {
-      if (!confirm('Are you sure you want to delete this user? This will also delete all their links.')) {
+      if (!confirm('Are you sure you want to delete this user? Their links will be hidden and the account can be restored from Deleted Users until it is purged.')) {
         return
       }
       
@@ -135,6 +471,90 @@ const AdminApp = {
       }
     }
 
+    const isSuspended = (u) => {
+      if (!u.suspended_at) return false
+      return !u.suspended_until || new Date(u.suspended_until.replace(' ', 'T')) > new Date()
+    }
+
+    const suspendUser = async (userId) => {
+      const reason = prompt('Reason for the suspension:')
+      if (!reason || !reason.trim()) return
+      const until = prompt('Suspend until (YYYY-MM-DD), or leave empty to suspend until lifted:', '')
+      if (until === null) return
+      
+      if (!token.value) return
+      
+      try {
+        const response = await fetch(`/api/admin/users/${userId}/suspend`, {
+          method: 'PUT',
+          headers: {
+            'Authorization': `Bearer ${token.value}`,
+            'Content-Type': 'application/json'
+          },
+          body: JSON.stringify({ reason: reason.trim(), until: until.trim() })
+        })
+        
+        if (response.ok) {
+          await fetchUsers()
+        } else {
+          error.value = await errorMessage(response, 'Failed to suspend user')
+        }
+      } catch (err) {
+        error.value = 'Network error while suspending user'
+      }
+    }
+
+    const unsuspendUser = async (userId) => {
+      if (!token.value) return
+      
+      try {
+        const response = await fetch(`/api/admin/users/${userId}/unsuspend`, {
+          method: 'PUT',
+          headers: {
+            'Authorization': `Bearer ${token.value}`
+          }
+        })
+        
+        if (response.ok) {
+          await fetchUsers()
+        } else {
+          error.value = 'Failed to lift suspension'
+        }
+      } catch (err) {
+        error.value = 'Network error while lifting suspension'
+      }
+    }
+
+    // Opens the app as the user in this tab. The impersonation token is kept in
+    // sessionStorage so the admin's own session in localStorage is left untouched.
+    const viewAsUser = async (u) => {
+      const reason = prompt(`Reason for viewing as ${u.username}:`)
+      if (!reason || !reason.trim()) return
+      const allowWrites = confirm('Allow making changes as this user? Cancel for a read-only session.')
+      
+      if (!token.value) return
+      
+      try {
+        const response = await fetch(`/api/admin/users/${u.id}/impersonate`, {
+          method: 'POST',
+          headers: {
+            'Authorization': `Bearer ${token.value}`,
+            'Content-Type': 'application/json'
+          },
+          body: JSON.stringify({ reason: reason.trim(), allow_writes: allowWrites })
+        })
+        
+        if (response.ok) {
+          sessionStorage.setItem('impersonation', JSON.stringify(await response.json()))
+          window.location.href = '/'
+        } else {
+          error.value = await errorMessage(response, 'Failed to view as user')
+        }
+      } catch (err) {
+        error.value = 'Network error while starting impersonation'
+      }
+    }
+
     const deleteLink = async (linkId) => {
       if (!confirm('Are you sure you want to delete this link?')) {
         return
@@ -222,12 +642,24 @@ const AdminApp = {
         fetchUsers()
       } else if (tab === 'links') {
         fetchLinks()
+      } else if (tab === 'reports') {
+        fetchReports()
+      } else if (tab === 'deleted') {
+        fetchDeletedUsers()
+      } else if (tab === 'domains') {
+        fetchDomainRules()
+      } else if (tab === 'stats') {
+        fetchStats()
       }
     }
 
     onMounted(() => {
       if (checkAuth()) {
-        fetchUsers()
+        if (isAdmin()) {
+          fetchUsers()
+        } else {
+          switchTab('links')
+        }
       }
     })
 
@@ -235,12 +667,39 @@ const AdminApp = {
       user,
       allUsers,
       allLinks,
+      userFilters,
+      linkFilters,
+      userPage,
+      linkPage,
+      searchUsers,
+      searchLinks,
+      selectedLinks,
+      bulkLinks,
+      pageUsers,
+      pageLinks,
+      deletedUsers,
+      reports,
+      resolveReport,
+      stats,
+      barHeight,
+      formatBytes,
+      domainRules,
+      domainForm,
+      saveDomainRule,
+      deleteDomainRule,
       activeTab,
       loading,
       error,
+      isAdmin,
       switchTab,
-      toggleUserAdmin,
+      setUserRole,
       deleteUser,
+      isSuspended,
+      suspendUser,
+      unsuspendUser,
+      viewAsUser,
+      restoreUser,
+      purgeUser,
       deleteLink,
       toggleLinkLock,
       forcePrivateLink,
@@ -254,7 +713,7 @@ const AdminApp = {
         <div class="header-content">
           <h1>🔗 Admin Panel</h1>
           <div class="user-info">
-            <span v-if="user">{{ user.username }} (Admin)</span>
+            <span v-if="user">{{ user.username }} ({{ isAdmin() ? 'Admin' : 'Moderator' }})</span>
             <button @click="logout" class="logout-btn">Logout</button>
           </div>
         </div>
@@ -262,6 +721,7 @@ const AdminApp = {
 
       <nav class="admin-nav">
         <button 
+          v-if="isAdmin()"
           @click="switchTab('users')" 
           :class="['nav-btn', { active: activeTab === 'users' }]"
         >
@@ -273,29 +733,210 @@ const AdminApp = {
         >
           Links
         </button>
+        <button 
+          @click="switchTab('reports')" 
+          :class="['nav-btn', { active: activeTab === 'reports' }]"
+        >
+          Reports
+        </button>
+        <button 
+          v-if="isAdmin()"
+          @click="switchTab('deleted')" 
+          :class="['nav-btn', { active: activeTab === 'deleted' }]"
+        >
+          Deleted Users
+        </button>
+        <button 
+          v-if="isAdmin()"
+          @click="switchTab('domains')" 
+          :class="['nav-btn', { active: activeTab === 'domains' }]"
+        >
+          Domains
+        </button>
+        <button 
+          v-if="isAdmin()"
+          @click="switchTab('stats')" 
+          :class="['nav-btn', { active: activeTab === 'stats' }]"
+        >
+          Stats
+        </button>
       </nav>
 
       <div v-if="error" class="error-message">{{ error }}</div>
       <div v-if="loading" class="loading">Loading...</div>
 
+      <!-- Reports Tab -->
+      <div v-if="activeTab === 'reports'" class="admin-section">
+        <h2>Moderation Queue</h2>
+        <div v-if="reports.length === 0" class="empty-message">No open reports</div>
+        <div v-else class="links-list">
+          <div v-for="report in reports" :key="report.id" class="link-item">
+            <div class="link-info">
+              <a :href="report.link_url" target="_blank" rel="noopener noreferrer" class="link-url">{{ report.link_url }}</a>
+              <span class="link-meta">
+                Owner: {{ report.link_owner }} | Reported by: {{ report.reporter }} | {{ report.reason }} | {{ report.created_at }}
+              </span>
+              <p v-if="report.details" class="link-description">{{ report.details }}</p>
+            </div>
+            <div class="link-actions">
+              <button @click="resolveReport(report.id, 'dismiss')" class="admin-toggle-btn">Dismiss</button>
+              <button @click="resolveReport(report.id, 'force_private')" class="private-btn">Force Private</button>
+              <button v-if="isAdmin()" @click="resolveReport(report.id, 'delete')" class="delete-btn">Delete Link</button>
+              <button v-if="isAdmin()" @click="resolveReport(report.id, 'suspend')" class="delete-btn">Suspend Owner</button>
+            </div>
+          </div>
+        </div>
+      </div>
+
+      <!-- Stats Tab -->
+      <div v-if="activeTab === 'stats' && stats" class="admin-section">
+        <h2>Statistics</h2>
+        <div class="stats-grid">
+          <div class="stat-card"><strong>{{ stats.total_users }}</strong><span>Users</span></div>
+          <div class="stat-card"><strong>{{ stats.active_users_7d }} / {{ stats.active_users_30d }}</strong><span>Active (7d / 30d)</span></div>
+          <div class="stat-card"><strong>{{ stats.total_links }}</strong><span>Links</span></div>
+          <div class="stat-card"><strong>{{ Math.round(stats.public_ratio * 100) }}%</strong><span>Public ({{ stats.public_links }} / {{ stats.private_links }} private)</span></div>
+          <div class="stat-card"><strong>{{ formatBytes(stats.storage_bytes) }}</strong><span>Database ({{ formatBytes(stats.free_bytes) }} free)</span></div>
+        </div>
+        <h3>New links per day (last {{ stats.days }} days)</h3>
+        <div class="stats-chart">
+          <div v-for="d in stats.new_links" :key="d.date" class="stats-bar" :style="{ height: barHeight(stats.new_links, d.count) }" :title="d.date + ': ' + d.count"></div>
+        </div>
+        <h3>New users per day</h3>
+        <div class="stats-chart">
+          <div v-for="d in stats.new_users" :key="d.date" class="stats-bar" :style="{ height: barHeight(stats.new_users, d.count) }" :title="d.date + ': ' + d.count"></div>
+        </div>
+        <div class="stats-grid">
+          <div>
+            <h3>Top domains</h3>
+            <ol><li v-for="d in stats.top_domains" :key="d.name">{{ d.name }} ({{ d.count }})</li></ol>
+          </div>
+          <div>
+            <h3>Top tags</h3>
+            <ol><li v-for="t in stats.top_tags" :key="t.name">{{ t.name }} ({{ t.count }})</li></ol>
+          </div>
+          <div>
+            <h3>Most accessed</h3>
+            <ol><li v-for="l in stats.most_accessed" :key="l.id"><a :href="l.url" target="_blank" rel="noopener noreferrer" class="link-url">{{ l.url }}</a> ({{ l.access_count }})</li></ol>
+          </div>
+        </div>
+      </div>
+
+      <!-- Domains Tab -->
+      <div v-if="activeTab === 'domains'" class="admin-section">
+        <h2>Domain Policy</h2>
+        <form @submit.prevent="saveDomainRule" class="domain-form">
+          <input v-model="domainForm.pattern" placeholder="example.com or *.example.com" required />
+          <select v-model="domainForm.action" class="role-select">
+            <option value="block">Block</option>
+            <option value="private_only">Private only</option>
+            <option value="allow">Allow (exception)</option>
+          </select>
+          <input v-model="domainForm.note" placeholder="Note (optional)" />
+          <label>
+            <input type="checkbox" v-model="domainForm.apply_existing" />
+            Force matching public links private
+          </label>
+          <button type="submit" class="admin-toggle-btn">Save Rule</button>
+        </form>
+        <div v-if="domainRules.length === 0" class="empty-message">No domain rules</div>
+        <div v-else class="users-list">
+          <div v-for="rule in domainRules" :key="rule.id" class="user-item">
+            <div class="user-info">
+              <strong>{{ rule.pattern }}</strong>
+              <span :class="rule.action === 'allow' ? 'admin-badge' : 'locked-badge'">{{ rule.action.toUpperCase() }}</span>
+              <span class="user-meta">{{ rule.note }} | By: {{ rule.created_by }} | {{ rule.created_at }}</span>
+            </div>
+            <div class="user-actions">
+              <button @click="deleteDomainRule(rule.id)" class="delete-btn">Delete</button>
+            </div>
+          </div>
+        </div>
+      </div>
+
+      <!-- Deleted Users Tab -->
+      <div v-if="activeTab === 'deleted'" class="admin-section">
+        <h2>Deleted Users</h2>
+        <div v-if="deletedUsers.length === 0" class="empty-message">No deleted users</div>
+        <div v-else class="users-list">
+          <div v-for="u in deletedUsers" :key="u.id" class="user-item">
+            <div class="user-info">
+              <strong>{{ u.username }}</strong>
+              <span class="user-meta">ID: {{ u.id }} | Deleted: {{ u.deleted_at }}</span>
+            </div>
+            <div class="user-actions">
+              <button @click="restoreUser(u.id)" class="admin-toggle-btn">Restore</button>
+              <button @click="purgeUser(u.id)" class="delete-btn">Delete Forever</button>
+            </div>
+          </div>
+        </div>
+      </div>
+
       <!-- Users Tab -->
       <div v-if="activeTab === 'users'" class="admin-section">
         <h2>User Management</h2>
+        <form @submit.prevent="searchUsers" class="domain-form">
+          <input v-model="userFilters.q" placeholder="Search username" />
+          <select v-model="userFilters.role" class="role-select">
+            <option value="">All roles</option>
+            <option value="user">User</option>
+            <option value="moderator">Moderator</option>
+            <option value="admin">Admin</option>
+          </select>
+          <select v-model="userFilters.sort" class="role-select">
+            <option value="created_at">Newest</option>
+            <option value="username">Username</option>
+            <option value="link_count">Link count</option>
+            <option value="last_login">Last login</option>
+          </select>
+          <select v-model="userFilters.order" class="role-select">
+            <option value="desc">Descending</option>
+            <option value="asc">Ascending</option>
+          </select>
+          <button type="submit" class="admin-toggle-btn">Search</button>
+        </form>
         <div v-if="allUsers.length === 0" class="empty-message">No users found</div>
         <div v-else class="users-list">
           <div v-for="u in allUsers" :key="u.id" class="user-item">
             <div class="user-info">
               <strong>{{ u.username }}</strong>
-              <span class="user-meta">ID: {{ u.id }} | Created: {{ u.createdAt }}</span>
-              <span v-if="u.isAdmin" class="admin-badge">ADMIN</span>
+              <span class="user-meta">ID: {{ u.id }} | Created: {{ u.createdAt }} | Links: {{ u.link_count }} | Last login: {{ u.last_login_at || 'never' }}</span>
+              <span v-if="u.role && u.role !== 'user'" class="admin-badge">{{ u.role.toUpperCase() }}</span>
+              <span v-if="isSuspended(u)" class="locked-badge" :title="u.suspend_reason">
+                SUSPENDED{{ u.suspended_until ? ' until ' + u.suspended_until : '' }}
+              </span>
             </div>
             <div class="user-actions">
-              <button 
+              <select 
                 v-if="u.id !== user?.id"
-                @click="toggleUserAdmin(u.id, !u.isAdmin)" 
-                :class="['admin-toggle-btn', { active: u.isAdmin }]"
+                :value="u.role"
+                @change="setUserRole(u.id, $event.target.value)"
+                class="role-select"
+              >
+                <option value="user">User</option>
+                <option value="moderator">Moderator</option>
+                <option value="admin">Admin</option>
+              </select>
+              <button 
+                v-if="u.id !== user?.id && isSuspended(u)"
+                @click="unsuspendUser(u.id)"
+                class="admin-toggle-btn"
+              >
+                Unsuspend
+              </button>
+              <button 
+                v-else-if="u.id !== user?.id"
+                @click="suspendUser(u.id)"
+                class="lock-btn"
+              >
+                Suspend
+              </button>
+              <button 
+                v-if="isAdmin() && u.role === 'user' && !u.isAdmin && !isSuspended(u)"
+                @click="viewAsUser(u)"
+                class="admin-toggle-btn"
               >
-                {{ u.isAdmin ? 'Remove Admin' : 'Make Admin' }}
+                View as
               </button>
               <button 
                 v-if="u.id !== user?.id"
@@ -307,14 +948,55 @@ const AdminApp = {
             </div>
           </div>
         </div>
+        <div class="pagination">
+          <button @click="pageUsers(-1)" :disabled="userPage.offset === 0" class="admin-toggle-btn">Previous</button>
+          <span>{{ userPage.total === 0 ? 0 : userPage.offset + 1 }}–{{ Math.min(userPage.offset + userPage.limit, userPage.total) }} of {{ userPage.total }}</span>
+          <button @click="pageUsers(1)" :disabled="userPage.offset + userPage.limit >= userPage.total" class="admin-toggle-btn">Next</button>
+        </div>
       </div>
 
       <!-- Links Tab -->
       <div v-if="activeTab === 'links'" class="admin-section">
         <h2>Link Management</h2>
+        <form @submit.prevent="searchLinks" class="domain-form">
+          <input v-model="linkFilters.q" placeholder="Search URL or description" />
+          <input v-model="linkFilters.username" placeholder="Username" />
+          <input v-model="linkFilters.domain" placeholder="Domain" />
+          <select v-model="linkFilters.private" class="role-select">
+            <option value="">Public and private</option>
+            <option value="false">Public</option>
+            <option value="true">Private</option>
+          </select>
+          <select v-model="linkFilters.locked" class="role-select">
+            <option value="">Locked or not</option>
+            <option value="true">Locked</option>
+            <option value="false">Unlocked</option>
+          </select>
+          <input type="date" v-model="linkFilters.since" title="Created from" />
+          <input type="date" v-model="linkFilters.until" title="Created before" />
+          <select v-model="linkFilters.sort" class="role-select">
+            <option value="created_at">Newest</option>
+            <option value="access_count">Most accessed</option>
+            <option value="username">Username</option>
+            <option value="url">URL</option>
+          </select>
+          <select v-model="linkFilters.order" class="role-select">
+            <option value="desc">Descending</option>
+            <option value="asc">Ascending</option>
+          </select>
+          <button type="submit" class="admin-toggle-btn">Search</button>
+        </form>
+        <div class="bulk-actions">
+          <span>{{ selectedLinks.length ? selectedLinks.length + ' selected' : 'No selection: actions apply to all matching links' }}</span>
+          <button @click="bulkLinks('lock')" class="lock-btn">Lock</button>
+          <button @click="bulkLinks('unlock')" class="lock-btn">Unlock</button>
+          <button @click="bulkLinks('force_private')" class="private-btn">Force Private</button>
+          <button v-if="isAdmin()" @click="bulkLinks('delete')" class="delete-btn">Delete</button>
+        </div>
         <div v-if="allLinks.length === 0" class="empty-message">No links found</div>
         <div v-else class="links-list">
           <div v-for="link in allLinks" :key="link.id" class="link-item">
+            <input type="checkbox" :value="link.id" v-model="selectedLinks" class="bulk-select" />
             <div class="link-info">
               <a :href="link.url" target="_blank" class="link-url">{{ link.url }}</a>
               <div class="link-meta">
@@ -340,6 +1022,7 @@ const AdminApp = {
                 Force Private
               </button>
               <button 
+                v-if="isAdmin()"
                 @click="deleteLink(link.id)" 
                 class="delete-btn"
               >
@@ -348,6 +1031,11 @@ const AdminApp = {
             </div>
           </div>
         </div>
+        <div class="pagination">
+          <button @click="pageLinks(-1)" :disabled="linkPage.offset === 0" class="admin-toggle-btn">Previous</button>
+          <span>{{ linkPage.total === 0 ? 0 : linkPage.offset + 1 }}–{{ Math.min(linkPage.offset + linkPage.limit, linkPage.total) }} of {{ linkPage.total }}</span>
+          <button @click="pageLinks(1)" :disabled="linkPage.offset + linkPage.limit >= linkPage.total" class="admin-toggle-btn">Next</button>
+        </div>
       </div>
     </div>
   `
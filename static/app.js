@@ -1,4 +1,5 @@
 import { createApp, reactive } from 'vue';
+import { watchLinkEvents } from './events.js';
 
 // Internationalization system
 const i18n = reactive({
@@ -36,6 +37,13 @@ const i18n = reactive({
       sessionExpired: 'Session expired',
       failedToLoadLinks: 'Failed to load links',
       failedToAddLink: 'Failed to add link',
+      domainBlocked: 'Links to this domain are not allowed',
+
+      // Impersonation
+      viewingAs: 'Viewing as',
+      readOnly: 'read-only',
+      impersonationUntil: 'until',
+      exitImpersonation: 'Exit',
 
       // Success messages
       linkAddedSuccess: 'Link added successfully!',
@@ -73,6 +81,8 @@ const i18n = reactive({
 
       // Navigation
       viewPublicLinks: 'View Public Links',
+      saveBookmarklet: 'Save to Links',
+      bookmarkletHint: 'Drag this to your bookmarks bar, then click it on any page to save it',
 
       // Theme
       darkMode: 'Dark Mode',
@@ -113,8 +123,20 @@ const i18n = reactive({
 
       // Delete
       deleteLink: 'Delete',
-      confirmDelete: 'Are you sure you want to delete this link?',
-      linkDeleted: 'Link deleted successfully!',
+      confirmDelete: 'Move this link to the trash?',
+      linkDeleted: 'Link moved to trash',
+
+      // Trash
+      trash: 'Trash',
+      backToLinks: 'Back to links',
+      trashEmpty: 'Trash is empty',
+      trashNote: 'Deleted links are permanently removed after a while.',
+      restore: 'Restore',
+      deleteForever: 'Delete forever',
+      emptyTrash: 'Empty trash',
+      confirmDeleteForever: 'Permanently delete this link? This cannot be undone.',
+      confirmEmptyTrash: 'Permanently delete everything in the trash? This cannot be undone.',
+      deletedOn: 'Deleted',
 
       // Metadata
       autoFill: 'Auto-fill',
@@ -150,6 +172,13 @@ const i18n = reactive({
       sessionExpired: 'Sessão expirada',
       failedToLoadLinks: 'Falha ao carregar links',
       failedToAddLink: 'Falha ao adicionar link',
+      domainBlocked: 'Links para este domínio não são permitidos',
+
+      // Impersonation
+      viewingAs: 'Visualizando como',
+      readOnly: 'somente leitura',
+      impersonationUntil: 'até',
+      exitImpersonation: 'Sair',
 
       // Success messages
       linkAddedSuccess: 'Link adicionado com sucesso!',
@@ -187,6 +216,8 @@ const i18n = reactive({
 
       // Navigation
       viewPublicLinks: 'Ver Links Públicos',
+      saveBookmarklet: 'Salvar no Links',
+      bookmarkletHint: 'Arraste para a barra de favoritos e clique nela em qualquer página para salvá-la',
 
       // Theme
       darkMode: 'Modo Escuro',
@@ -227,8 +258,20 @@ const i18n = reactive({
 
       // Delete
       deleteLink: 'Excluir',
-      confirmDelete: 'Tem certeza que deseja excluir este link?',
-      linkDeleted: 'Link excluído com sucesso!',
+      confirmDelete: 'Mover este link para a lixeira?',
+      linkDeleted: 'Link movido para a lixeira',
+
+      // Trash
+      trash: 'Lixeira',
+      backToLinks: 'Voltar aos links',
+      trashEmpty: 'A lixeira está vazia',
+      trashNote: 'Links excluídos são removidos permanentemente depois de um tempo.',
+      restore: 'Restaurar',
+      deleteForever: 'Excluir para sempre',
+      emptyTrash: 'Esvaziar lixeira',
+      confirmDeleteForever: 'Excluir este link permanentemente? Esta ação não pode ser desfeita.',
+      confirmEmptyTrash: 'Excluir permanentemente tudo na lixeira? Esta ação não pode ser desfeita.',
+      deletedOn: 'Excluído',
 
       // Metadata
       autoFill: 'Preencher automaticamente',
@@ -311,6 +354,7 @@ const LinksApp = {
       isAuthenticated: false,
       user: null,
       token: null,
+      impersonation: null, // Set while an admin is viewing the app as another user
       links: {},
       url: '',
       description: '',
@@ -322,6 +366,8 @@ const LinksApp = {
       categoryFilter: 'all', // 'all' or specific category
       sortBy: 'date', // 'date', 'date-old', 'alphabetical', 'access', 'category'
       isDarkMode: false,
+      showTrash: false,
+      trash: [],
       loading: {
         auth: false,
         links: false,
@@ -332,6 +378,7 @@ const LinksApp = {
         auth: '',
         addLink: ''
       },
+      refreshTimer: null,
       success: {
         addLink: false,
         deleteLink: false,
@@ -345,6 +392,12 @@ const LinksApp = {
     this.initTheme();
   },
   computed: {
+    // Opens /save in a popup with the current page's URL, title and selected text
+    bookmarklet() {
+      return "javascript:(()=>{window.open('" + window.location.origin +
+        "/save?url='+encodeURIComponent(location.href)+'&title='+encodeURIComponent(document.title)" +
+        "+'&text='+encodeURIComponent(String(getSelection())),'links-save','width=440,height=420')})()";
+    },
     filteredLinks() {
       const query = this.searchQuery.toLowerCase().trim();
 
@@ -455,6 +508,22 @@ const LinksApp = {
       return i18n.getLanguages();
     },
     checkAuth() {
+      // An admin's "view as user" session takes precedence in this tab only
+      const impersonation = sessionStorage.getItem('impersonation');
+      if (impersonation) {
+        const session = JSON.parse(impersonation);
+        if (new Date(session.expires_at.replace(' ', 'T')) > new Date()) {
+          this.impersonation = session;
+          this.token = session.token;
+          this.user = session.user;
+          this.isAuthenticated = true;
+          this.getLinks();
+          this.watchEvents();
+          return;
+        }
+        sessionStorage.removeItem('impersonation');
+      }
+
       const token = localStorage.getItem('token');
       const user = localStorage.getItem('user');
 
@@ -463,12 +532,28 @@ const LinksApp = {
         this.user = JSON.parse(user);
         this.isAuthenticated = true;
         this.getLinks();
+        this.watchEvents();
       } else {
         // Redirect to login if not authenticated
         window.location.href = '/login';
       }
     },
+    endImpersonation() {
+      fetch('/api/impersonation/end', {
+        method: 'POST',
+        headers: this.getAuthHeaders()
+      })
+      .catch(err => console.error('Error ending impersonation:', err))
+      .finally(() => {
+        sessionStorage.removeItem('impersonation');
+        window.location.href = '/admin';
+      });
+    },
     logout() {
+      if (this.impersonation) {
+        this.endImpersonation();
+        return;
+      }
       this.isAuthenticated = false;
       this.user = null;
       this.token = null;
@@ -504,8 +589,17 @@ const LinksApp = {
         'Authorization': `Bearer ${this.token}`
       };
     },
-    getLinks() {
-      this.loading.links = true;
+    // Reload the list when one of the user's links changes elsewhere, such as in
+    // another tab, through the API or by an admin
+    watchEvents() {
+      watchLinkEvents(this.token, (type, data) => {
+        if (type !== 'reset' && data.userId !== this.user.id) return;
+        clearTimeout(this.refreshTimer);
+        this.refreshTimer = setTimeout(() => this.getLinks(true), 300);
+      });
+    },
+    getLinks(quiet = false) {
+      if (!quiet) this.loading.links = true;
 
       fetch(`/api/links`, {
         headers: this.getAuthHeaders()
@@ -578,6 +672,9 @@ const LinksApp = {
             this.logout();
             throw new Error(this.t('sessionExpired'));
           }
+          if (res.status === 403) {
+            throw new Error(this.t('domainBlocked'));
+          }
           throw new Error(this.t('failedToAddLink'));
         }
         return res.json();
@@ -661,6 +758,74 @@ const LinksApp = {
         }
       });
     },
+    toggleTrash() {
+      this.showTrash = !this.showTrash;
+      if (this.showTrash) {
+        this.getTrash();
+      } else {
+        this.getLinks();
+      }
+    },
+    getTrash() {
+      this.loading.links = true;
+
+      fetch('/api/links/trash', {
+        headers: this.getAuthHeaders()
+      })
+      .then(res => {
+        if (!res.ok) {
+          if (res.status === 401) {
+            this.logout();
+            throw new Error(this.t('sessionExpired'));
+          }
+          throw new Error('Failed to load trash');
+        }
+        return res.json();
+      })
+      .then(links => {
+        this.trash = links;
+      })
+      .catch(err => {
+        console.error('Error loading trash:', err);
+      })
+      .finally(() => {
+        this.loading.links = false;
+      });
+    },
+    trashAction(url, method) {
+      return fetch(url, {
+        method,
+        headers: this.getAuthHeaders()
+      })
+      .then(res => {
+        if (!res.ok) {
+          if (res.status === 401) {
+            this.logout();
+            throw new Error(this.t('sessionExpired'));
+          }
+          throw new Error('Failed to update trash');
+        }
+        this.getTrash();
+      })
+      .catch(err => {
+        console.error('Error updating trash:', err);
+      });
+    },
+    restoreLink(linkId) {
+      this.trashAction(`/api/links/${linkId}/restore`, 'PUT');
+    },
+    purgeLink(linkId) {
+      if (!confirm(this.t('confirmDeleteForever'))) {
+        return;
+      }
+      this.trashAction(`/api/links/${linkId}/purge`, 'DELETE');
+    },
+    emptyTrash() {
+      if (!confirm(this.t('confirmEmptyTrash'))) {
+        return;
+      }
+      this.trashAction('/api/links/trash', 'DELETE');
+    },
     clearError(type) {
       this.errors[type] = '';
     },
@@ -732,6 +897,14 @@ const LinksApp = {
   },
   template: `
   <div class="app" :class="{ 'dark-mode': isDarkMode }">
+    <div v-if="impersonation" class="impersonation-banner">
+      <span>
+        {{ t('viewingAs') }} <strong>{{ user.username }}</strong>{{ impersonation.read_only ? ' (' + t('readOnly') + ')' : '' }}
+        {{ t('impersonationUntil') }} {{ impersonation.expires_at }}
+      </span>
+      <button @click="endImpersonation()" class="logout-btn">{{ t('exitImpersonation') }}</button>
+    </div>
+
     <!-- Main App -->
     <div class="main-app">
       <!-- Header -->
@@ -740,6 +913,7 @@ const LinksApp = {
         <div class="user-info">
           <span>{{ t('welcome') }}, {{ user.username }}!</span>
           <a href="/?view=public" class="public-view-btn">{{ t('viewPublicLinks') }}</a>
+          <a :href="bookmarklet" class="public-view-btn" :title="t('bookmarkletHint')" @click.prevent>{{ t('saveBookmarklet') }}</a>
           <select v-model="currentLanguage" @change="changeLanguage($event.target.value)" class="lang-select">
             <option value="en">{{ t('english') }}</option>
             <option value="pt">{{ t('portuguese') }}</option>
@@ -869,6 +1043,10 @@ const LinksApp = {
             </select>
           </div>
 
+          <button @click="toggleTrash()" class="link-btn">
+            {{ showTrash ? t('backToLinks') : t('trash') }}
+          </button>
+
           <div class="filter-container">
             <label>{{ t('sortBy') }}:</label>
             <select v-model="sortBy" class="privacy-filter">
@@ -890,6 +1068,32 @@ const LinksApp = {
           {{ t('loading') }}
         </div>
 
+        <div v-else-if="showTrash">
+          <div v-if="trash.length === 0" class="empty-state">
+            <h3>{{ t('trashEmpty') }}</h3>
+          </div>
+          <div v-else class="date-group">
+            <h3 class="date-header">{{ t('trash') }}</h3>
+            <p class="privacy-note">{{ t('trashNote') }}</p>
+            <button @click="emptyTrash()" class="delete-btn">{{ t('emptyTrash') }}</button>
+            <div class="links-list">
+              <div v-for="link in trash" :key="link.id" class="link-item">
+                <div class="link-header">
+                  <span class="link-url">{{ link.url }}</span>
+                  <div class="link-actions">
+                    <span class="link-time">{{ t('deletedOn') }} {{ toDate(link.deleted_at) }}</span>
+                    <button @click="restoreLink(link.id)" class="favorite-btn">{{ t('restore') }}</button>
+                    <button @click="purgeLink(link.id)" class="delete-btn">{{ t('deleteForever') }}</button>
+                  </div>
+                </div>
+                <div v-if="link.description" class="link-description">
+                  {{ link.description }}
+                </div>
+              </div>
+            </div>
+          </div>
+        </div>
+
         <div v-else-if="!hasLinks" class="empty-state">
           <h3>{{ t('noLinksYet') }}</h3>
           <p>{{ t('addFirstLink') }}</p>
@@ -0,0 +1,71 @@
+// Live link updates from GET /api/events (Server-Sent Events). EventSource cannot
+// send an Authorization header, so signed-in pages read the stream with fetch
+// instead. Both resume from the last event ID after a disconnect.
+
+const EVENT_TYPES = ['link.created', 'link.updated', 'link.deleted', 'link.made_public', 'link.hidden', 'reset'];
+
+// watchLinkEvents calls onEvent(type, data) for every event; without a token only
+// public link events arrive. It returns a function that stops watching.
+export function watchLinkEvents(token, onEvent) {
+  if (!token) {
+    const source = new EventSource('/api/events');
+    for (const type of EVENT_TYPES) {
+      source.addEventListener(type, e => onEvent(type, JSON.parse(e.data)));
+    }
+    return () => source.close();
+  }
+
+  const controller = new AbortController();
+  let lastId = '';
+  let retry = 5000;
+
+  const dispatch = block => {
+    let type = 'message';
+    let data = '';
+    for (const line of block.split('\n')) {
+      if (line.startsWith(':')) continue;
+      const i = line.indexOf(':');
+      const field = i < 0 ? line : line.slice(0, i);
+      const value = i < 0 ? '' : line.slice(i + 1).replace(/^ /, '');
+      if (field === 'id') lastId = value;
+      else if (field === 'event') type = value;
+      else if (field === 'data') data += value;
+      else if (field === 'retry' && /^\d+$/.test(value)) retry = Number(value);
+    }
+    if (data && EVENT_TYPES.includes(type)) {
+      onEvent(type, JSON.parse(data));
+    }
+  };
+
+  const connect = async () => {
+    while (!controller.signal.aborted) {
+      try {
+        const headers = { 'Authorization': `Bearer ${token}`, 'Accept': 'text/event-stream' };
+        if (lastId) headers['Last-Event-ID'] = lastId;
+        const res = await fetch('/api/events', { headers, signal: controller.signal });
+        if (res.status === 401 || res.status === 403) return;
+        if (!res.ok) throw new Error(`Event stream failed: ${res.status}`);
+
+        const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+        let buffer = '';
+        for (;;) {
+          const { value, done } = await reader.read();
+          if (done) break;
+          buffer += value.replace(/\r\n?/g, '\n');
+          let end;
+          while ((end = buffer.indexOf('\n\n')) >= 0) {
+            dispatch(buffer.slice(0, end));
+            buffer = buffer.slice(end + 2);
+          }
+        }
+      } catch (err) {
+        if (controller.signal.aborted) return;
+        console.error('Event stream error:', err);
+      }
+      await new Promise(resolve => setTimeout(resolve, retry));
+    }
+  };
+
+  connect();
+  return () => controller.abort();
+}
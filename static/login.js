@@ -28,7 +28,20 @@ const i18n = reactive({
       passwordMinLength: 'Password must be at least 3 characters',
       loginFailed: 'Invalid credentials',
       registrationFailed: 'Username already exists or invalid data',
-      backToPublicLinks: 'Back to Public Links'
+      backToPublicLinks: 'Back to Public Links',
+      twoFactorCode: 'Authentication or recovery code',
+      verify: 'Verify',
+      twoFactorFailed: 'Invalid two-factor code',
+      forgotPassword: 'Forgot your password?',
+      email: 'Email',
+      sendResetLink: 'Send reset link',
+      resetLinkSent: 'If that address is verified on an account, a reset link is on its way.',
+      newPassword: 'New password',
+      resetPassword: 'Reset password',
+      passwordResetDone: 'Password updated. You can now log in.',
+      resetFailed: 'Invalid or expired reset link',
+      emailVerified: 'Email address confirmed.',
+      emailVerifyFailed: 'Invalid or expired verification link'
     },
     pt: {
       appTitle: 'Links',
@@ -53,7 +66,20 @@ const i18n = reactive({
       passwordMinLength: 'Senha deve ter pelo menos 3 caracteres',
       loginFailed: 'Credenciais inválidas',
       registrationFailed: 'Usuário já existe ou dados inválidos',
-      backToPublicLinks: 'Voltar aos Links Públicos'
+      backToPublicLinks: 'Voltar aos Links Públicos',
+      twoFactorCode: 'Código de autenticação ou de recuperação',
+      verify: 'Verificar',
+      twoFactorFailed: 'Código de dois fatores inválido',
+      forgotPassword: 'Esqueceu sua senha?',
+      email: 'Email',
+      sendResetLink: 'Enviar link de redefinição',
+      resetLinkSent: 'Se esse endereço estiver verificado em uma conta, um link de redefinição foi enviado.',
+      newPassword: 'Nova senha',
+      resetPassword: 'Redefinir senha',
+      passwordResetDone: 'Senha atualizada. Você já pode entrar.',
+      resetFailed: 'Link de redefinição inválido ou expirado',
+      emailVerified: 'Endereço de email confirmado.',
+      emailVerifyFailed: 'Link de verificação inválido ou expirado'
     }
   },
   
@@ -92,6 +118,13 @@ const LoginApp = {
       showLogin: true,
       username: '',
       password: '',
+      mfaToken: '',
+      twoFactorCode: '',
+      showForgot: false,
+      email: '',
+      resetToken: '',
+      newPassword: '',
+      info: '',
       loading: {
         auth: false
       },
@@ -104,6 +137,7 @@ const LoginApp = {
   },
   created() {
     this.handleOAuthCallback();
+    this.handleEmailLinks();
     this.initTheme();
   },
   computed: {
@@ -161,10 +195,14 @@ const LoginApp = {
         return res.json();
       })
       .then(data => {
+        if (data.two_factor_required) {
+          this.mfaToken = data.mfa_token;
+          return;
+        }
         if (data.token) {
           localStorage.setItem('token', data.token);
           localStorage.setItem('user', JSON.stringify(data.user));
-          window.location.href = '/';
+          this.redirectAfterLogin();
         }
       })
       .catch(err => {
@@ -175,6 +213,41 @@ const LoginApp = {
         this.loading.auth = false;
       });
     },
+    verifyTwoFactor() {
+      this.loading.auth = true;
+      this.errors.auth = '';
+
+      fetch('/api/login/2fa', {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+          mfa_token: this.mfaToken,
+          code: this.twoFactorCode.trim()
+        })
+      })
+      .then(res => {
+        if (!res.ok) {
+          throw new Error(this.t('twoFactorFailed'));
+        }
+        return res.json();
+      })
+      .then(data => {
+        if (data.token) {
+          localStorage.setItem('token', data.token);
+          localStorage.setItem('user', JSON.stringify(data.user));
+          this.redirectAfterLogin();
+        }
+      })
+      .catch(err => {
+        console.error('Two-factor verification failed:', err);
+        this.errors.auth = err.message || this.t('twoFactorFailed');
+      })
+      .finally(() => {
+        this.loading.auth = false;
+      });
+    },
     register() {
       if (!this.validateAuthForm()) return;
       
@@ -201,7 +274,7 @@ const LoginApp = {
         if (data.token) {
           localStorage.setItem('token', data.token);
           localStorage.setItem('user', JSON.stringify(data.user));
-          window.location.href = '/';
+          this.redirectAfterLogin();
         }
       })
       .catch(err => {
@@ -227,19 +300,105 @@ const LoginApp = {
       this.errors[type] = '';
     },
     loginWithGoogle() {
-      window.location.href = '/api/auth/google';
+      const redirect = new URLSearchParams(window.location.search).get('redirect');
+      window.location.href = '/api/auth/google' + (redirect ? '?redirect=' + encodeURIComponent(redirect) : '');
+    },
+    handleEmailLinks() {
+      const urlParams = new URLSearchParams(window.location.search);
+      this.resetToken = urlParams.get('reset_token') || '';
+
+      const verifyToken = urlParams.get('verify_email_token');
+      if (verifyToken) {
+        fetch('/api/email/verify', {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify({ token: verifyToken })
+        })
+        .then(res => {
+          if (!res.ok) throw new Error(this.t('emailVerifyFailed'));
+          this.info = this.t('emailVerified');
+        })
+        .catch(err => {
+          this.errors.auth = err.message;
+        });
+      }
+    },
+    requestReset() {
+      this.loading.auth = true;
+      this.errors.auth = '';
+
+      fetch('/api/password/forgot', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ email: this.email.trim() })
+      })
+      .then(() => {
+        this.info = this.t('resetLinkSent');
+        this.showForgot = false;
+      })
+      .finally(() => {
+        this.loading.auth = false;
+      });
+    },
+    resetPassword() {
+      this.loading.auth = true;
+      this.errors.auth = '';
+
+      fetch('/api/password/reset', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ token: this.resetToken, new_password: this.newPassword })
+      })
+      .then(res => {
+        if (!res.ok) throw new Error(this.t('resetFailed'));
+        this.resetToken = '';
+        this.newPassword = '';
+        this.info = this.t('passwordResetDone');
+        window.history.replaceState({}, '', '/login');
+      })
+      .catch(err => {
+        this.errors.auth = err.message;
+      })
+      .finally(() => {
+        this.loading.auth = false;
+      });
+    },
+    // Returns to the local page that sent the user here (?redirect=), such as the
+    // bookmarklet's /save popup, or to the app. Like the server's ValidateRedirect it
+    // refuses backslashes, which browsers read as "/" (so "/\evil.com" is off-site).
+    redirectAfterLogin() {
+      const redirect = new URLSearchParams(window.location.search).get('redirect') || '/';
+      let target = '/';
+      if (redirect.startsWith('/') && !redirect.includes('\\')) {
+        try {
+          const url = new URL(redirect, window.location.origin);
+          if (url.origin === window.location.origin) {
+            target = url.pathname + url.search + url.hash;
+          }
+        } catch (err) {
+          // Not a URL; fall back to the app
+        }
+      }
+      window.location.href = target;
     },
     handleOAuthCallback() {
       const urlParams = new URLSearchParams(window.location.search);
       const token = urlParams.get('token');
       const userStr = urlParams.get('user');
+      const mfaToken = urlParams.get('mfa_token');
+      
+      // Google sign-in to an account with two-factor authentication enabled
+      if (mfaToken) {
+        this.mfaToken = mfaToken;
+        return;
+      }
       
       if (token && userStr) {
         try {
           const user = JSON.parse(decodeURIComponent(userStr));
           localStorage.setItem('token', token);
           localStorage.setItem('user', JSON.stringify(user));
-          window.location.href = '/';
+          this.redirectAfterLogin();
         } catch (e) {
           console.error('Error parsing OAuth callback:', e);
         }
@@ -266,7 +425,55 @@ const LoginApp = {
           <span>{{ t('orSeparator') }}</span>
         </div>
         
-        <form @submit.prevent="showLogin ? login() : register()">
+        <div v-if="info" class="success-message" @click="info = ''">
+          {{ info }}
+        </div>
+        
+        <form v-if="resetToken" @submit.prevent="resetPassword()">
+          <input 
+            v-model="newPassword" 
+            :placeholder="t('newPassword')" 
+            type="password"
+            autocomplete="new-password"
+            :disabled="loading.auth"
+            required
+          >
+          <button type="submit" :disabled="loading.auth" class="primary-btn">
+            {{ t('resetPassword') }}
+          </button>
+        </form>
+        
+        <form v-else-if="showForgot" @submit.prevent="requestReset()">
+          <input 
+            v-model="email" 
+            :placeholder="t('email')" 
+            type="email"
+            :disabled="loading.auth"
+            required
+          >
+          <button type="submit" :disabled="loading.auth" class="primary-btn">
+            {{ t('sendResetLink') }}
+          </button>
+        </form>
+        
+        <form v-else-if="mfaToken" @submit.prevent="verifyTwoFactor()">
+          <input 
+            v-model="twoFactorCode" 
+            :placeholder="t('twoFactorCode')" 
+            type="text"
+            autocomplete="one-time-code"
+            :disabled="loading.auth"
+            @input="clearError('auth')"
+            required
+          >
+          
+          <button type="submit" :disabled="loading.auth" class="primary-btn">
+            <span v-if="loading.auth">Loading...</span>
+            {{ t('verify') }}
+          </button>
+        </form>
+        
+        <form v-else @submit.prevent="showLogin ? login() : register()">
           <input 
             v-model="username" 
             :placeholder="t('username')" 
@@ -290,6 +497,10 @@ const LoginApp = {
           </button>
         </form>
         
+        <button v-if="showLogin && !showForgot && !resetToken" @click="showForgot = true; clearError('auth')" class="link-btn">
+          {{ t('forgotPassword') }}
+        </button>
+        
         <button @click="showLogin = !showLogin; clearError('auth')" class="link-btn">
           {{ showLogin ? t('needAccount') : t('haveAccount') }}
         </button>
@@ -1,4 +1,5 @@
 import { createApp, reactive } from 'vue';
+import { watchLinkEvents } from './events.js';
 
 // Internationalization system
 const i18n = reactive({
@@ -29,7 +30,19 @@ const i18n = reactive({
       yourLinks: 'Your Links',
       manageYourLinks: 'Manage your links',
       wantToShare: 'Want to share a link?',
-      loginToShare: 'Login to add your links and share them with everyone!'
+      loginToShare: 'Login to add your links and share them with everyone!',
+      report: 'Report',
+      reportReason: 'Reason',
+      reasonSpam: 'Spam',
+      reasonAbuse: 'Abusive or harassing',
+      reasonIllegal: 'Illegal content',
+      reasonOther: 'Other',
+      reportDetails: 'Details (optional)',
+      sendReport: 'Send report',
+      cancel: 'Cancel',
+      reportSent: 'Thanks, a moderator will review this link.',
+      reportDuplicate: 'You have already reported this link.',
+      reportFailed: 'Could not send the report.'
     },
     pt: {
       appTitle: 'Links',
@@ -55,7 +68,19 @@ const i18n = reactive({
       yourLinks: 'Seus Links',
       manageYourLinks: 'Gerencie seus links',
       wantToShare: 'Quer compartilhar um link?',
-      loginToShare: 'Entre para adicionar seus links e compartilhá-los com todos!'
+      loginToShare: 'Entre para adicionar seus links e compartilhá-los com todos!',
+      report: 'Denunciar',
+      reportReason: 'Motivo',
+      reasonSpam: 'Spam',
+      reasonAbuse: 'Abusivo ou ofensivo',
+      reasonIllegal: 'Conteúdo ilegal',
+      reasonOther: 'Outro',
+      reportDetails: 'Detalhes (opcional)',
+      sendReport: 'Enviar denúncia',
+      cancel: 'Cancelar',
+      reportSent: 'Obrigado, um moderador vai analisar este link.',
+      reportDuplicate: 'Você já denunciou este link.',
+      reportFailed: 'Não foi possível enviar a denúncia.'
     }
   },
   
@@ -119,7 +144,11 @@ const PublicLinksApp = {
         links: false
       },
       isAuthenticated: false,
+      userId: null,
       isDarkMode: false,
+      reporting: null, // { linkId, reason, details }
+      reportMessage: '',
+      refreshTimer: null,
       // Remove currentLanguage from data since we'll use computed property
     }
   },
@@ -127,6 +156,12 @@ const PublicLinksApp = {
     this.checkAuth();
     this.getPublicLinks();
     this.initTheme();
+
+    // Public link events only; reload once a burst of them is over
+    watchLinkEvents(null, () => {
+      clearTimeout(this.refreshTimer);
+      this.refreshTimer = setTimeout(() => this.getPublicLinks(true), 300);
+    });
   },
   computed: {
     byDate() {
@@ -144,6 +179,44 @@ const PublicLinksApp = {
       const token = localStorage.getItem('token');
       const user = localStorage.getItem('user');
       this.isAuthenticated = !!(token && user);
+      if (this.isAuthenticated) {
+        try {
+          this.userId = JSON.parse(user).id;
+        } catch (e) {
+          this.userId = null;
+        }
+      }
+    },
+    startReport(linkId) {
+      this.reporting = { linkId, reason: 'spam', details: '' };
+      this.reportMessage = '';
+    },
+    sendReport() {
+      const { linkId, reason, details } = this.reporting;
+
+      fetch(`/api/links/${linkId}/report`, {
+        method: 'POST',
+        headers: {
+          'Authorization': `Bearer ${localStorage.getItem('token')}`,
+          'Content-Type': 'application/json'
+        },
+        body: JSON.stringify({ reason, details })
+      })
+      .then(res => {
+        if (res.status === 409) {
+          this.reportMessage = this.t('reportDuplicate');
+        } else if (!res.ok) {
+          this.reportMessage = this.t('reportFailed');
+        } else {
+          this.reportMessage = this.t('reportSent');
+        }
+      })
+      .catch(() => {
+        this.reportMessage = this.t('reportFailed');
+      })
+      .finally(() => {
+        this.reporting = null;
+      });
     },
     changeLanguage(lang) {
       i18n.setLanguage(lang);
@@ -168,8 +241,8 @@ const PublicLinksApp = {
         localStorage.setItem('theme', 'light');
       }
     },
-    getPublicLinks() {
-      this.loading.links = true;
+    getPublicLinks(quiet = false) {
+      if (!quiet) this.loading.links = true;
       
       fetch('/api/public-links')
       .then(res => {
@@ -244,6 +317,7 @@ const PublicLinksApp = {
       </div>
       
       <div v-else>
+        <div v-if="reportMessage" class="success-message" @click="reportMessage = ''">{{ reportMessage }}</div>
         <div v-for="date in byDate" :key="date" class="date-group">
           <h3 class="date-header">{{ toDate(date) }}</h3>
           <div class="links-list">
@@ -255,8 +329,28 @@ const PublicLinksApp = {
                 <div class="link-meta">
                   <span class="link-time">{{ toTime(link.created_at) }}</span>
                   <span class="posted-by">{{ t('postedBy') }} <strong>{{ link.username }}</strong></span>
+                  <button
+                    v-if="isAuthenticated && link.userId !== userId"
+                    @click="startReport(link.id)"
+                    class="link-btn"
+                  >
+                    {{ t('report') }}
+                  </button>
                 </div>
               </div>
+
+              <form v-if="reporting && reporting.linkId === link.id" @submit.prevent="sendReport()" class="report-form">
+                <label>{{ t('reportReason') }}:</label>
+                <select v-model="reporting.reason" class="privacy-filter">
+                  <option value="spam">{{ t('reasonSpam') }}</option>
+                  <option value="abuse">{{ t('reasonAbuse') }}</option>
+                  <option value="illegal">{{ t('reasonIllegal') }}</option>
+                  <option value="other">{{ t('reasonOther') }}</option>
+                </select>
+                <textarea v-model="reporting.details" :placeholder="t('reportDetails')" maxlength="500" rows="2"></textarea>
+                <button type="submit" class="primary-btn">{{ t('sendReport') }}</button>
+                <button type="button" @click="reporting = null" class="link-btn">{{ t('cancel') }}</button>
+              </form>
               
               <div v-if="link.description" class="link-description">
                 {{ link.description }}
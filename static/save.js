@@ -0,0 +1,243 @@
+import { createApp, reactive } from 'vue';
+
+// Popup opened by the bookmarklet (/save?url=&title=&text=). It shows the page prefilled
+// in a form and saves it with the signed-in session only when the user confirms, so a
+// link to /save from another site cannot save anything on its own.
+
+const i18n = reactive({
+  currentLang: 'en',
+
+  translations: {
+    en: {
+      appTitle: 'Links',
+      saving: 'Saving…',
+      save: 'Save',
+      cancel: 'Cancel',
+      descriptionPlaceholder: 'Description (optional)',
+      tagsPlaceholder: 'Tags (optional, comma separated)',
+      saved: 'Saved to your links',
+      removed: 'Link removed',
+      signInToSave: 'Sign in to save this page',
+      signIn: 'Sign in',
+      noURL: 'Nothing to save: open this page from the bookmarklet',
+      domainBlocked: 'Links to this domain are not allowed',
+      saveFailed: 'Could not save the link',
+      undo: 'Undo',
+      openLinks: 'Open Links',
+      close: 'Close',
+      private: 'Private',
+      tags: 'Tags'
+    },
+    pt: {
+      appTitle: 'Links',
+      saving: 'Salvando…',
+      save: 'Salvar',
+      cancel: 'Cancelar',
+      descriptionPlaceholder: 'Descrição (opcional)',
+      tagsPlaceholder: 'Tags (opcional, separadas por vírgula)',
+      saved: 'Salvo nos seus links',
+      removed: 'Link removido',
+      signInToSave: 'Entre para salvar esta página',
+      signIn: 'Entrar',
+      noURL: 'Nada para salvar: abra esta página pelo bookmarklet',
+      domainBlocked: 'Links para este domínio não são permitidos',
+      saveFailed: 'Não foi possível salvar o link',
+      undo: 'Desfazer',
+      openLinks: 'Abrir Links',
+      close: 'Fechar',
+      private: 'Privado',
+      tags: 'Tags'
+    }
+  },
+
+  t(key) {
+    const translation = this.translations[this.currentLang][key];
+    return translation || this.translations['en'][key] || key;
+  },
+
+  detectLanguage() {
+    const browserLang = navigator.language || navigator.userLanguage;
+    this.currentLang = browserLang.startsWith('pt') ? 'pt' : 'en';
+
+    const savedLang = localStorage.getItem('language');
+    if (savedLang && this.translations[savedLang]) {
+      this.currentLang = savedLang;
+    }
+  }
+});
+
+i18n.detectLanguage();
+
+const SaveApp = {
+  data() {
+    return {
+      status: 'confirm', // confirm, saving, saved, removed, signin, error
+      error: '',
+      page: null,
+      tags: '',
+      isPrivate: false,
+      link: null,
+      isDarkMode: localStorage.getItem('theme') === 'dark'
+    }
+  },
+  created() {
+    if (this.isDarkMode) {
+      document.body.classList.add('dark-mode');
+    }
+    this.load();
+  },
+  methods: {
+    t(key) {
+      return i18n.t(key);
+    },
+    // The page to save comes from the query string, or from sessionStorage when
+    // coming back from the login page (which drops the query)
+    pending() {
+      const params = new URLSearchParams(window.location.search);
+      if (params.get('url')) {
+        const page = { url: params.get('url'), title: params.get('title') || '', text: params.get('text') || '' };
+        sessionStorage.setItem('quickSave', JSON.stringify(page));
+        window.history.replaceState({}, '', '/save');
+        return page;
+      }
+      const stored = sessionStorage.getItem('quickSave');
+      return stored ? JSON.parse(stored) : null;
+    },
+    load() {
+      this.page = this.pending();
+      if (!this.page) {
+        this.status = 'error';
+        this.error = this.t('noURL');
+        return;
+      }
+      this.status = localStorage.getItem('token') ? 'confirm' : 'signin';
+    },
+    // Only called from the Save button
+    save() {
+      const token = localStorage.getItem('token');
+      if (!token) {
+        this.status = 'signin';
+        return;
+      }
+
+      this.status = 'saving';
+      this.error = '';
+      fetch('/api/quick-save', {
+        method: 'POST',
+        headers: {
+          'Content-Type': 'application/json',
+          'Authorization': `Bearer ${token}`
+        },
+        body: JSON.stringify({ ...this.page, tags: this.tags, is_private: this.isPrivate })
+      })
+      .then(res => {
+        if (res.status === 401) {
+          localStorage.removeItem('token');
+          localStorage.removeItem('user');
+          this.status = 'signin';
+          return null;
+        }
+        if (!res.ok) {
+          throw new Error(res.status === 403 ? this.t('domainBlocked') : this.t('saveFailed'));
+        }
+        return res.json();
+      })
+      .then(link => {
+        if (link) {
+          sessionStorage.removeItem('quickSave');
+          this.link = link;
+          this.status = 'saved';
+        }
+      })
+      .catch(err => {
+        console.error('Quick save failed:', err);
+        this.status = 'confirm';
+        this.error = err.message || this.t('saveFailed');
+      });
+    },
+    undo() {
+      fetch(`/api/links/${this.link.id}`, {
+        method: 'DELETE',
+        headers: { 'Authorization': `Bearer ${localStorage.getItem('token')}` }
+      })
+      .then(res => {
+        if (!res.ok) {
+          throw new Error(this.t('saveFailed'));
+        }
+        this.status = 'removed';
+      })
+      .catch(err => {
+        this.error = err.message;
+      });
+    },
+    cancel() {
+      sessionStorage.removeItem('quickSave');
+      window.close();
+    },
+    close() {
+      window.close();
+    }
+  },
+  template: `
+  <div class="app" :class="{ 'dark-mode': isDarkMode }">
+    <div class="auth-container">
+      <div class="auth-form quick-save">
+        <h1>{{ t('appTitle') }}</h1>
+
+        <form v-if="status === 'confirm' || status === 'saving'" @submit.prevent="save()">
+          <p class="quick-save-url">{{ page.url }}</p>
+          <p v-if="page.title">{{ page.title }}</p>
+          <textarea
+            v-model="page.text"
+            :placeholder="t('descriptionPlaceholder')"
+            :disabled="status === 'saving'"
+            rows="3"
+          ></textarea>
+          <input v-model="tags" :placeholder="t('tagsPlaceholder')" :disabled="status === 'saving'">
+          <div class="privacy-checkbox">
+            <input type="checkbox" v-model="isPrivate" :disabled="status === 'saving'">
+            <span>{{ t('private') }}</span>
+          </div>
+          <div v-if="error" class="error-message">{{ error }}</div>
+          <div class="quick-save-actions">
+            <button type="submit" class="login-btn" :disabled="status === 'saving'">
+              {{ status === 'saving' ? t('saving') : t('save') }}
+            </button>
+            <button type="button" @click="cancel()" class="theme-btn">{{ t('cancel') }}</button>
+          </div>
+        </form>
+
+        <template v-else-if="status === 'saved'">
+          <div class="success-message">{{ t('saved') }}</div>
+          <p class="quick-save-url">{{ link.url }}</p>
+          <p v-if="link.description">{{ link.description }}</p>
+          <p v-if="link.tags">{{ t('tags') }}: {{ link.tags }}</p>
+          <p v-if="link.is_private">{{ t('private') }}</p>
+          <div v-if="error" class="error-message">{{ error }}</div>
+          <div class="quick-save-actions">
+            <button @click="undo()" class="logout-btn">{{ t('undo') }}</button>
+            <a href="/" target="_blank" class="login-btn">{{ t('openLinks') }}</a>
+            <button @click="close()" class="theme-btn">{{ t('close') }}</button>
+          </div>
+        </template>
+
+        <template v-else-if="status === 'removed'">
+          <div class="success-message">{{ t('removed') }}</div>
+          <div class="quick-save-actions">
+            <button @click="close()" class="theme-btn">{{ t('close') }}</button>
+          </div>
+        </template>
+
+        <template v-else-if="status === 'signin'">
+          <p>{{ t('signInToSave') }}</p>
+          <a href="/login?redirect=/save" class="login-btn">{{ t('signIn') }}</a>
+        </template>
+
+        <div v-else class="error-message">{{ error }}</div>
+      </div>
+    </div>
+  </div>
+  `
+};
+
+createApp(SaveApp).mount('#app');